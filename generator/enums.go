@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aybabtme/sequel/reflector"
+)
+
+// enumType is the Go type generated for an enum or set column.
+type enumType struct {
+	Name   string // The Go type of the column.
+	Member string // For sets, the Go type of the members.
+	Column reflector.Column
+	IsSet  bool
+	Consts []enumConst
+}
+
+type enumConst struct {
+	Name  string
+	Value string
+}
+
+// enumTypeName names the type of an enum or set column after the row
+// type holding it, like UserStatus.
+func enumTypeName(datatype string, col reflector.Column) string {
+	return datatype + export(camelize(col.Name))
+}
+
+// enumTypes lists the types to generate for the enum and set columns
+// among cols.
+func enumTypes(datatype string, cols []reflector.Column) []enumType {
+	var enums []enumType
+	for _, col := range cols {
+		if col.Type != reflector.SQLEnum && col.Type != reflector.SQLSet {
+			continue
+		}
+		enum := enumType{
+			Name:   enumTypeName(datatype, col),
+			Column: col,
+			IsSet:  col.Type == reflector.SQLSet,
+		}
+		constType := enum.Name
+		if enum.IsSet {
+			enum.Member = enum.Name + "Member"
+			constType = enum.Member
+		}
+		seen := make(map[string]bool)
+		for _, val := range col.Values {
+			name := constType + enumConstName(val)
+			for i := 2; seen[name]; i++ {
+				name = constType + enumConstName(val) + strconv.Itoa(i)
+			}
+			seen[name] = true
+			enum.Consts = append(enum.Consts, enumConst{Name: name, Value: val})
+		}
+		enums = append(enums, enum)
+	}
+	return enums
+}
+
+// enumConstName turns an enum value into the suffix of a Go identifier.
+func enumConstName(val string) string {
+	clean := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, val)
+	name := export(camelize(strings.Trim(clean, "_")))
+	if name == "" {
+		return "Empty"
+	}
+	return name
+}
+
+func hasSet(enums []enumType) bool {
+	for _, enum := range enums {
+		if enum.IsSet {
+			return true
+		}
+	}
+	return false
+}
@@ -21,6 +21,12 @@ var funcmap = template.FuncMap{
 	"idx_list_args":      idxListArgs,
 	"idx_query_args":     idxQueryArgs,
 	"export":             export,
+	"triggers_doc":       triggersDoc,
+	"doc":                docComment,
+	"routine_methods":    routineMethods,
+	"routine_imports":    routineImports,
+	"has_procedure":      hasProcedure,
+	"decimal_generated":  decimalGenerated,
 
 	"createQuery":   createQuery,
 	"retrieveQuery": retrieveQuery,
@@ -28,6 +34,10 @@ var funcmap = template.FuncMap{
 	"deleteQuery":   deleteQuery,
 	"listQuery":     listQuery,
 	"listIndex":     listIndex,
+	"refreshQuery":  refreshQuery,
+
+	"viewListQuery":      viewListQuery,
+	"viewListWhereQuery": viewListWhereQuery,
 }
 
 func camelize(str string) string {
@@ -40,7 +50,14 @@ func camelize(str string) string {
 		"_id": "_ID",
 	}
 
-	b := []byte(str)
+	// names like `first-name` separate their words with what Go
+	// identifiers can't hold
+	b := []byte(strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, str))
 
 	for from, to := range converts {
 		b = bytes.Replace(b, []byte(from), []byte(to), -1)
@@ -100,7 +117,35 @@ func variableToGoValue(v reflector.Variable) string {
 	return fmt.Sprintf("%#v", v.Value)
 }
 
-func columnToGoType(c reflector.Column) string {
+// decimalGenerated tells if common.go must declare the decimal types.
+func decimalGenerated() bool {
+	return DecimalImport == ""
+}
+
+// columnToGoType is the type of the field holding column c in the
+// row type `datatype`.
+func columnToGoType(datatype string, c reflector.Column) string {
+	var goType string
+	switch c.Type {
+	case reflector.SQLEnum, reflector.SQLSet:
+		goType = enumTypeName(datatype, c)
+	case reflector.SQLJSON:
+		goType = "json.RawMessage"
+	case reflector.SQLPoint:
+		goType = "Point"
+	case reflector.SQLGeometry:
+		goType = "Geometry"
+	case reflector.SQLBit:
+		goType = "Bits"
+	}
+	if goType != "" {
+		// nil when NULL
+		if c.Nullable {
+			return "*" + goType
+		}
+		return goType
+	}
+
 	if c.Nullable {
 		switch c.Type {
 		case reflector.SQLString:
@@ -108,9 +153,18 @@ func columnToGoType(c reflector.Column) string {
 		case reflector.SQLBytes:
 			return "[]byte"
 		case reflector.SQLInteger:
+			switch {
+			case isBoolInteger(c):
+				return "NullBool"
+			case c.Unsigned && (c.Size == 8 || c.Size == 0):
+				// doesn't fit in an int64
+				return "NullUint64"
+			}
 			return "NullInt64"
 		case reflector.SQLFloat:
 			return "NullFloat64"
+		case reflector.SQLDecimal:
+			return NullDecimalType
 		case reflector.SQLBool:
 			return "NullBool"
 		case reflector.SQLTime:
@@ -124,9 +178,11 @@ func columnToGoType(c reflector.Column) string {
 	case reflector.SQLBytes:
 		return "[]byte"
 	case reflector.SQLInteger:
-		return "int"
+		return integerGoType(c)
 	case reflector.SQLFloat:
 		return "float64"
+	case reflector.SQLDecimal:
+		return DecimalType
 	case reflector.SQLBool:
 		return "bool"
 	case reflector.SQLTime:
@@ -135,32 +191,97 @@ func columnToGoType(c reflector.Column) string {
 	panic(c)
 }
 
-func idxListArgs(idx reflector.Index) string {
+// integerGoType is the smallest Go integer type holding every value of
+// the integer column c.
+func integerGoType(c reflector.Column) string {
+	if isBoolInteger(c) {
+		return "bool"
+	}
+	var bits string
+	switch c.Size {
+	case 0:
+		// unknown, as for SQLite
+		if c.Unsigned {
+			return "uint"
+		}
+		return "int"
+	case 1:
+		bits = "8"
+	case 2:
+		bits = "16"
+	case 3, 4:
+		bits = "32"
+	default:
+		bits = "64"
+	}
+	if c.Unsigned {
+		return "uint" + bits
+	}
+	return "int" + bits
+}
+
+// isBoolInteger tells if c is a `tinyint(1)`, which MySQL uses for
+// booleans.
+func isBoolInteger(c reflector.Column) bool {
+	return c.Type == reflector.SQLInteger && c.Size == 1 && c.Length == 1
+}
+
+// docComment turns a comment set on the database into the lines of a
+// Go doc comment.
+func docComment(text string) string {
+	text = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(text)
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("// "+strings.TrimSpace(line), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// triggersDoc lists the triggers fired by `event`, to be appended to
+// the doc comment of the operation causing it.
+func triggersDoc(tbl reflector.Table, event string) string {
+	trgs := tbl.TriggersOn(event)
+	if len(trgs) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(trgs))
+	for _, trg := range trgs {
+		names = append(names, fmt.Sprintf("%s (%s %s)", trg.Name, trg.Timing, trg.Event))
+	}
+	return "\n//\n// Fires triggers " + strings.Join(names, ", ") + "."
+}
+
+func idxListArgs(datatype string, idx reflector.Index) string {
 	buf := bytes.NewBuffer(nil)
+	names := idxArgNames(idx)
 	for i, col := range idx.Columns {
 		if i != 0 {
 			fmt.Fprint(buf, ", ")
 		}
 
-		t := columnToGoType(col)
-		if i+1 < len(idx.Columns) && t == columnToGoType(idx.Columns[i+1]) {
-			fmt.Fprintf(buf, "%s", camelize(col.Name))
+		t := columnToGoType(datatype, col)
+		if i+1 < len(idx.Columns) && t == columnToGoType(datatype, idx.Columns[i+1]) {
+			fmt.Fprintf(buf, "%s", names[i])
 		} else {
-			fmt.Fprintf(buf, "%s %s", camelize(col.Name), t)
+			fmt.Fprintf(buf, "%s %s", names[i], t)
 		}
 
 	}
 	return buf.String()
 }
 func idxQueryArgs(idx reflector.Index) string {
-	buf := bytes.NewBuffer(nil)
-	for i, col := range idx.Columns {
-		if i != 0 {
-			fmt.Fprint(buf, ", ")
-		}
-		fmt.Fprintf(buf, "%s", camelize(col.Name))
+	return strings.Join(idxArgNames(idx), ", ")
+}
+
+// idxArgNames name the arguments taking the columns of idx, like those
+// of routines.
+func idxArgNames(idx reflector.Index) []string {
+	names := make([]string, 0, len(idx.Columns))
+	seen := make(map[string]bool)
+	for _, col := range idx.Columns {
+		names = append(names, routineArgName(col.Name, seen))
 	}
-	return buf.String()
+	return names
 }
 
 var singularToPlurar = map[string]string{
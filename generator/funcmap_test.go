@@ -0,0 +1,24 @@
+package generator
+
+import "testing"
+
+func TestDocComment(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Shown to users.", "// Shown to users."},
+		{"  padded  ", "// padded"},
+		{"First line.\nSecond line.", "// First line.\n// Second line."},
+		{"Paragraph.\n\n  Indented.\n", "// Paragraph.\n//\n// Indented."},
+		{"Windows\r\nlines\rand old Macs", "// Windows\n// lines\n// and old Macs"},
+		{"Not a /* block */ comment", "// Not a /* block */ comment"},
+		{"*/ closes nothing\n/* opens nothing", "// */ closes nothing\n// /* opens nothing"},
+	}
+
+	for _, tt := range tests {
+		if got := docComment(tt.text); got != tt.want {
+			t.Fatalf("docComment(%q)=%q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -21,11 +21,32 @@ var (
 	DefaultFileperm os.FileMode = 0644
 )
 
+// Decimal columns use these types. By default they're generated in
+// common.go. To use another implementation, set DecimalImport to the
+// package providing it, for instance "github.com/shopspring/decimal"
+// with "decimal.Decimal" and "decimal.NullDecimal". The types must
+// implement sql.Scanner and driver.Valuer.
+var (
+	DecimalImport   = ""
+	DecimalType     = "Decimal"
+	NullDecimalType = "NullDecimal"
+)
+
 func Generate(dirname string, schema *reflector.DBSchema) error {
+	// the queries use `?` placeholders and LastInsertId, which Postgres
+	// doesn't know
+	if schema.Dialect == reflector.DialectPostgres {
+		return fmt.Errorf("can't generate a client for PostgreSQL schema %q", schema.Name)
+	}
 
 	files := map[string][]byte{}
 	t := template.New("root").Funcs(funcmap)
 
+	// shared by the tables and views
+	if _, err := t.New("enums").Parse(tmpl.EnumTemplate); err != nil {
+		return err
+	}
+
 	compileAndAdd := func(tname, tcontent string, tvalue interface{}) error {
 		buf := bytes.NewBuffer(nil)
 		subT, err := t.New(tname).Parse(tcontent)
@@ -55,11 +76,27 @@ func Generate(dirname string, schema *reflector.DBSchema) error {
 		}
 	}
 
-	needsTime := func(tbl reflector.Table) bool {
-		if tbl.Has("created_at") != nil || tbl.Has("updated_at") != nil {
-			return true
+	// the package of the decimal types, if the columns need it
+	decimalImport := func(cols []reflector.Column) string {
+		for _, col := range cols {
+			if col.Type == reflector.SQLDecimal {
+				return DecimalImport
+			}
 		}
-		for _, col := range tbl.Columns {
+		return ""
+	}
+
+	hasJSONField := func(cols []reflector.Column) bool {
+		for _, col := range cols {
+			if col.Type == reflector.SQLJSON {
+				return true
+			}
+		}
+		return false
+	}
+
+	hasTimeField := func(cols []reflector.Column) bool {
+		for _, col := range cols {
 			if col.Type == reflector.SQLTime && !col.Nullable {
 				return true
 			}
@@ -67,17 +104,101 @@ func Generate(dirname string, schema *reflector.DBSchema) error {
 		return false
 	}
 
+	// columns that a write leaves to the database, and those that its
+	// BEFORE triggers assign, which Create and Update read back using
+	// the primary key
+	refreshed := func(tbl reflector.Table, sets []reflector.Column, event string) []reflector.Column {
+		if tbl.Pk == nil {
+			return nil
+		}
+		var (
+			cols []reflector.Column
+			sent = make(map[string]bool)
+			seen = make(map[string]bool)
+		)
+		for _, col := range sets {
+			sent[col.Name] = true
+		}
+		for _, col := range tbl.Columns {
+			if !sent[col.Name] && !tbl.InPk(col.Name) {
+				seen[col.Name] = true
+				cols = append(cols, col)
+			}
+		}
+		for _, trg := range tbl.TriggersOn(event) {
+			if trg.Timing != "BEFORE" {
+				continue
+			}
+			for _, name := range trg.AssignedColumns() {
+				if col := tbl.Has(name); col != nil && !seen[name] {
+					seen[name] = true
+					cols = append(cols, *col)
+				}
+			}
+		}
+		return cols
+	}
+
+	// the column of the primary key that Create reads back with
+	// LastInsertId, if any
+	pkAutoIncrement := func(tbl reflector.Table) *reflector.Column {
+		if tbl.Pk == nil {
+			return nil
+		}
+		for i, col := range tbl.Pk.Columns {
+			if col.ParseExtra().AutoIncrement {
+				return &tbl.Pk.Columns[i]
+			}
+		}
+		return nil
+	}
+
 	for _, tbl := range schema.Tables {
 		basename := pluralize(tbl.Name)
 		filename := basename + ".go"
 		testFilename := basename + "_test.go"
 
+		enums := enumTypes(singularize(export(camelize(pluralize(tbl.Name)))), tbl.Columns)
+
+		// stamped by Create and Update, unless the database does it
+		createdAt, updatedAt := tbl.Has("created_at"), tbl.Has("updated_at")
+		if createdAt != nil && !createdAt.SetOnInsert() {
+			createdAt = nil
+		}
+		if updatedAt != nil && !updatedAt.SetOnUpdate() {
+			updatedAt = nil
+		}
+
+		var updateRefreshed []reflector.Column
+		if len(updateFields(tbl)) != 0 {
+			updateRefreshed = refreshed(tbl, updateFields(tbl), "UPDATE")
+		}
+
+		// a column like `key` takes the name of the Key method
+		hasKeyField := false
+		for _, col := range tbl.Columns {
+			hasKeyField = hasKeyField || export(camelize(col.Name)) == "Key"
+		}
+
 		tval := map[string]interface{}{
 			"DB":           schema,
 			"Tbl":          tbl,
-			"HasCreatedAt": tbl.Has("created_at"),
-			"HasUpdatedAt": tbl.Has("updated_at"),
-			"NeedsTime":    needsTime(tbl),
+			"HasKeyField":  hasKeyField,
+			"Enums":        enums,
+			"HasSet":       hasSet(enums),
+			"HasCreatedAt": createdAt,
+			"HasUpdatedAt": updatedAt,
+			"NeedsTime":    createdAt != nil || updatedAt != nil || hasTimeField(tbl.Columns),
+			"NeedsJSON":    hasJSONField(tbl.Columns),
+
+			"DecimalImport": decimalImport(tbl.Columns),
+
+			"PkAutoIncrement": pkAutoIncrement(tbl),
+			"InsertColumns":   insertFields(tbl),
+			"UpdateColumns":   updateFields(tbl),
+			"CreateRefreshed": refreshed(tbl, insertFields(tbl), "INSERT"),
+			"UpdateRefreshed": updateRefreshed,
+			"Validations":     validations(tbl),
 		}
 
 		for tname, tcontent := range map[string]string{
@@ -91,6 +212,28 @@ func Generate(dirname string, schema *reflector.DBSchema) error {
 		}
 	}
 
+	for _, view := range schema.Views {
+		filename := pluralize(view.Name) + ".go"
+
+		enums := enumTypes(singularize(export(camelize(pluralize(view.Name)))), view.Columns)
+
+		tval := map[string]interface{}{
+			"DB":        schema,
+			"View":      view,
+			"Enums":     enums,
+			"HasSet":    hasSet(enums),
+			"NeedsTime": hasTimeField(view.Columns),
+			"NeedsJSON": hasJSONField(view.Columns),
+
+			"DecimalImport": decimalImport(view.Columns),
+		}
+
+		err := compileAndAdd(filename, tmpl.ViewTemplate, tval)
+		if err != nil {
+			return err
+		}
+	}
+
 	return createFiles(filepath.Join(dirname, schema.Name), files)
 }
 
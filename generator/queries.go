@@ -10,7 +10,7 @@ import (
 	"github.com/aybabtme/sequel/reflector"
 )
 
-func createQuery(tbl reflector.Table) string {
+func createQuery(d reflector.Dialect, tbl reflector.Table) string {
 	query := `
 INSERT INTO %s (
 %s
@@ -18,18 +18,18 @@ INSERT INTO %s (
 
 	cols := bytes.NewBuffer(nil)
 	w := tabwriter.NewWriter(cols, 4, 8, 0, ' ', 0)
-	for i, col := range setFields(tbl) {
+	for i, col := range insertFields(tbl) {
 		if i == 0 {
-			fmt.Fprintf(w, "\t%s", escape(col.Name))
+			fmt.Fprintf(w, "\t%s", d.Quote(col.Name))
 		} else {
-			fmt.Fprintf(w, "\n\t, %s", escape(col.Name))
+			fmt.Fprintf(w, "\n\t, %s", d.Quote(col.Name))
 		}
 	}
 	w.Flush()
 
 	vals := bytes.NewBuffer(nil)
 	w = tabwriter.NewWriter(vals, 4, 8, 0, ' ', 0)
-	for i := range setFields(tbl) {
+	for i := range insertFields(tbl) {
 		if i == 0 {
 			fmt.Fprintf(w, "?")
 		} else {
@@ -39,10 +39,10 @@ INSERT INTO %s (
 	w.Flush()
 	vals.WriteString("")
 
-	return "`" + fmt.Sprintf(query, tbl.Name, cols.String(), vals.String()) + "`"
+	return rawString(fmt.Sprintf(query, d.Quote(tbl.Name), cols.String(), vals.String()))
 }
 
-func retrieveQuery(tbl reflector.Table) string {
+func retrieveQuery(d reflector.Dialect, tbl reflector.Table) string {
 
 	query := `
 SELECT %s
@@ -50,41 +50,57 @@ FROM %s
 WHERE %s
 LIMIT 1`
 
-	return "`" + fmt.Sprintf(
+	return rawString(fmt.Sprintf(
 		query,
-		selectString(tbl),
-		tbl.Name,
-		whereString(tbl),
-	) + "`"
+		selectString(d, tbl),
+		d.Quote(tbl.Name),
+		whereString(d, tbl),
+	))
 }
 
-func updateQuery(tbl reflector.Table) string {
+func updateQuery(d reflector.Dialect, tbl reflector.Table) string {
 	query := `
 UPDATE %s
 SET %s
 WHERE %s`
 
-	return "`" + fmt.Sprintf(
+	return rawString(fmt.Sprintf(
 		query,
-		tbl.Name,
-		setString(tbl),
-		whereString(tbl),
-	) + "`"
+		d.Quote(tbl.Name),
+		setString(d, tbl),
+		whereString(d, tbl),
+	))
 }
 
-func deleteQuery(tbl reflector.Table) string {
+func deleteQuery(d reflector.Dialect, tbl reflector.Table) string {
 	query := `
 DELETE FROM %s
 WHERE %s`
 
-	return "`" + fmt.Sprintf(
+	return rawString(fmt.Sprintf(
 		query,
-		tbl.Name,
-		whereString(tbl),
-	) + "`"
+		d.Quote(tbl.Name),
+		whereString(d, tbl),
+	))
 }
 
-func listQuery(tbl reflector.Table) string {
+// refreshQuery reads back the given columns of a row, by primary key.
+func refreshQuery(d reflector.Dialect, tbl reflector.Table, cols []reflector.Column) string {
+	query := `
+SELECT %s
+FROM %s
+WHERE %s
+LIMIT 1`
+
+	return rawString(fmt.Sprintf(
+		query,
+		selectString(d, reflector.Table{Columns: cols}),
+		d.Quote(tbl.Name),
+		whereString(d, tbl),
+	))
+}
+
+func listQuery(d reflector.Dialect, tbl reflector.Table) string {
 	query := `
 SELECT %s
 FROM %s
@@ -92,15 +108,15 @@ ORDER BY %s
 LIMIT 10000
 OFFSET ?`
 
-	return "`" + fmt.Sprintf(
+	return rawString(fmt.Sprintf(
 		query,
-		selectString(tbl),
-		tbl.Name,
-		orderByString(tbl),
-	) + "`"
+		selectString(d, tbl),
+		d.Quote(tbl.Name),
+		orderByString(d, tbl),
+	))
 }
 
-func listIndex(tbl reflector.Table, idx reflector.Index) string {
+func listIndex(d reflector.Dialect, tbl reflector.Table, idx reflector.Index) string {
 	query := `
 SELECT %s
 FROM %s
@@ -109,105 +125,164 @@ ORDER BY %s
 LIMIT 10000
 OFFSET ?`
 
-	return "`" + fmt.Sprintf(
+	return rawString(fmt.Sprintf(
+		query,
+		selectString(d, tbl),
+		d.Quote(tbl.Name),
+		whereIdxString(d, idx),
+		orderByIdxString(d, idx),
+	))
+}
+
+func viewListQuery(d reflector.Dialect, view reflector.View) string {
+	query := `
+SELECT %s
+FROM %s
+ORDER BY %s
+LIMIT 10000
+OFFSET ?`
+
+	return rawString(fmt.Sprintf(
+		query,
+		selectString(d, viewTable(view)),
+		d.Quote(view.Name),
+		d.Quote(view.Columns[0].Name),
+	))
+}
+
+// viewListWhereQuery leaves the WHERE clause as a verb, to be
+// formatted with the caller's condition. Any other % is escaped.
+func viewListWhereQuery(d reflector.Dialect, view reflector.View) string {
+	query := `
+SELECT %s
+FROM %s
+WHERE %%s
+ORDER BY %s
+LIMIT 10000
+OFFSET ?`
+
+	escape := strings.NewReplacer("%", "%%").Replace
+	return rawString(fmt.Sprintf(
 		query,
-		selectString(tbl),
-		tbl.Name,
-		whereIdxString(idx),
-		orderByIdxString(idx),
-	) + "`"
+		escape(selectString(d, viewTable(view))),
+		escape(d.Quote(view.Name)),
+		escape(d.Quote(view.Columns[0].Name)),
+	))
 }
 
-func selectString(tbl reflector.Table) string {
+// viewTable lets the query builders of tables work on views.
+func viewTable(view reflector.View) reflector.Table {
+	return reflector.Table{Name: view.Name, Columns: view.Columns}
+}
+
+func selectString(d reflector.Dialect, tbl reflector.Table) string {
 	selects := bytes.NewBuffer(nil)
 	w := tabwriter.NewWriter(selects, 4, 8, 0, ' ', 0)
 	for i, col := range tbl.Columns {
 		if i == 0 {
-			fmt.Fprintf(w, "\n\t%s", escape(col.Name))
+			fmt.Fprintf(w, "\n\t%s", d.Quote(col.Name))
 		} else {
-			fmt.Fprintf(w, "\n\t, %s", escape(col.Name))
+			fmt.Fprintf(w, "\n\t, %s", d.Quote(col.Name))
 		}
 	}
 	w.Flush()
 	return selects.String()
 }
 
-func whereString(tbl reflector.Table) string {
+// whereString finds a row by its primary key, or else by all its fields,
+// which can then be NULL.
+func whereString(d reflector.Dialect, tbl reflector.Table) string {
 	wheres := bytes.NewBuffer(nil)
 	w := tabwriter.NewWriter(wheres, 4, 8, 0, ' ', 0)
 	for i, col := range whereFields(tbl) {
+		op := "="
+		if tbl.Pk == nil && col.Nullable {
+			op = nullSafeEqual(d)
+		}
 		if i == 0 {
-			fmt.Fprintf(w, "\n\t%s\t = ?", escape(col.Name))
+			fmt.Fprintf(w, "\n\t%s\t %s ?", d.Quote(col.Name), op)
 		} else {
-			fmt.Fprintf(w, "\n\tAND %s\t = ?", escape(col.Name))
+			fmt.Fprintf(w, "\n\tAND %s\t %s ?", d.Quote(col.Name), op)
 		}
 	}
 	w.Flush()
 	return wheres.String()
 }
 
-func whereIdxString(idx reflector.Index) string {
+// nullSafeEqual is the operator comparing a column to a value, true
+// when both are NULL.
+func nullSafeEqual(d reflector.Dialect) string {
+	switch d {
+	case reflector.DialectSQLite:
+		return "IS"
+	case reflector.DialectPostgres:
+		return "IS NOT DISTINCT FROM"
+	}
+	return "<=>"
+}
+
+func whereIdxString(d reflector.Dialect, idx reflector.Index) string {
 	wheres := bytes.NewBuffer(nil)
 	w := tabwriter.NewWriter(wheres, 4, 8, 0, ' ', 0)
 	for i, col := range idx.Columns {
 		if i == 0 {
-			fmt.Fprintf(w, "\n\t%s\t = ?", escape(col.Name))
+			fmt.Fprintf(w, "\n\t%s\t = ?", d.Quote(col.Name))
 		} else {
-			fmt.Fprintf(w, "\n\tAND %s\t = ?", escape(col.Name))
+			fmt.Fprintf(w, "\n\tAND %s\t = ?", d.Quote(col.Name))
 		}
 	}
 	w.Flush()
 	return wheres.String()
 }
 
-func setString(tbl reflector.Table) string {
+func setString(d reflector.Dialect, tbl reflector.Table) string {
 	sets := bytes.NewBuffer(nil)
 	w := tabwriter.NewWriter(sets, 4, 8, 0, ' ', 0)
-	for i, col := range setFields(tbl) {
+	for i, col := range updateFields(tbl) {
 		if i == 0 {
-			fmt.Fprintf(w, "\n\t%s\t = ?", escape(col.Name))
+			fmt.Fprintf(w, "\n\t%s\t = ?", d.Quote(col.Name))
 		} else {
-			fmt.Fprintf(w, "\n\t, %s\t = ?", escape(col.Name))
+			fmt.Fprintf(w, "\n\t, %s\t = ?", d.Quote(col.Name))
 		}
 	}
 	w.Flush()
 	return sets.String()
 }
 
-func orderByString(tbl reflector.Table) string {
+func orderByString(d reflector.Dialect, tbl reflector.Table) string {
 	// order by pk index, or by some index, or fallback to
 	// the first column in the table
 	if tbl.Pk != nil {
 		for _, col := range tbl.Pk.Parts {
 			if col.IsAscending {
-				return escape(col.ColumnName)
+				return d.Quote(col.ColumnName)
 			}
 		}
 	}
 	for _, idx := range tbl.Indices {
 		for _, col := range idx.Parts {
 			if col.IsAscending {
-				return escape(col.ColumnName)
+				return d.Quote(col.ColumnName)
 			}
 		}
 	}
 	log.Printf("Table %q doesn't have an ordered column in one of its primary key or index. "+
 		"Ordering will be done using column %q as a fallback.", tbl.Name, tbl.Columns[0].Name)
-	return escape(tbl.Columns[0].Name)
+	return d.Quote(tbl.Columns[0].Name)
 }
 
-func orderByIdxString(idx reflector.Index) string {
+func orderByIdxString(d reflector.Dialect, idx reflector.Index) string {
 	// order by some index, or fallback to
 	// the first column in the table
 	for _, col := range idx.Parts {
 		if col.IsAscending {
-			return escape(col.ColumnName)
+			return d.Quote(col.ColumnName)
 		}
 	}
 	log.Printf("Index %q doesn't have any ordered column."+
 		"Ordering will be done using column %q as a fallback.",
 		idx.KeyName, idx.Columns[0].Name)
-	return escape(idx.Columns[0].Name)
+	return d.Quote(idx.Columns[0].Name)
 }
 
 func whereFields(tbl reflector.Table) []reflector.Column {
@@ -217,218 +292,33 @@ func whereFields(tbl reflector.Table) []reflector.Column {
 	return tbl.Columns
 }
 
-func setFields(tbl reflector.Table) []reflector.Column {
+// insertFields are the columns that Create sets, leaving out those the
+// database assigns. A key is set unless it's auto-incremented, for the
+// row to be found again.
+func insertFields(tbl reflector.Table) []reflector.Column {
 	var sets []reflector.Column
 	for _, col := range tbl.Columns {
-		if str, ok := col.Extra.([]byte); ok {
-			if string(str) == "auto_increment" {
-				continue
-			}
+		if col.SetOnInsert() || tbl.InPk(col.Name) && !col.ParseExtra().AutoIncrement {
+			sets = append(sets, col)
 		}
-		sets = append(sets, col)
 	}
 	return sets
 }
 
-func escape(colName string) string {
-	switch strings.ToLower(colName) {
-	default:
-		return colName
-	case "accessible",
-		"add",
-		"all",
-		"alter",
-		"analyze",
-		"and",
-		"asasc",
-		"asensitive",
-		"beforebetween",
-		"bigint",
-		"binaryblob",
-		"both",
-		"bycall",
-		"cascade",
-		"case",
-		"changechar",
-		"charactercheckcollate",
-		"column",
-		"conditionconstraint",
-		"continue",
-		"convert",
-		"create",
-		"crosscurrent_date",
-		"current_time",
-		"current_timestampcurrent_user",
-		"cursor",
-		"database",
-		"databasesday_hour",
-		"day_microsecond",
-		"day_minute",
-		"day_second",
-		"dec",
-		"decimal",
-		"declare",
-		"default",
-		"delayed",
-		"delete",
-		"desc",
-		"describe",
-		"deterministic",
-		"distinct",
-		"distinctrow",
-		"div",
-		"double",
-		"drop",
-		"dual",
-		"each",
-		"else",
-		"elseif",
-		"enclosed",
-		"escaped",
-		"exists",
-		"exit",
-		"explain",
-		"false",
-		"fetchfloatfloat4",
-		"float8",
-		"for",
-		"force",
-		"foreign",
-		"from",
-		"fulltext",
-		"get",
-		"grantgroup",
-		"having",
-		"high_priorityhour_microsecond",
-		"hour_minute",
-		"hour_second",
-		"if",
-		"ignore",
-		"in",
-		"index",
-		"infile",
-		"innerinout",
-		"insensitive",
-		"insert",
-		"int",
-		"int1",
-		"int2",
-		"int3",
-		"int4",
-		"int8",
-		"integer",
-		"interval",
-		"into",
-		"io_after_gtids",
-		"io_before_gtids",
-		"is",
-		"iterate",
-		"join",
-		"key",
-		"keys",
-		"kill",
-		"leading",
-		"leave",
-		"left",
-		"like",
-		"limit",
-		"linear",
-		"linesload",
-		"localtimelocaltimestamp",
-		"lock",
-		"long",
-		"longblob",
-		"longtext",
-		"loop",
-		"low_priority",
-		"master_bind",
-		"master_ssl_verify_server_certmatchmaxvalue",
-		"mediumblob",
-		"mediumintmediumtext",
-		"middleintminute_microsecond",
-		"minute_second",
-		"mod",
-		"modifies",
-		"natural",
-		"not",
-		"no_write_to_binlog",
-		"null",
-		"numeric",
-		"on",
-		"optimize",
-		"optimizer_costs",
-		"option",
-		"optionally",
-		"or",
-		"orderout",
-		"outeroutfile",
-		"partition",
-		"precisionprimary",
-		"procedure",
-		"purgerangeread",
-		"readsread_write",
-		"real",
-		"references",
-		"regexp",
-		"release",
-		"rename",
-		"repeat",
-		"replace",
-		"require",
-		"resignal",
-		"restrict",
-		"return",
-		"revoke",
-		"right",
-		"rlikeschema",
-		"schemas",
-		"second_microsecond",
-		"select",
-		"sensitive",
-		"separatorset",
-		"show",
-		"signal",
-		"smallint",
-		"spatial",
-		"specific",
-		"sql",
-		"sqlexception",
-		"sqlstate",
-		"sqlwarning",
-		"sql_big_result",
-		"sql_calc_found_rows",
-		"sql_small_result",
-		"ssl",
-		"starting",
-		"straight_jointable",
-		"terminated",
-		"then",
-		"tinyblob",
-		"tinyint",
-		"tinytext",
-		"to",
-		"trailing",
-		"trigger",
-		"true",
-		"undo",
-		"unionunique",
-		"unlock",
-		"unsigned",
-		"update",
-		"usageuse",
-		"using",
-		"utc_date",
-		"utc_time",
-		"utc_timestamp",
-		"values",
-		"varbinaryvarchar",
-		"varcharacter",
-		"varying",
-		"when",
-		"wherewhilewith",
-		"writexor",
-		"year_month",
-		"zerofill":
-		return "`+\"`\"+`" + colName + "`+\"`\"+`"
+// updateFields are the columns that Update sets, leaving out those the
+// database assigns, and the primary key that finds the row.
+func updateFields(tbl reflector.Table) []reflector.Column {
+	var sets []reflector.Column
+	for _, col := range tbl.Columns {
+		if col.SetOnUpdate() && !tbl.InPk(col.Name) {
+			sets = append(sets, col)
+		}
 	}
+	return sets
+}
+
+// rawString is the query as a Go raw string literal, splicing in the
+// backticks it can't hold.
+func rawString(query string) string {
+	return "`" + strings.Replace(query, "`", "`+\"`\"+`", -1) + "`"
 }
@@ -0,0 +1,331 @@
+package generator
+
+import (
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aybabtme/sequel/reflector"
+)
+
+func TestQueriesQuoteIdentifiers(t *testing.T) {
+	id := reflector.Column{Name: "id", Type: reflector.SQLInteger, Extra: []byte("auto_increment")}
+	group := reflector.Column{Name: "group", Type: reflector.SQLString}
+	name := reflector.Column{Name: "first-name", Type: reflector.SQLString}
+	total := reflector.Column{Name: "total", Type: reflector.SQLDecimal, Extra: []byte("STORED GENERATED")}
+	tbl := reflector.Table{
+		Name:    "order",
+		Columns: []reflector.Column{name, group, id, total},
+		Pk: &reflector.Index{
+			KeyName: "PRIMARY",
+			Columns: []reflector.Column{id},
+			Parts:   []reflector.IndexPart{{ColumnName: "id", IsAscending: true}},
+		},
+	}
+	idx := reflector.Index{
+		KeyName: "group_idx",
+		Columns: []reflector.Column{group},
+		Parts:   []reflector.IndexPart{{ColumnName: "group", IsAscending: true}},
+	}
+	view := reflector.View{Name: "order-names", Columns: []reflector.Column{name}}
+
+	tests := []struct {
+		dialect reflector.Dialect
+		q       func(string) string
+	}{
+		{reflector.DialectMySQL, func(s string) string { return "`" + s + "`" }},
+		{reflector.DialectSQLite, func(s string) string { return `"` + s + `"` }},
+	}
+	for _, tt := range tests {
+		d, q := tt.dialect, tt.q
+		queries := []struct {
+			lit  string
+			want []string
+		}{
+			{createQuery(d, tbl), []string{"INSERT INTO " + q("order"), q("first-name"), q("group")}},
+			{retrieveQuery(d, tbl), []string{"FROM " + q("order"), q("id") + " = ?"}},
+			{updateQuery(d, tbl), []string{"UPDATE " + q("order"), ", " + q("group")}},
+			{deleteQuery(d, tbl), []string{"DELETE FROM " + q("order")}},
+			{refreshQuery(d, tbl, []reflector.Column{group}), []string{q("group"), "FROM " + q("order")}},
+			{listQuery(d, tbl), []string{"FROM " + q("order"), "ORDER BY " + q("id")}},
+			{listIndex(d, tbl, idx), []string{"FROM " + q("order"), q("group") + " = ?", "ORDER BY " + q("group")}},
+			{viewListQuery(d, view), []string{"FROM " + q("order-names"), "ORDER BY " + q("first-name")}},
+			{viewListWhereQuery(d, view), []string{"FROM " + q("order-names"), "WHERE %s"}},
+		}
+		for _, query := range queries {
+			sql := evalString(t, query.lit)
+			for _, want := range query.want {
+				if !strings.Contains(sql, want) {
+					t.Errorf("%v query\n%s\nlacks %q", d, sql, want)
+				}
+			}
+		}
+	}
+}
+
+func TestQueriesLeaveOutManagedColumns(t *testing.T) {
+	d := reflector.DialectMySQL
+	tbl := reflector.Table{
+		Name: "orders",
+		Columns: []reflector.Column{
+			{Name: "id", Type: reflector.SQLInteger, Extra: []byte("auto_increment")},
+			{Name: "price", Type: reflector.SQLDecimal},
+			{Name: "total", Type: reflector.SQLDecimal, Extra: []byte("STORED GENERATED")},
+			{Name: "created_at", Type: reflector.SQLTime, Extra: []byte("DEFAULT_GENERATED")},
+			{Name: "updated_at", Type: reflector.SQLTime, Extra: []byte("on update CURRENT_TIMESTAMP")},
+		},
+	}
+	tbl.Pk = &reflector.Index{KeyName: "PRIMARY", Columns: tbl.Columns[:1]}
+
+	tests := []struct {
+		query      string
+		has, lacks []string
+	}{
+		{createQuery(d, tbl), []string{"`price`", "`updated_at`"}, []string{"`id`", "`total`", "`created_at`"}},
+		{updateQuery(d, tbl), []string{"`price`", "`created_at`"}, []string{"`total`", "`updated_at`"}},
+	}
+	for _, tt := range tests {
+		sql := evalString(t, tt.query)
+		for _, want := range tt.has {
+			if !strings.Contains(sql, want) {
+				t.Errorf("query\n%s\nlacks %s", sql, want)
+			}
+		}
+		for _, unwanted := range tt.lacks {
+			if strings.Contains(sql, unwanted) {
+				t.Errorf("query\n%s\nsets %s", sql, unwanted)
+			}
+		}
+	}
+}
+
+func TestViewQueries(t *testing.T) {
+	d := reflector.DialectMySQL
+	view := reflector.View{
+		Name: "order_totals",
+		Columns: []reflector.Column{
+			{Name: "total", Type: reflector.SQLDecimal},
+			{Name: "id", Type: reflector.SQLInteger},
+			{Name: "paid_%", Type: reflector.SQLFloat},
+		},
+	}
+
+	list := evalString(t, viewListQuery(d, view))
+	if strings.Contains(list, "WHERE") {
+		t.Errorf("list query\n%s\nfilters rows", list)
+	}
+	where := fmt.Sprintf(evalString(t, viewListWhereQuery(d, view)), "`id` > ?")
+	for _, sql := range []string{list, where} {
+		total, id, paid := strings.Index(sql, "`total`"), strings.Index(sql, "`id`"), strings.Index(sql, "`paid_%`")
+		if total < 0 || total > id || id > paid {
+			t.Errorf("query\n%s\ndoesn't select the columns in order", sql)
+		}
+		if !strings.Contains(sql, "FROM `order_totals`") ||
+			!strings.HasSuffix(sql, "ORDER BY `total`\nLIMIT 10000\nOFFSET ?") {
+			t.Errorf("query\n%s\ndoesn't list the view by its first column", sql)
+		}
+	}
+	if !strings.Contains(where, "WHERE `id` > ?\nORDER BY") {
+		t.Errorf("query\n%s\nlacks the condition", where)
+	}
+}
+
+// evalString evaluates the Go string literal of a query, as generated.
+func evalString(t *testing.T, lit string) string {
+	tv, err := types.Eval(token.NewFileSet(), nil, token.NoPos, lit)
+	if err != nil {
+		t.Fatalf("evaluating %s: %v", lit, err)
+	}
+	return constant.StringVal(tv.Value)
+}
+
+func TestQueriesFindRowsByPrimaryKey(t *testing.T) {
+	d := reflector.DialectMySQL
+	uuid := reflector.Column{Name: "user_uuid", Type: reflector.SQLString}
+	tbl := reflector.Table{
+		Name: "accounts",
+		Columns: []reflector.Column{
+			{Name: "name", Type: reflector.SQLString},
+			uuid,
+			{Name: "id", Type: reflector.SQLInteger},
+		},
+		Pk: &reflector.Index{KeyName: "PRIMARY", Columns: []reflector.Column{uuid}},
+	}
+
+	var names []string
+	for _, col := range insertFields(tbl) {
+		names = append(names, col.Name)
+	}
+	if want := "name user_uuid id"; strings.Join(names, " ") != want {
+		t.Errorf("inserting %v, want %s", names, want)
+	}
+	names = names[:0]
+	for _, col := range updateFields(tbl) {
+		names = append(names, col.Name)
+	}
+	if want := "name id"; strings.Join(names, " ") != want {
+		t.Errorf("updating %v, want %s", names, want)
+	}
+
+	update := evalString(t, updateQuery(d, tbl))
+	where := update[strings.Index(update, "WHERE"):]
+	if !strings.Contains(where, "`user_uuid` = ?") || strings.Contains(where, "`id`") {
+		t.Errorf("update finds the row with\n%s", where)
+	}
+}
+
+func TestQueriesMatchNullsWithoutKey(t *testing.T) {
+	tbl := reflector.Table{
+		Name: "visits",
+		Columns: []reflector.Column{
+			{Name: "page", Type: reflector.SQLString},
+			{Name: "left_at", Type: reflector.SQLTime, Nullable: true},
+		},
+	}
+	tests := []struct {
+		d    reflector.Dialect
+		want []string
+	}{
+		{reflector.DialectMySQL, []string{"`page` = ?", "`left_at` <=> ?"}},
+		{reflector.DialectSQLite, []string{`"page" = ?`, `"left_at" IS ?`}},
+	}
+	for _, tt := range tests {
+		for _, query := range []string{updateQuery(tt.d, tbl), deleteQuery(tt.d, tbl)} {
+			query = strings.Join(strings.Fields(evalString(t, query)), " ")
+			where := query[strings.Index(query, "WHERE"):]
+			for _, want := range tt.want {
+				if !strings.Contains(where, want) {
+					t.Errorf("%v finds the row with %s, want %s", tt.d, where, want)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateRefusesPostgres(t *testing.T) {
+	schema := &reflector.DBSchema{Name: "shop", Dialect: reflector.DialectPostgres}
+	dir := t.TempDir()
+	if err := Generate(dir, schema); err == nil {
+		t.Fatal("generated a client for a PostgreSQL schema")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("wrote %d files", len(entries))
+	}
+}
+
+func TestGenerateCompositeKeys(t *testing.T) {
+	accountID := reflector.Column{Name: "account_id", Type: reflector.SQLInteger, Size: 8}
+	kind := reflector.Column{Name: "type", Type: reflector.SQLString}
+	seq := reflector.Column{Name: "seq", Type: reflector.SQLInteger, Size: 8}
+	offset := reflector.Column{Name: "offset", Type: reflector.SQLInteger, Size: 8}
+	d := reflector.Column{Name: "d", Type: reflector.SQLInteger, Size: 8}
+	key := reflector.Column{Name: "key", Type: reflector.SQLString}
+	tagID := reflector.Column{Name: "tag_id", Type: reflector.SQLInteger, Size: 8}
+	parts := []reflector.IndexPart{{ColumnName: "account_id", IsAscending: true}}
+	schema := &reflector.DBSchema{
+		Name:    "ledgerdb",
+		Dialect: reflector.DialectSQLite,
+		Tables: []reflector.Table{
+			{
+				Name:    "ledger",
+				Columns: []reflector.Column{accountID, kind, seq, {Name: "amount", Type: reflector.SQLInteger, Size: 8}, offset, d},
+				Pk:      &reflector.Index{KeyName: "PRIMARY", Columns: []reflector.Column{accountID, kind, seq}, Parts: parts},
+				Indices: []reflector.Index{{KeyName: "slot", NonUnique: true, Columns: []reflector.Column{offset, d}}},
+			},
+			{
+				// all key, nothing to update
+				Name:    "ledger_tags",
+				Columns: []reflector.Column{accountID, key, tagID},
+				Pk:      &reflector.Index{KeyName: "PRIMARY", Columns: []reflector.Column{accountID, key, tagID}, Parts: parts},
+			},
+		},
+	}
+	dir := t.TempDir()
+	if err := Generate(dir, schema); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file       string
+		has, lacks []string
+	}{
+		{"ledgers.go", []string{
+			"type LedgerKey struct",
+			"func (d Ledger) Key() LedgerKey",
+			"func (tbl *Ledgers) Retrieve(accountID int64, type_ string, seq int64) (*Ledger, bool, error)",
+			"tbl.retrieve.Query(accountID, type_, seq)",
+			"func (tbl *Ledgers) RetrieveKey(k LedgerKey) (*Ledger, bool, error)",
+			"func (tbl *Ledgers) ListBySlot(offset_, d_ int64, offset int) ([]Ledger, error)",
+			"tbl.idxSlot.Query(offset_, d_, offset)",
+			"tbl.update.Exec(append(d.updateFields(), d.pkFields()...)...)",
+			"tbl.delete.Exec(d.pkFields()...)",
+		}, nil},
+		{"ledger_tags.go", []string{
+			"type LedgerTagKey struct",
+			"Retrieve(accountID int64, key string, tagID int64)",
+		}, []string{"Update(", "UPDATE ", "Key() LedgerTagKey"}},
+	}
+	for _, tt := range tests {
+		src, err := os.ReadFile(filepath.Join(dir, schema.Name, tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tt.has {
+			if !strings.Contains(string(src), want) {
+				t.Errorf("%s lacks %q", tt.file, want)
+			}
+		}
+		for _, unwanted := range tt.lacks {
+			if strings.Contains(string(src), unwanted) {
+				t.Errorf("%s has %q", tt.file, unwanted)
+			}
+		}
+	}
+
+	runGenerated(t, schema, `
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestCompositeKeys(t *testing.T) {
+	fake := openFake(t, fakeTables{
+		"ledger": {{"account_id": int64(1), "type": "debit", "seq": int64(2), "amount": int64(30), "offset": int64(4), "d": int64(5)}},
+	})
+	db, err := NewDB(fake.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Ledger{AccountID: 1, Type: "debit", Seq: 2, Amount: 30, Offset: 4, D: 5}
+
+	got, ok, err := db.Ledgers.RetrieveKey(want.Key())
+	if err != nil || !ok || *got != want {
+		t.Fatalf("retrieved %+v, ok=%v, err=%v, want %+v", got, ok, err, want)
+	}
+	list, err := db.Ledgers.ListBySlot(4, 5, 0)
+	if err != nil || len(list) != 1 || list[0] != want {
+		t.Fatalf("listed %+v, err=%v, want %+v", list, err, want)
+	}
+
+	if err := db.Ledgers.Update(&want); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.LedgerTags.Delete(&LedgerTag{AccountID: 1, Key: "k", TagID: 3}); err != nil {
+		t.Fatal(err)
+	}
+	update := []driver.Value{int64(30), int64(4), int64(5), int64(1), "debit", int64(2)}
+	if args := fake.execs[0].args; !reflect.DeepEqual(args, update) {
+		t.Errorf("updated with %v, want %v", args, update)
+	}
+	if args := fake.execs[1].args; !reflect.DeepEqual(args, []driver.Value{int64(1), "k", int64(3)}) {
+		t.Errorf("deleted with %v, want the key", args)
+	}
+}
+`)
+}
@@ -0,0 +1,203 @@
+package generator
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aybabtme/sequel/reflector"
+)
+
+// routineMethod is the method generated on the client to call a stored
+// procedure or function.
+type routineMethod struct {
+	Name    string
+	Routine reflector.Routine
+	Ins     []goParam // The arguments of the method.
+	Args    []string  // The arguments bound in Query.
+	Outs    []goParam // The OUT and INOUT parameters, for procedures.
+	Returns string    // The Go type returned by functions.
+
+	Query     string    // Calls the routine.
+	SetVars   []goParam // INOUT parameters, set in session variables before the call.
+	OutsQuery string    // Reads the OUT and INOUT parameters after the call.
+}
+
+// goParam is a parameter of a routine, as seen in Go.
+type goParam struct {
+	Name  string // The Go identifier.
+	Type  string
+	Query string // For INOUT parameters, sets their session variable.
+}
+
+// routineMethods lists the methods to generate for the routines of
+// schema. Routines whose method would have the name of another are
+// skipped.
+func routineMethods(schema *reflector.DBSchema) []routineMethod {
+	var (
+		methods []routineMethod
+		seen    = make(map[string]bool)
+	)
+	for _, r := range schema.Routines {
+		m := routineMethod{
+			Name:    "Call" + export(camelize(r.Name)),
+			Routine: r,
+		}
+		if seen[m.Name] {
+			log.Printf("Skipping %s %q, a routine already generates method %s.", strings.ToLower(r.Type), r.Name, m.Name)
+			continue
+		}
+		seen[m.Name] = true
+
+		var (
+			args  []string
+			names = map[string]bool{}
+		)
+		for _, p := range r.Params {
+			name := routineArgName(p.Name, names)
+			switch p.Mode {
+			case "IN":
+				m.Ins = append(m.Ins, goParam{Name: name, Type: paramGoType(p.Column, false)})
+				m.Args = append(m.Args, name)
+				args = append(args, "?")
+			case "INOUT":
+				m.Ins = append(m.Ins, goParam{Name: name, Type: paramGoType(p.Column, false)})
+				m.SetVars = append(m.SetVars, goParam{
+					Name:  name,
+					Query: strconv.Quote("SET " + sessionVar(r, p) + " = ?"),
+				})
+				fallthrough
+			case "OUT":
+				m.Outs = append(m.Outs, goParam{
+					Name: routineArgName(p.Name+"_out", names),
+					Type: paramGoType(p.Column, true),
+				})
+				args = append(args, sessionVar(r, p))
+			}
+		}
+
+		call := schema.Dialect.Quote(r.Name) + "(" + strings.Join(args, ", ") + ")"
+		if r.IsFunction() {
+			m.Query = strconv.Quote("SELECT " + call)
+			ret := reflector.Column{Type: reflector.SQLBytes, Nullable: true}
+			if r.Returns != nil {
+				ret = *r.Returns
+			}
+			m.Returns = paramGoType(ret, true)
+		} else {
+			m.Query = strconv.Quote("CALL " + call)
+		}
+
+		if len(m.Outs) != 0 {
+			var vars []string
+			for _, p := range r.Params {
+				if p.Mode != "IN" {
+					vars = append(vars, sessionVar(r, p))
+				}
+			}
+			m.OutsQuery = strconv.Quote("SELECT " + strings.Join(vars, ", "))
+		}
+
+		methods = append(methods, m)
+	}
+	return methods
+}
+
+// hasProcedure tells if schema has procedures, which need helpers to
+// be called.
+func hasProcedure(schema *reflector.DBSchema) bool {
+	for _, r := range schema.Routines {
+		if !r.IsFunction() {
+			return true
+		}
+	}
+	return false
+}
+
+// routineImports lists the packages used by the methods calling the
+// routines of schema.
+func routineImports(schema *reflector.DBSchema) []string {
+	imports := map[string]bool{}
+	for _, m := range routineMethods(schema) {
+		if !m.Routine.IsFunction() {
+			imports["database/sql"] = true
+		}
+		params := append(append([]goParam{{Type: m.Returns}}, m.Ins...), m.Outs...)
+		for _, p := range params {
+			switch strings.TrimPrefix(p.Type, "*") {
+			case "time.Time":
+				imports["time"] = true
+			case "json.RawMessage":
+				imports["encoding/json"] = true
+			case DecimalType, NullDecimalType:
+				if DecimalImport != "" {
+					imports[DecimalImport] = true
+				}
+			}
+		}
+	}
+	var list []string
+	for pkg := range imports {
+		list = append(list, pkg)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// paramGoType is the Go type of a routine's parameter. Enums and sets
+// don't have types of their own, unlike in rows.
+func paramGoType(col reflector.Column, nullable bool) string {
+	col.Nullable = nullable
+	switch col.Type {
+	case reflector.SQLEnum, reflector.SQLSet:
+		col.Type = reflector.SQLString
+	}
+	return columnToGoType("", col)
+}
+
+// sessionVar is the variable receiving an OUT parameter, since drivers
+// can't bind them.
+func sessionVar(r reflector.Routine, p reflector.Param) string {
+	return "@" + reflector.DialectMySQL.Quote(r.Name+"_"+p.Name)
+}
+
+// routineArgName turns the name of a parameter into an unexported Go
+// identifier that is unique among names, which it's added to.
+func routineArgName(name string, names map[string]bool) string {
+	arg := camelize(name)
+	if arg == strings.ToUpper(arg) {
+		// like ID
+		arg = strings.ToLower(arg)
+	}
+	if r, size := utf8.DecodeRuneInString(arg); r != utf8.RuneError {
+		arg = string(unicode.ToLower(r)) + arg[size:]
+	}
+	if arg == "" {
+		arg = "arg"
+	}
+	for reserved[arg] || names[arg] {
+		arg += "_"
+	}
+	names[arg] = true
+	return arg
+}
+
+// reserved are the identifiers that can't name arguments: the keywords
+// of Go, and the names used in the generated methods, of routines and
+// of tables.
+var reserved = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true,
+	"continue": true, "default": true, "defer": true, "else": true,
+	"fallthrough": true, "for": true, "func": true, "go": true,
+	"goto": true, "if": true, "import": true, "interface": true,
+	"map": true, "package": true, "range": true, "return": true,
+	"select": true, "struct": true, "switch": true, "type": true,
+	"var": true,
+
+	"db": true, "q": true, "rs": true, "err": true, "ret": true,
+	"scan": true, "sql": true, "time": true, "json": true,
+	"tbl": true, "d": true, "list": true, "rows": true, "offset": true,
+}
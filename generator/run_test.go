@@ -0,0 +1,463 @@
+package generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/aybabtme/sequel/reflector"
+)
+
+func TestGeneratedCreateReadsBack(t *testing.T) {
+	schema := &reflector.DBSchema{
+		Name:    "shop",
+		Dialect: reflector.DialectSQLite,
+		Tables: []reflector.Table{
+			shopOrders,
+			{
+				Name: "events",
+				Columns: []reflector.Column{
+					{Name: "name", Type: reflector.SQLString},
+					{Name: "created_at", Type: reflector.SQLTime},
+					{Name: "updated_at", Type: reflector.SQLTime},
+				},
+			},
+		},
+	}
+
+	runGenerated(t, schema, `
+import (
+	"testing"
+	"time"
+)
+
+func TestCreate(t *testing.T) {
+	created := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	fake := openFake(t, fakeTables{
+		"orders": {{"id": int64(7), "total": int64(12), "created_at": created}},
+	})
+	fake.lastInsertID = 7
+	db, err := NewDB(fake.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := &Order{Total: 12}
+	if err := db.Orders.Create(order); err != nil {
+		t.Fatal(err)
+	}
+	if order.ID != 7 || !order.CreatedAt.Equal(created) {
+		t.Errorf("created %+v, want the id and created_at of the database", order)
+	}
+
+	event := &Event{Name: "launch"}
+	if err := db.Events.Create(event); err != nil {
+		t.Fatal(err)
+	}
+	if event.CreatedAt.IsZero() {
+		t.Errorf("created %+v, without stamping it", event)
+	}
+	if args := fake.execs[len(fake.execs)-1].args; len(args) != 3 {
+		t.Errorf("inserted %v, want the 3 columns", args)
+	}
+}
+`)
+}
+
+func TestGeneratedFindsRows(t *testing.T) {
+	schema := &reflector.DBSchema{
+		Name:    "shop",
+		Dialect: reflector.DialectSQLite,
+		Tables: []reflector.Table{
+			shopOrders,
+			{
+				Name: "visits",
+				Columns: []reflector.Column{
+					{Name: "page", Type: reflector.SQLString},
+					{Name: "hits", Type: reflector.SQLInteger, Size: 8},
+					{Name: "updated_at", Type: reflector.SQLTime, Nullable: true},
+				},
+			},
+		},
+	}
+
+	runGenerated(t, schema, `
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFind(t *testing.T) {
+	created := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	fake := openFake(t, fakeTables{
+		"orders": {{"id": int64(7), "total": int64(12), "created_at": created}},
+	})
+	db, err := NewDB(fake.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Order{ID: 7, Total: 12, CreatedAt: created}
+
+	order, ok, err := db.Orders.Retrieve(7)
+	if err != nil || !ok {
+		t.Fatalf("retrieved ok=%v, err=%v", ok, err)
+	}
+	if *order != want {
+		t.Errorf("retrieved %+v, want %+v", order, want)
+	}
+	for name, list := range map[string]func() ([]Order, error){
+		"List":           func() ([]Order, error) { return db.Orders.List(0) },
+		"ListByTotal": func() ([]Order, error) { return db.Orders.ListByTotal(12, 0) },
+	} {
+		orders, err := list()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(orders) != 1 || orders[0] != want {
+			t.Errorf("%s listed %+v, want %+v", name, orders, want)
+		}
+	}
+}
+
+func TestKeylessWrites(t *testing.T) {
+	fake := openFake(t, nil)
+	db, err := NewDB(fake.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	visit := &Visit{Page: "/", Hits: 3}
+	if err := db.Visits.Update(visit); err != nil {
+		t.Fatal(err)
+	}
+	if !visit.UpdatedAt.Valid {
+		t.Errorf("updated %+v, without stamping it", visit)
+	}
+	if err := db.Visits.Delete(visit); err != nil {
+		t.Fatal(err)
+	}
+
+	// found by all their fields, as they were before the update, even
+	// NULL ones
+	for _, exec := range fake.execs {
+		query := strings.Join(strings.Fields(exec.query), " ")
+		if !strings.Contains(query, "\"hits\" = ?") || !strings.Contains(query, "\"updated_at\" IS ?") {
+			t.Errorf("wrote with %s, want the nullable updated_at matched with IS", query)
+		}
+	}
+	update, del := fake.execs[0].args, fake.execs[1].args
+	if len(update) != 6 || !reflect.DeepEqual(update[3:5], []driver.Value{"/", int64(3)}) || update[5] != nil {
+		t.Errorf("updated with %v, want 3 fields set and the 3 fields before", update)
+	}
+	if len(del) != 3 || !reflect.DeepEqual(del[:2], []driver.Value{"/", int64(3)}) {
+		t.Errorf("deleted with %v, want the 3 fields", del)
+	}
+}
+`)
+}
+
+func TestGeneratedCallsProcedures(t *testing.T) {
+	schema := &reflector.DBSchema{
+		Name:    "shop",
+		Dialect: reflector.DialectSQLite,
+		Routines: []reflector.Routine{{
+			Name: "close_order",
+			Type: "PROCEDURE",
+			Params: []reflector.Param{
+				{Column: reflector.Column{Name: "order_id", Type: reflector.SQLInteger, Size: 4, Nullable: true}, Mode: "IN"},
+				{Column: reflector.Column{Name: "total", Type: reflector.SQLDecimal, Precision: 10, Scale: 2, Nullable: true}, Mode: "OUT"},
+				{Column: reflector.Column{Name: "note", Type: reflector.SQLString, Length: 20, Nullable: true}, Mode: "INOUT"},
+			},
+		}},
+	}
+
+	runGenerated(t, schema, `
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+	fake := openFake(t, nil)
+	fake.answers = map[string][]fakeSet{
+		"CALL": {
+			{cols: []string{"line"}, rows: [][]driver.Value{{int64(1)}, {int64(2)}}},
+			{cols: []string{"lines"}, rows: [][]driver.Value{{int64(2)}}},
+		},
+		"SELECT @": {
+			{cols: []string{"total", "note"}, rows: [][]driver.Value{{"12.50", "closed"}}},
+		},
+	}
+	db, err := NewDB(fake.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got [][]int64
+	total, note, err := db.CallCloseOrder(7, "open", func(set int, rs *sql.Rows) error {
+		got = append(got, nil)
+		for rs.Next() {
+			var n int64
+			if err := rs.Scan(&n); err != nil {
+				return err
+			}
+			got[set] = append(got[set], n)
+		}
+		return rs.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]int64{{1, 2}, {2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("scanned %v, want %v", got, want)
+	}
+	if total.Decimal.String() != "12.50" || note.String != "closed" {
+		t.Errorf("returned %v and %v, want the OUT parameters", total, note)
+	}
+
+	// the INOUT parameter is set, the procedure called with the IN one,
+	// then the OUT ones read
+	if len(fake.execs) != 3 {
+		t.Fatalf("executed %v", fake.execs)
+	}
+	if args := fake.execs[0].args; !reflect.DeepEqual(args, []driver.Value{"open"}) {
+		t.Errorf("set the INOUT parameter with %v", args)
+	}
+	if args := fake.execs[1].args; !reflect.DeepEqual(args, []driver.Value{int64(7)}) {
+		t.Errorf("called with %v", args)
+	}
+}
+`)
+}
+
+// shopOrders has an auto-incremented key, a column set by the database,
+// and an index.
+var shopOrders = reflector.Table{
+	Name: "orders",
+	Columns: []reflector.Column{
+		{Name: "id", Type: reflector.SQLInteger, Size: 8, Extra: []byte("auto_increment")},
+		{Name: "total", Type: reflector.SQLInteger, Size: 8},
+		{Name: "created_at", Type: reflector.SQLTime, Default: "CURRENT_TIMESTAMP", Extra: []byte("DEFAULT_GENERATED")},
+	},
+	Pk: &reflector.Index{
+		KeyName: "PRIMARY",
+		Columns: []reflector.Column{{Name: "id", Type: reflector.SQLInteger, Size: 8, Extra: []byte("auto_increment")}},
+		Parts:   []reflector.IndexPart{{ColumnName: "id", IsAscending: true}},
+	},
+	Indices: []reflector.Index{{
+		KeyName:   "total",
+		NonUnique: true,
+		Columns:   []reflector.Column{{Name: "total", Type: reflector.SQLInteger, Size: 8}},
+		Parts:     []reflector.IndexPart{{ColumnName: "total", IsAscending: true}},
+	}},
+}
+
+// runGenerated generates the client of schema, and runs the Go `test`
+// in its package with `go test`, against the database of fakeSource.
+// The generated tests of the client and tables, which need a real
+// database, are left out; those of common.go are run.
+func runGenerated(t *testing.T, schema *reflector.DBSchema, test string) {
+	if testing.Short() {
+		t.Skip("builds the generated client")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("needs the go command")
+	}
+
+	dir := t.TempDir()
+	if err := Generate(dir, schema); err != nil {
+		t.Fatal(err)
+	}
+	pkg := filepath.Join(dir, schema.Name)
+	generated, err := filepath.Glob(filepath.Join(pkg, "*_test.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range generated {
+		if filepath.Base(name) == "common_test.go" {
+			continue
+		}
+		if err := os.Remove(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(dir, "go.mod"):       "module generated\n\ngo 1.21\n",
+		filepath.Join(pkg, "fake_test.go"): "package " + schema.Name + "\n" + fakeSource,
+		filepath.Join(pkg, "run_test.go"):  "package " + schema.Name + "\n" + test,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(gobin, "test", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("testing the generated client: %v\n%s", err, out)
+	}
+}
+
+// fakeSource is an in-memory database/sql driver, not a real database.
+// It answers the SELECTs with the columns they ask of every row of their
+// table, ignoring WHERE, and only records what's executed: tests check
+// what the queries match by their text and arguments. Other queries get
+// the result sets given for them.
+const fakeSource = `
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fakeTables map[string][]map[string]driver.Value
+
+type fakeDB struct {
+	db           *sql.DB
+	tables       fakeTables
+	lastInsertID int64
+	execs        []fakeExec
+
+	// answers are the result sets of the queries starting with their
+	// key, like CALLs, which don't read a table
+	answers map[string][]fakeSet
+}
+
+type fakeSet struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+type fakeExec struct {
+	query string
+	args  []driver.Value
+}
+
+var (
+	fakeOnce sync.Once
+	fakeMu   sync.Mutex
+	fakeDBs  = make(map[string]*fakeDB)
+)
+
+func openFake(t *testing.T, tables fakeTables) *fakeDB {
+	fakeOnce.Do(func() { sql.Register("fake", fakeDriver{}) })
+	fake := &fakeDB{tables: tables}
+	fakeMu.Lock()
+	fakeDBs[t.Name()] = fake
+	fakeMu.Unlock()
+
+	db, err := sql.Open("fake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	fake.db = db
+	return fake
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	fakeMu.Lock()
+	defer fakeMu.Unlock()
+	return fakeConn{fakeDBs[dsn]}, nil
+}
+
+type fakeConn struct{ fake *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.fake, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+// fakeTx doesn't isolate anything.
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	fake  *fakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.fake.execs = append(s.fake.execs, fakeExec{s.query, args})
+	return fakeResult{s.fake.lastInsertID}, nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	for prefix, sets := range s.fake.answers {
+		if strings.HasPrefix(strings.TrimSpace(s.query), prefix) {
+			s.fake.execs = append(s.fake.execs, fakeExec{s.query, args})
+			rows := &fakeRows{next: sets}
+			return rows, rows.NextResultSet()
+		}
+	}
+	sel := strings.SplitN(s.query, "SELECT", 2)
+	if len(sel) != 2 {
+		return nil, fmt.Errorf("unexpected query %q", s.query)
+	}
+	parts := strings.SplitN(sel[1], "FROM", 2)
+	unquote := func(name string) string { return strings.Trim(strings.TrimSpace(name), "\"") }
+	var cols []string
+	for _, col := range strings.Split(parts[0], ",") {
+		cols = append(cols, unquote(col))
+	}
+	rows := &fakeRows{cols: cols}
+	for _, row := range s.fake.tables[unquote(strings.Fields(parts[1])[0])] {
+		values := make([]driver.Value, len(cols))
+		for i, col := range cols {
+			values[i] = row[col]
+		}
+		rows.rows = append(rows.rows, values)
+	}
+	return rows, nil
+}
+
+type fakeResult struct{ id int64 }
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.id, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return 1, nil }
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+	next []fakeSet // the result sets after this one
+}
+
+func (r *fakeRows) HasNextResultSet() bool { return len(r.next) != 0 }
+func (r *fakeRows) NextResultSet() error {
+	if len(r.next) == 0 {
+		return io.EOF
+	}
+	r.cols, r.rows, r.next = r.next[0].cols, r.next[0].rows, r.next[1:]
+	return nil
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+`
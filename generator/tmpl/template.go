@@ -3,11 +3,15 @@ package tmpl
 //go:generate embed file -var ClientTemplate -source client.go.tmpl
 //go:generate embed file -var CommonTemplate -source common.go.tmpl
 //go:generate embed file -var TableTemplate -source table.go.tmpl
+//go:generate embed file -var ViewTemplate -source view.go.tmpl
+//go:generate embed file -var EnumTemplate -source enum.go.tmpl
 
 const (
-	ClientTemplate = "package {{.Name}}\n\n{{$db_name := .Name | camelize | export}}\n\ntype {{$db_name}}DB struct {\n    Querier\n\n{{range .Tables}}{{$tbl_name := .Name | camelize | pluralize | export}}\n    {{$tbl_name}} *{{$tbl_name}}{{end}}\n}\n\nfunc NewDB(querier Querier) (*{{$db_name}}DB, error) {\n    var err error\n    db := &{{$db_name}}DB{Querier: querier, }\n\n    {{range .Tables}}\n    {{$tbl_name := .Name | camelize | pluralize | export}}\n    db.{{$tbl_name}}, err = new{{$tbl_name}}(db)\n    if err != nil {\n        return nil, err\n    }\n    {{end}}\n\n    return db, nil\n}\n\n// Vars contains values set in a database.\nvar Vars = struct { {{range .Variables}}\n    {{.Name | camelize | export}} {{. | var_to_go_type}} {{end}}\n} { {{range .Variables}}\n    {{.Name | camelize | export}}: {{. | var_to_go_value}}, {{end}}\n}\n"
-	CommonTemplate = "package {{.Name}}\n\nimport (\n    \"database/sql\"\n    \"database/sql/driver\"\n    \"encoding/json\"\n    \"time\"\n    \"bytes\"\n\n    \"github.com/go-sql-driver/mysql\"\n)\n\nvar (\n    _ Querier = &sql.DB{}\n    _ Querier = &sql.Tx{}\n)\n\n// Querier is the interface implemented by types that can\ntype Querier interface {\n    Exec(query string, args ...interface{}) (sql.Result, error)\n    Prepare(query string) (*sql.Stmt, error)\n    Query(query string, args ...interface{}) (*sql.Rows, error)\n    QueryRow(query string, args ...interface{}) *sql.Row\n}\n\nvar (\n    _ RowScanner = &sql.Row{}\n    _ RowScanner = &sql.Rows{}\n)\n\ntype RowScanner interface {\n    Scan(dest ...interface{}) error\n}\n\ntype Updater interface {\n    fields() []interface{}\n    cols() []string\n    FieldByColName(field string) (interface{}, error)\n}\n\n// Scan sets the columns named in cols into dst, using the data\n// in rs.\nfunc Scan(rs RowScanner, dst Updater, cols []string) error {\n    toScan := make([]interface{}, 0, len(cols))\n    for _, col := range cols {\n        field, err := dst.FieldByColName(col)\n        if err != nil {\n            return err\n        }\n        toScan = append(toScan, field)\n    }\n    return rs.Scan(toScan...)\n}\n\n\n// Null types\n\ntype NullInt64 sql.NullInt64\n\nvar (\n    _ json.Unmarshaler = &NullInt64{}\n    _ driver.Value     = &NullInt64{}\n)\n\nfunc NewInt64(i int64) NullInt64 {\n    return NullInt64{Int64: i, Valid: true}\n}\n\nfunc (n *NullInt64) Scan(value interface{}) error {\n    sqln := new(sql.NullInt64)\n    err := sqln.Scan(value)\n    *n = NullInt64(*sqln)\n    return err\n}\n\nfunc (n NullInt64) Value() (driver.Value, error) {\n    return sql.NullInt64(n).Value()\n}\n\nfunc (n *NullInt64) UnmarshalJSON(data []byte) error {\n    if bytes.Equal(data, []byte(\"null\")) {\n        n.Valid = false\n        return nil\n    }\n    err := json.Unmarshal(data, &n.Int64)\n    n.Valid = (err == nil)\n    return err\n}\n\nfunc (n NullInt64) MarshalJSON() ([]byte, error) {\n    if !n.Valid {\n        return []byte(\"null\"), nil\n    }\n    return json.Marshal(n.Int64)\n}\n\ntype NullString sql.NullString\n\nvar (\n    _ json.Unmarshaler = &NullString{}\n    _ driver.Value     = &NullString{}\n)\n\nfunc NewString(s string) NullString {\n    return NullString{String: s, Valid: true}\n}\n\nfunc (n *NullString) Scan(value interface{}) error {\n    sqln := new(sql.NullString)\n    err := sqln.Scan(value)\n    *n = NullString(*sqln)\n    return err\n}\n\nfunc (n NullString) Value() (driver.Value, error) {\n    return sql.NullString(n).Value()\n}\n\nfunc (n *NullString) UnmarshalJSON(data []byte) error {\n    if bytes.Equal(data, []byte(\"null\")) {\n        n.Valid = false\n        return nil\n    }\n    err := json.Unmarshal(data, &n.String)\n    n.Valid = (err == nil)\n    return err\n}\n\nfunc (n NullString) MarshalJSON() ([]byte, error) {\n    if !n.Valid {\n        return []byte(\"null\"), nil\n    }\n    return json.Marshal(n.String)\n}\n\ntype NullFloat64 sql.NullFloat64\n\nvar (\n    _ json.Unmarshaler = &NullFloat64{}\n    _ driver.Value     = &NullFloat64{}\n)\n\nfunc NewFloat64(f float64) NullFloat64 {\n    return NullFloat64{Float64: f, Valid: true}\n}\n\nfunc (n *NullFloat64) Scan(value interface{}) error {\n    sqln := new(sql.NullFloat64)\n    err := sqln.Scan(value)\n    *n = NullFloat64(*sqln)\n    return err\n}\n\nfunc (n NullFloat64) Value() (driver.Value, error) {\n    return sql.NullFloat64(n).Value()\n}\n\nfunc (n *NullFloat64) UnmarshalJSON(data []byte) error {\n    if bytes.Equal(data, []byte(\"null\")) {\n        n.Valid = false\n        return nil\n    }\n    err := json.Unmarshal(data, &n.Float64)\n    n.Valid = (err == nil)\n    return err\n}\n\nfunc (n NullFloat64) MarshalJSON() ([]byte, error) {\n    if !n.Valid {\n        return []byte(\"null\"), nil\n    }\n    return json.Marshal(n.Float64)\n}\n\ntype NullBool sql.NullBool\n\nvar (\n    _ json.Unmarshaler = &NullBool{}\n    _ driver.Value     = &NullBool{}\n)\n\nfunc NewBool(b bool) NullBool {\n    return NullBool{Bool: b, Valid: true}\n}\n\nfunc (n *NullBool) Scan(value interface{}) error {\n    sqln := new(sql.NullBool)\n    err := sqln.Scan(value)\n    *n = NullBool(*sqln)\n    return err\n}\n\nfunc (n NullBool) Value() (driver.Value, error) {\n    return sql.NullBool(n).Value()\n}\n\nfunc (n *NullBool) UnmarshalJSON(data []byte) error {\n    if bytes.Equal(data, []byte(\"null\")) {\n        n.Valid = false\n        return nil\n    }\n    err := json.Unmarshal(data, &n.Bool)\n    n.Valid = (err == nil)\n    return err\n}\n\nfunc (n NullBool) MarshalJSON() ([]byte, error) {\n    if !n.Valid {\n        return []byte(\"null\"), nil\n    }\n    return json.Marshal(n.Bool)\n}\n\ntype NullTime mysql.NullTime\n\nvar (\n    _ json.Unmarshaler = &NullTime{}\n    _ driver.Value     = &NullTime{}\n)\n\nfunc NewTime(t time.Time) NullTime {\n    return NullTime{Time: t, Valid: true}\n}\n\nfunc (n *NullTime) Scan(value interface{}) error {\n    sqln := new(mysql.NullTime)\n    err := sqln.Scan(value)\n    *n = NullTime(*sqln)\n    return err\n}\n\nfunc (n NullTime) Value() (driver.Value, error) {\n    return mysql.NullTime(n).Value()\n}\n\nfunc (n *NullTime) UnmarshalJSON(data []byte) error {\n    if bytes.Equal(data, []byte(\"null\")) {\n        n.Valid = false\n        return nil\n    }\n    err := json.Unmarshal(data, &n.Time)\n    n.Valid = (err == nil)\n    return err\n}\n\nfunc (n NullTime) MarshalJSON() ([]byte, error) {\n    if !n.Valid {\n        return []byte(\"null\"), nil\n    }\n    return json.Marshal(n.Time)\n}\n\nfunc isCommandOnTableDenied(err error) bool {\n    e, ok := err.(*mysql.MySQLError)\n    if !ok {\n        return false\n    }\n    return e.Number == 1142\n}\n"
-	TableTemplate  = "package {{.DB.Name}}\n\nimport (\n    {{if .NeedsTime}}\"time\"{{end}}\n    \"database/sql\"\n    \"log\"\n    \"fmt\"\n)\n\n{{$db_name := .DB.Name | camelize | export}}\n{{$tbl := .Tbl}}\n{{$tbl_name := .Tbl.Name | camelize | pluralize | export}}\n{{$datatype :=  $tbl_name | singularize }}\n\nconst (\n    create{{$tbl_name}}SQL   = {{$tbl | createQuery}}\n\n    {{if $tbl.Pk}}retrieve{{$tbl_name}}SQL = {{$tbl | retrieveQuery }}\n    {{end}}\n    update{{$tbl_name}}SQL   = {{$tbl | updateQuery }}\n\n    delete{{$tbl_name}}SQL   = {{$tbl | deleteQuery }}\n\n    list{{$tbl_name}}SQL     = {{$tbl | listQuery }}\n\n    {{range $tbl.Indices}}{{$idxname := .KeyName | camelize | export}}\n    list{{$tbl_name}}Idx{{$idxname}}SQL = {{listIndex $tbl .}}\n    {{end}}\n)\n\n// {{$tbl_name}} provides operations on {{$datatype}}\n// stored in {{$db_name}}.\ntype {{$tbl_name}} struct {\n    db   Querier\n    Name string\n\n    create   *sql.Stmt\n    {{if $tbl.Pk}}retrieve *sql.Stmt {{end}}\n    update   *sql.Stmt\n    delete   *sql.Stmt\n    list     *sql.Stmt\n\n    {{range $tbl.Indices}}{{$idxname := .KeyName | camelize | export}}\n    idx{{.KeyName | camelize | export}} *sql.Stmt{{end}}\n}\n\nfunc new{{$tbl_name}}(db Querier) (*{{$tbl_name}}, error) {\n    var err error\n    tbl := &{{$tbl_name}}{db: db, Name: \"{{$tbl.Name}}\"}\n\n    bindings := []struct {\n        query string\n        stmt  **sql.Stmt\n    }{\n        {query: create{{$tbl_name}}SQL, stmt: &tbl.create},\n        {{if $tbl.Pk}}{query: retrieve{{$tbl_name}}SQL, stmt: &tbl.retrieve},{{end}}\n        {query: update{{$tbl_name}}SQL, stmt: &tbl.update},\n        {query: delete{{$tbl_name}}SQL, stmt: &tbl.delete},\n        {query: list{{$tbl_name}}SQL, stmt: &tbl.list},\n        // indices {{range $tbl.Indices}}{{$idxname := .KeyName | camelize | export}}\n        {query: list{{$tbl_name}}Idx{{$idxname}}SQL, stmt: &tbl.idx{{.KeyName | camelize | export}} }, {{end}}\n    }\n\n    for _, bind := range bindings {\n        (*bind.stmt), err = db.Prepare(bind.query)\n        switch {\n        case isCommandOnTableDenied(err):\n            log.Printf(\"unauthorized to perform query: %q\", bind.query)\n            return nil, nil // code trying to use this stmt should panic if they're not authorized\n        case err != nil:\n            return nil, fmt.Errorf(\"preparing query: %v, query:\\n%s\", err, bind.query)\n        }\n    }\n\n    return tbl, err\n}\n\n\n\n// {{$datatype}} represents a row in table {{$tbl_name}}.\ntype {{$datatype}} struct { {{range $tbl.Columns}}\n    {{.Name | camelize | export}} {{. | col_to_go_type}} {{end}}\n}\n\n// ensures that {{$datatype}} implements the Updater interface.\nvar _ Updater = &{{$datatype}}{}\n\nfunc (d {{$datatype}}) cols() []string {\n    return []string{ {{range .Columns}}\n        \"{{.Name}}\",{{end}}\n    }\n}\n\nfunc (d {{$datatype}}) fields() []interface{}{\n    return []interface{}{ {{range .Columns}}\n        &d.{{.Name | camelize | export}}, {{end}}\n    }\n}\n\n// FieldByColName returns the field in {{$datatype}} that represents the\n// column named `col`.\nfunc (d {{$datatype}}) FieldByColName(col string) (interface{}, error) {\n    switch col { {{range .Columns}}\n    case \"{{.Name}}\":\n        return &d.{{.Name | camelize | export}}, nil{{end}}\n    default:\n        return nil, fmt.Errorf(\"invalid column %q\", col)\n    }\n}\n\n\n{{if $tbl.Pk}}\n{{$pklen := len $tbl.Pk.Columns}}\n{{if eq $pklen 1}}\n{{$col := index $tbl.Pk.Columns 0}}\n{{$colname := $col.Name | camelize | export}}\n// Create a new {{$datatype}}.\nfunc (tbl *{{$tbl_name}}) Create(d *{{$datatype}}) error {\n\n    {{ $col := .HasUpdatedAt}}\n    {{if $col}}\n    {{if $col.Nullable}}\n    d.CreatedAt = NewTime(time.Now())\n    {{else}}\n    d.CreatedAt = time.Now().UTC().Truncate(time.Second)\n    {{end}}\n    {{end}}\n\n    // skip the ID\n    res, err := tbl.create.Exec(d.fields()[1:]...)\n    if err != nil {\n        return err\n    }\n\n    id, err := res.LastInsertId()\n    if err != nil {\n        return err\n    }\n\n    d.{{$colname}} = int(id)\n    return nil\n}\n\n// Retrieve an existing {{$datatype}} by ID.\nfunc (tbl *{{$tbl_name}}) Retrieve(id int64) (*{{$datatype}}, bool, error) {\n\n    rs, err := tbl.retrieve.Query(id)\n    switch err {\n    default:\n        return nil, false, err\n    case sql.ErrNoRows:\n        return nil, false, nil\n    case nil:\n        defer rs.Close()\n    }\n    if !rs.Next() {\n        return nil, false, nil\n    }\n    d := &{{$datatype}}{}\n    return d, true, Scan(rs, d, d.cols())\n}\n\n// Update an existing {{$datatype}} by ID.\nfunc (tbl *{{$tbl_name}}) Update(d *{{$datatype}}) error {\n    {{ $col := .HasUpdatedAt}}\n    {{if $col}}\n    {{if $col.Nullable}}\n    d.UpdatedAt = NewTime(time.Now())\n    {{else}}\n    d.UpdatedAt = time.Now().UTC().Truncate(time.Second)\n    {{end}}\n    {{end}}\n\n    _, err := tbl.update.Exec(append(d.fields()[1:], d.{{$colname}})...)\n    return err\n}\n\n// Delete an existing {{$datatype}} by ID.\nfunc (tbl *{{$tbl_name}}) Delete(d *{{$datatype}}) error {\n    _, err := tbl.delete.Exec(d.{{$colname}})\n    return err\n}\n{{else}}\n\n// Delete an existing {{$datatype}} by its fields (all fields must match).\nfunc (tbl *{{$tbl_name}}) Delete(d *{{$datatype}}) error {\n    _, err := tbl.delete.Exec(d.fields()...)\n    return err\n}\n{{end}}\n\n\n{{else}}\n\n// Create a new {{$datatype}}.\nfunc (tbl *{{$tbl_name}}) Create(d *{{$datatype}}) error {\n    {{if .HasCreatedAt}}\n    d.CreatedAt = NewTime(time.Now())\n    {{end}}\n    _, err := tbl.create.Exec(d.fields()...)\n    return err\n}\n\n// Update an existing {{$datatype}} by its fields (all fields must match).\nfunc (tbl *{{$tbl_name}}) Update(d *{{$datatype}}) error {\n    {{if .HasUpdatedAt}}\n    d.UpdatedAt = NewTime(time.Now())\n    {{end}}\n    _, err := tbl.update.Exec(d.fields()...)\n    return err\n}\n\n// Delete an existing {{$datatype}} by its fields (all fields must match).\nfunc (tbl *{{$tbl_name}}) Delete(d *{{$datatype}}) error {\n    _, err := tbl.delete.Exec(d.fields()...)\n    return err\n}\n\n{{end}}\n\n// List all {{$datatype}}s starting from an offset. Limited to 10k rows.\nfunc (tbl *{{$tbl_name}}) List(offset int) ([]{{$datatype}}, error) {\n    var list []{{$datatype}}\n\n    rows, err := tbl.list.Query(offset)\n    switch err {\n    default:\n        return nil, err\n    case sql.ErrNoRows:\n        return list, nil\n    case nil:\n        defer rows.Close()\n    }\n\n    for rows.Next() {\n        d := {{$datatype}}{}\n        if err := Scan(rows, &d, d.cols()); err != nil {\n            return list, err\n        }\n        list = append(list, d)\n    }\n\n    return list, rows.Err()\n}\n\n{{range $tbl.Indices}}{{$idxname := .KeyName | camelize | export}}\n// ListBy{{$idxname}} finds all {{$datatype}}s that match the query\n// on the index `{{.KeyName}}`, starting at `offset`, limited to 10k rows.\nfunc (tbl *{{$tbl_name}}) ListBy{{$idxname}}({{. | idx_list_args}}, offset int) ([]{{$datatype}}, error) {\n    var list []{{$datatype}}\n\n    rows, err := tbl.idx{{$idxname}}.Query({{. | idx_query_args}}, offset)\n    switch err {\n    default:\n        return nil, err\n    case sql.ErrNoRows:\n        return list, nil\n    case nil:\n        defer rows.Close()\n    }\n\n    for rows.Next() {\n        d := {{$datatype}}{}\n        if err := Scan(rows, &d, d.cols()); err != nil {\n            return list, err\n        }\n        list = append(list, d)\n    }\n\n    return list, rows.Err()\n}\n{{end}}\n"
+	ClientTemplate = "package {{.Name}}\n\nimport ({{range routine_imports .}}\n    \"{{.}}\"{{end}}\n)\n\n{{$db_name := .Name | camelize | export}}\n\ntype {{$db_name}}DB struct {\n    Querier\n\n{{range .Tables}}{{$tbl_name := .Name | camelize | pluralize | export}}\n    {{$tbl_name}} *{{$tbl_name}}{{end}}\n\n{{range .Views}}{{$view_name := .Name | camelize | pluralize | export}}\n    {{$view_name}} *{{$view_name}}{{end}}\n}\n\nfunc NewDB(querier Querier) (*{{$db_name}}DB, error) {\n    {{if or .Tables .Views}}var err error{{end}}\n    db := &{{$db_name}}DB{Querier: querier, }\n\n    {{range .Tables}}\n    {{$tbl_name := .Name | camelize | pluralize | export}}\n    db.{{$tbl_name}}, err = new{{$tbl_name}}(db)\n    if err != nil {\n        return nil, err\n    }\n    {{end}}\n\n    {{range .Views}}\n    {{$view_name := .Name | camelize | pluralize | export}}\n    db.{{$view_name}}, err = new{{$view_name}}(db)\n    if err != nil {\n        return nil, err\n    }\n    {{end}}\n\n    return db, nil\n}\n\n// Vars contains values set in a database.\nvar Vars = struct { {{range .Variables}}\n    {{.Name | camelize | export}} {{. | var_to_go_type}} {{end}}\n} { {{range .Variables}}\n    {{.Name | camelize | export}}: {{. | var_to_go_value}}, {{end}}\n}\n\n{{if has_procedure .}}\n// onOneConn runs f on a single connection, since the session variables\n// receiving OUT parameters don't outlive it. A transaction is started\n// unless querier already is one.\nfunc onOneConn(querier Querier, f func(Querier) error) error {\n    db, ok := querier.(interface {\n        Begin() (*sql.Tx, error)\n    })\n    if !ok {\n        return f(querier)\n    }\n    tx, err := db.Begin()\n    if err != nil {\n        return err\n    }\n    if err := f(tx); err != nil {\n        tx.Rollback()\n        return err\n    }\n    return tx.Commit()\n}\n\n// scanResultSets passes each result set of rs to scan, then closes rs.\nfunc scanResultSets(rs *sql.Rows, scan func(set int, rs *sql.Rows) error) error {\n    defer rs.Close()\n    for set := 0; ; set++ {\n        if scan != nil {\n            if err := scan(set, rs); err != nil {\n                return err\n            }\n        }\n        if !rs.NextResultSet() {\n            break\n        }\n    }\n    return rs.Err()\n}\n{{end}}\n\n{{range routine_methods .}}{{$m := .}}\n{{if .Routine.IsFunction}}\n// {{.Name}} returns the result of function {{.Routine.Name}}.{{if .Routine.Comment}}\n//\n{{doc .Routine.Comment}}{{end}}\nfunc (db *{{$db_name}}DB) {{.Name}}({{range $i, $p := .Ins}}{{if $i}}, {{end}}{{$p.Name}} {{$p.Type}}{{end}}) ({{.Returns}}, error) {\n    var ret {{.Returns}}\n    err := db.QueryRow({{.Query}}{{range .Args}}, {{.}}{{end}}).Scan(&ret)\n    return ret, err\n}\n{{else}}\n// {{.Name}} calls procedure {{.Routine.Name}}. Each result set it returns\n// is passed to scan, in order, and scan can be nil if there are none.{{if .Outs}}\n// It returns the OUT parameters{{range .Outs}} {{.Name}}{{end}}.{{end}}{{if .Routine.Comment}}\n//\n{{doc .Routine.Comment}}{{end}}\nfunc (db *{{$db_name}}DB) {{.Name}}({{range .Ins}}{{.Name}} {{.Type}}, {{end}}scan func(set int, rs *sql.Rows) error) ({{range .Outs}}{{.Name}} {{.Type}}, {{end}}err error) {\n    err = onOneConn(db.Querier, func(q Querier) error { {{range .SetVars}}\n        if _, err := q.Exec({{.Query}}, {{.Name}}); err != nil {\n            return err\n        }{{end}}\n        rs, err := q.Query({{$m.Query}}{{range .Args}}, {{.}}{{end}})\n        if err != nil {\n            return err\n        }\n        if err := scanResultSets(rs, scan); err != nil {\n            return err\n        }\n        {{if .Outs}}return q.QueryRow({{.OutsQuery}}).Scan({{range $i, $p := .Outs}}{{if $i}}, {{end}}&{{$p.Name}}{{end}}){{else}}return nil{{end}}\n    })\n    return\n}\n{{end}}\n{{end}}\n"
+	CommonTemplate = "package {{.Name}}\n\n{{$sqlite := eq .Dialect.Driver \"sqlite3\"}}\n\nimport (\n    \"database/sql\"\n    \"database/sql/driver\"\n    \"encoding/binary\"\n    \"encoding/json\"\n    \"fmt\"\n    \"math\"\n    {{if decimal_generated}}\"math/big\"{{end}}\n    \"strconv\"\n    {{if decimal_generated}}\"strings\"{{end}}\n    \"time\"\n    \"bytes\"\n    \"unicode/utf8\"\n    {{if not $sqlite}}\n\n    \"github.com/go-sql-driver/mysql\"{{end}}\n)\n\nvar (\n    _ Querier = &sql.DB{}\n    _ Querier = &sql.Tx{}\n)\n\n// Querier is the interface implemented by types that can\ntype Querier interface {\n    Exec(query string, args ...interface{}) (sql.Result, error)\n    Prepare(query string) (*sql.Stmt, error)\n    Query(query string, args ...interface{}) (*sql.Rows, error)\n    QueryRow(query string, args ...interface{}) *sql.Row\n}\n\nvar (\n    _ RowScanner = &sql.Row{}\n    _ RowScanner = &sql.Rows{}\n)\n\ntype RowScanner interface {\n    Scan(dest ...interface{}) error\n}\n\ntype Updater interface {\n    fields() []interface{}\n    cols() []string\n    FieldByColName(field string) (interface{}, error)\n}\n\n// Scan sets the columns named in cols into dst, using the data\n// in rs.\nfunc Scan(rs RowScanner, dst Updater, cols []string) error {\n    toScan := make([]interface{}, 0, len(cols))\n    for _, col := range cols {\n        field, err := dst.FieldByColName(col)\n        if err != nil {\n            return err\n        }\n        toScan = append(toScan, field)\n    }\n    return rs.Scan(toScan...)\n}\n\n\n// ValidationError is returned by the Validate methods of rows that\n// the database would reject.\ntype ValidationError struct {\n    Table  string\n    Column string\n    Reason string\n}\n\nfunc (e *ValidationError) Error() string {\n    return fmt.Sprintf(\"invalid %s.%s: %s\", e.Table, e.Column, e.Reason)\n}\n\n// charLen counts characters like the database does to enforce the\n// length of a column.\nfunc charLen(s string) int {\n    return utf8.RuneCountInString(s)\n}\n\n// Null types\n\ntype NullInt64 sql.NullInt64\n\nvar (\n    _ json.Unmarshaler = &NullInt64{}\n    _ driver.Value     = &NullInt64{}\n)\n\nfunc NewInt64(i int64) NullInt64 {\n    return NullInt64{Int64: i, Valid: true}\n}\n\nfunc (n *NullInt64) Scan(value interface{}) error {\n    sqln := new(sql.NullInt64)\n    err := sqln.Scan(value)\n    *n = NullInt64(*sqln)\n    return err\n}\n\nfunc (n NullInt64) Value() (driver.Value, error) {\n    return sql.NullInt64(n).Value()\n}\n\nfunc (n *NullInt64) UnmarshalJSON(data []byte) error {\n    if bytes.Equal(data, []byte(\"null\")) {\n        n.Valid = false\n        return nil\n    }\n    err := json.Unmarshal(data, &n.Int64)\n    n.Valid = (err == nil)\n    return err\n}\n\nfunc (n NullInt64) MarshalJSON() ([]byte, error) {\n    if !n.Valid {\n        return []byte(\"null\"), nil\n    }\n    return json.Marshal(n.Int64)\n}\n\n// NullUint64 holds unsigned bigints, which don't fit in a NullInt64.\ntype NullUint64 struct {\n    Uint64 uint64\n    Valid  bool\n}\n\nvar (\n    _ json.Unmarshaler = &NullUint64{}\n    _ driver.Value     = &NullUint64{}\n)\n\nfunc NewUint64(i uint64) NullUint64 {\n    return NullUint64{Uint64: i, Valid: true}\n}\n\nfunc (n *NullUint64) Scan(value interface{}) error {\n    n.Uint64, n.Valid = 0, false\n    var err error\n    switch v := value.(type) {\n    case nil:\n        return nil\n    case int64:\n        if v < 0 {\n            return fmt.Errorf(\"can't scan negative %d into NullUint64\", v)\n        }\n        n.Uint64 = uint64(v)\n    case uint64:\n        n.Uint64 = v\n    case []byte:\n        n.Uint64, err = strconv.ParseUint(string(v), 10, 64)\n    case string:\n        n.Uint64, err = strconv.ParseUint(v, 10, 64)\n    default:\n        return fmt.Errorf(\"can't scan %T into NullUint64\", value)\n    }\n    n.Valid = (err == nil)\n    return err\n}\n\nfunc (n NullUint64) Value() (driver.Value, error) {\n    if !n.Valid {\n        return nil, nil\n    }\n    if n.Uint64 > math.MaxInt64 {\n        // drivers only take int64, the database parses the rest\n        return strconv.FormatUint(n.Uint64, 10), nil\n    }\n    return int64(n.Uint64), nil\n}\n\nfunc (n *NullUint64) UnmarshalJSON(data []byte) error {\n    if bytes.Equal(data, []byte(\"null\")) {\n        n.Valid = false\n        return nil\n    }\n    err := json.Unmarshal(data, &n.Uint64)\n    n.Valid = (err == nil)\n    return err\n}\n\nfunc (n NullUint64) MarshalJSON() ([]byte, error) {\n    if !n.Valid {\n        return []byte(\"null\"), nil\n    }\n    return json.Marshal(n.Uint64)\n}\n\n{{if decimal_generated}}\n// Decimal is an exact decimal number. It keeps the digits sent by the\n// database, so values aren't rounded like they are with float64.\ntype Decimal struct {\n    digits string\n}\n\nvar (\n    _ json.Unmarshaler = &Decimal{}\n    _ driver.Valuer    = Decimal{}\n)\n\n// ParseDecimal reads a decimal number like \"-12.50\" or \"1.25e3\". Its\n// digits are kept, written as a JSON number: \".5\" is read as \"0.5\", \"5.\"\n// as \"5\", \"007\" as \"7\" and \"1.25e3\" as \"1250\".\nfunc ParseDecimal(s string) (Decimal, error) {\n    num, sign := s, \"\"\n    switch {\n    case strings.HasPrefix(num, \"-\"):\n        num, sign = num[1:], \"-\"\n    case strings.HasPrefix(num, \"+\"):\n        num = num[1:]\n    }\n    exp := 0\n    if i := strings.IndexAny(num, \"eE\"); i >= 0 {\n        e, err := strconv.Atoi(num[i+1:])\n        if err != nil || e > maxDecimalExp || e < -maxDecimalExp {\n            return Decimal{}, fmt.Errorf(\"invalid decimal %q\", s)\n        }\n        num, exp = num[:i], e\n    }\n    whole, frac := num, \"\"\n    if i := strings.IndexByte(num, '.'); i >= 0 {\n        whole, frac = num[:i], num[i+1:]\n    }\n    if whole+frac == \"\" || !isDecimalDigits(whole) || !isDecimalDigits(frac) {\n        return Decimal{}, fmt.Errorf(\"invalid decimal %q\", s)\n    }\n    if exp != 0 {\n        // move the point by the exponent\n        digits, point := whole+frac, len(whole)+exp\n        if point < 0 {\n            digits, point = strings.Repeat(\"0\", -point)+digits, 0\n        }\n        if point > len(digits) {\n            digits += strings.Repeat(\"0\", point-len(digits))\n        }\n        whole, frac = digits[:point], digits[point:]\n    }\n    whole = strings.TrimLeft(whole, \"0\")\n    if whole == \"\" {\n        whole = \"0\"\n    }\n    if frac != \"\" {\n        whole += \".\" + frac\n    }\n    return Decimal{digits: sign + whole}, nil\n}\n\n// maxDecimalExp bounds the exponents ParseDecimal reads, as each adds a\n// digit.\nconst maxDecimalExp = 1000\n\nfunc isDecimalDigits(s string) bool {\n    for _, r := range s {\n        if r < '0' || r > '9' {\n            return false\n        }\n    }\n    return true\n}\n\n// String returns the digits of d, as read from the database.\nfunc (d Decimal) String() string {\n    if d.digits == \"\" {\n        return \"0\"\n    }\n    return d.digits\n}\n\n// Rat returns the exact value of d.\nfunc (d Decimal) Rat() *big.Rat {\n    r, _ := new(big.Rat).SetString(d.String())\n    return r\n}\n\n// Float64 returns the nearest float64 to d.\nfunc (d Decimal) Float64() float64 {\n    f, _ := d.Rat().Float64()\n    return f\n}\n\nfunc (d *Decimal) Scan(value interface{}) error {\n    var err error\n    switch v := value.(type) {\n    case []byte:\n        *d, err = ParseDecimal(string(v))\n    case string:\n        *d, err = ParseDecimal(v)\n    case int64:\n        *d = Decimal{digits: strconv.FormatInt(v, 10)}\n    case float64:\n        // some drivers, like SQLite's, don't keep the digits\n        *d = Decimal{digits: strconv.FormatFloat(v, 'f', -1, 64)}\n    default:\n        err = fmt.Errorf(\"can't scan %T into Decimal\", value)\n    }\n    return err\n}\n\nfunc (d Decimal) Value() (driver.Value, error) {\n    return d.String(), nil\n}\n\nfunc (d *Decimal) UnmarshalJSON(data []byte) error {\n    var err error\n    if len(data) > 1 && data[0] == '\"' {\n        var s string\n        if err := json.Unmarshal(data, &s); err != nil {\n            return err\n        }\n        *d, err = ParseDecimal(s)\n    } else {\n        *d, err = ParseDecimal(string(data))\n    }\n    return err\n}\n\n// MarshalJSON writes d as a JSON number, without rounding it.\nfunc (d Decimal) MarshalJSON() ([]byte, error) {\n    return []byte(d.String()), nil\n}\n\ntype NullDecimal struct {\n    Decimal Decimal\n    Valid   bool\n}\n\nvar (\n    _ json.Unmarshaler = &NullDecimal{}\n    _ driver.Valuer    = NullDecimal{}\n)\n\nfunc NewDecimal(d Decimal) NullDecimal {\n    return NullDecimal{Decimal: d, Valid: true}\n}\n\nfunc (n *NullDecimal) Scan(value interface{}) error {\n    if value == nil {\n        n.Decimal, n.Valid = Decimal{}, false\n        return nil\n    }\n    err := n.Decimal.Scan(value)\n    n.Valid = (err == nil)\n    return err\n}\n\nfunc (n NullDecimal) Value() (driver.Value, error) {\n    if !n.Valid {\n        return nil, nil\n    }\n    return n.Decimal.Value()\n}\n\nfunc (n *NullDecimal) UnmarshalJSON(data []byte) error {\n    if bytes.Equal(data, []byte(\"null\")) {\n        n.Valid = false\n        return nil\n    }\n    err := n.Decimal.UnmarshalJSON(data)\n    n.Valid = (err == nil)\n    return err\n}\n\nfunc (n NullDecimal) MarshalJSON() ([]byte, error) {\n    if !n.Valid {\n        return []byte(\"null\"), nil\n    }\n    return n.Decimal.MarshalJSON()\n}\n{{end}}\n\n// TypedJSON stores a value of any type in a JSON column. Wrap a\n// pointer to scan a column into a type of your own:\n//\n//     var prefs Preferences\n//     err := rs.Scan(&TypedJSON{V: &prefs})\ntype TypedJSON struct {\n    V interface{}\n}\n\nfunc (t *TypedJSON) Scan(value interface{}) error {\n    switch v := value.(type) {\n    case nil:\n        return nil\n    case []byte:\n        return json.Unmarshal(v, t.V)\n    case string:\n        return json.Unmarshal([]byte(v), t.V)\n    }\n    return fmt.Errorf(\"can't scan %T into TypedJSON\", value)\n}\n\nfunc (t TypedJSON) Value() (driver.Value, error) {\n    b, err := json.Marshal(t.V)\n    if err != nil {\n        return nil, err\n    }\n    // sent as text, JSON columns reject binary strings\n    return string(b), nil\n}\n\n// Geometry is the value of a spatial column, as stored by MySQL: a\n// SRID followed by the geometry in WKB.\ntype Geometry struct {\n    SRID uint32\n    WKB  []byte\n}\n\nfunc (g *Geometry) Scan(value interface{}) error {\n    b, ok := value.([]byte)\n    if !ok {\n        return fmt.Errorf(\"can't scan %T into Geometry\", value)\n    }\n    if len(b) < 4 {\n        return fmt.Errorf(\"invalid geometry of %d bytes\", len(b))\n    }\n    g.SRID = binary.LittleEndian.Uint32(b)\n    g.WKB = append([]byte(nil), b[4:]...)\n    return nil\n}\n\nfunc (g Geometry) Value() (driver.Value, error) {\n    b := make([]byte, 4, 4+len(g.WKB))\n    binary.LittleEndian.PutUint32(b, g.SRID)\n    return append(b, g.WKB...), nil\n}\n\n// Point decodes g, if it holds a point.\nfunc (g Geometry) Point() (Point, error) {\n    wkb := g.WKB\n    if len(wkb) != 21 {\n        return Point{}, fmt.Errorf(\"invalid WKB point of %d bytes\", len(wkb))\n    }\n    var order binary.ByteOrder = binary.LittleEndian\n    if wkb[0] == 0 {\n        order = binary.BigEndian\n    }\n    if typ := order.Uint32(wkb[1:]); typ != 1 {\n        return Point{}, fmt.Errorf(\"WKB geometry of type %d isn't a point\", typ)\n    }\n    return Point{\n        SRID: g.SRID,\n        X:    math.Float64frombits(order.Uint64(wkb[5:])),\n        Y:    math.Float64frombits(order.Uint64(wkb[13:])),\n    }, nil\n}\n\n// Point is the value of a point column.\ntype Point struct {\n    SRID uint32\n    X, Y float64\n}\n\nfunc (p *Point) Scan(value interface{}) error {\n    var g Geometry\n    if err := g.Scan(value); err != nil {\n        return err\n    }\n    pt, err := g.Point()\n    if err != nil {\n        return err\n    }\n    *p = pt\n    return nil\n}\n\nfunc (p Point) Value() (driver.Value, error) {\n    return p.Geometry().Value()\n}\n\n// Geometry encodes p in WKB.\nfunc (p Point) Geometry() Geometry {\n    wkb := make([]byte, 21)\n    wkb[0] = 1 // little endian\n    binary.LittleEndian.PutUint32(wkb[1:], 1)\n    binary.LittleEndian.PutUint64(wkb[5:], math.Float64bits(p.X))\n    binary.LittleEndian.PutUint64(wkb[13:], math.Float64bits(p.Y))\n    return Geometry{SRID: p.SRID, WKB: wkb}\n}\n\n// Bits is the value of a bit(n) column. Bit 0 is the rightmost one.\ntype Bits uint64\n\n// Bit tells if bit i is set.\nfunc (b Bits) Bit(i uint) bool {\n    return b&(1<<i) != 0\n}\n\n// With returns b with bit i set or cleared.\nfunc (b Bits) With(i uint, set bool) Bits {\n    if set {\n        return b | 1<<i\n    }\n    return b &^ (1 << i)\n}\n\nfunc (b *Bits) Scan(value interface{}) error {\n    switch v := value.(type) {\n    case []byte:\n        // big endian, as sent by MySQL\n        if len(v) > 8 {\n            return fmt.Errorf(\"can't scan %d bytes into Bits\", len(v))\n        }\n        var n Bits\n        for _, c := range v {\n            n = n<<8 | Bits(c)\n        }\n        *b = n\n    case int64:\n        *b = Bits(v)\n    default:\n        return fmt.Errorf(\"can't scan %T into Bits\", value)\n    }\n    return nil\n}\n\nfunc (b Bits) Value() (driver.Value, error) {\n    if b > math.MaxInt64 {\n        v := make([]byte, 8)\n        binary.BigEndian.PutUint64(v, uint64(b))\n        return v, nil\n    }\n    return int64(b), nil\n}\n\ntype NullString sql.NullString\n\nvar (\n    _ json.Unmarshaler = &NullString{}\n    _ driver.Value     = &NullString{}\n)\n\nfunc NewString(s string) NullString {\n    return NullString{String: s, Valid: true}\n}\n\nfunc (n *NullString) Scan(value interface{}) error {\n    sqln := new(sql.NullString)\n    err := sqln.Scan(value)\n    *n = NullString(*sqln)\n    return err\n}\n\nfunc (n NullString) Value() (driver.Value, error) {\n    return sql.NullString(n).Value()\n}\n\nfunc (n *NullString) UnmarshalJSON(data []byte) error {\n    if bytes.Equal(data, []byte(\"null\")) {\n        n.Valid = false\n        return nil\n    }\n    err := json.Unmarshal(data, &n.String)\n    n.Valid = (err == nil)\n    return err\n}\n\nfunc (n NullString) MarshalJSON() ([]byte, error) {\n    if !n.Valid {\n        return []byte(\"null\"), nil\n    }\n    return json.Marshal(n.String)\n}\n\ntype NullFloat64 sql.NullFloat64\n\nvar (\n    _ json.Unmarshaler = &NullFloat64{}\n    _ driver.Value     = &NullFloat64{}\n)\n\nfunc NewFloat64(f float64) NullFloat64 {\n    return NullFloat64{Float64: f, Valid: true}\n}\n\nfunc (n *NullFloat64) Scan(value interface{}) error {\n    sqln := new(sql.NullFloat64)\n    err := sqln.Scan(value)\n    *n = NullFloat64(*sqln)\n    return err\n}\n\nfunc (n NullFloat64) Value() (driver.Value, error) {\n    return sql.NullFloat64(n).Value()\n}\n\nfunc (n *NullFloat64) UnmarshalJSON(data []byte) error {\n    if bytes.Equal(data, []byte(\"null\")) {\n        n.Valid = false\n        return nil\n    }\n    err := json.Unmarshal(data, &n.Float64)\n    n.Valid = (err == nil)\n    return err\n}\n\nfunc (n NullFloat64) MarshalJSON() ([]byte, error) {\n    if !n.Valid {\n        return []byte(\"null\"), nil\n    }\n    return json.Marshal(n.Float64)\n}\n\ntype NullBool sql.NullBool\n\nvar (\n    _ json.Unmarshaler = &NullBool{}\n    _ driver.Value     = &NullBool{}\n)\n\nfunc NewBool(b bool) NullBool {\n    return NullBool{Bool: b, Valid: true}\n}\n\nfunc (n *NullBool) Scan(value interface{}) error {\n    sqln := new(sql.NullBool)\n    err := sqln.Scan(value)\n    *n = NullBool(*sqln)\n    return err\n}\n\nfunc (n NullBool) Value() (driver.Value, error) {\n    return sql.NullBool(n).Value()\n}\n\nfunc (n *NullBool) UnmarshalJSON(data []byte) error {\n    if bytes.Equal(data, []byte(\"null\")) {\n        n.Valid = false\n        return nil\n    }\n    err := json.Unmarshal(data, &n.Bool)\n    n.Valid = (err == nil)\n    return err\n}\n\nfunc (n NullBool) MarshalJSON() ([]byte, error) {\n    if !n.Valid {\n        return []byte(\"null\"), nil\n    }\n    return json.Marshal(n.Bool)\n}\n\n{{if $sqlite}}\ntype NullTime struct {\n    Time  time.Time\n    Valid bool\n}\n{{else}}\ntype NullTime mysql.NullTime\n{{end}}\n\nvar (\n    _ json.Unmarshaler = &NullTime{}\n    _ driver.Value     = &NullTime{}\n)\n\nfunc NewTime(t time.Time) NullTime {\n    return NullTime{Time: t, Valid: true}\n}\n\n{{if $sqlite}}\n// timeLayouts are the formats in which SQLite stores times as text.\nvar timeLayouts = []string{\n    \"2006-01-02 15:04:05.999999999-07:00\",\n    \"2006-01-02T15:04:05.999999999-07:00\",\n    \"2006-01-02 15:04:05.999999999\",\n    \"2006-01-02T15:04:05.999999999\",\n    \"2006-01-02 15:04\",\n    \"2006-01-02T15:04\",\n    \"2006-01-02\",\n}\n\nfunc (n *NullTime) Scan(value interface{}) error {\n    var str string\n    switch v := value.(type) {\n    case nil:\n        n.Time, n.Valid = time.Time{}, false\n        return nil\n    case time.Time:\n        n.Time, n.Valid = v, true\n        return nil\n    case []byte:\n        str = string(v)\n    case string:\n        str = v\n    default:\n        return fmt.Errorf(\"can't scan %T into NullTime\", value)\n    }\n    for _, layout := range timeLayouts {\n        t, err := time.ParseInLocation(layout, str, time.UTC)\n        if err == nil {\n            n.Time, n.Valid = t, true\n            return nil\n        }\n    }\n    return fmt.Errorf(\"invalid time string: %q\", str)\n}\n\nfunc (n NullTime) Value() (driver.Value, error) {\n    if !n.Valid {\n        return nil, nil\n    }\n    return n.Time, nil\n}\n{{else}}\nfunc (n *NullTime) Scan(value interface{}) error {\n    sqln := new(mysql.NullTime)\n    err := sqln.Scan(value)\n    *n = NullTime(*sqln)\n    return err\n}\n\nfunc (n NullTime) Value() (driver.Value, error) {\n    return mysql.NullTime(n).Value()\n}\n{{end}}\n\nfunc (n *NullTime) UnmarshalJSON(data []byte) error {\n    if bytes.Equal(data, []byte(\"null\")) {\n        n.Valid = false\n        return nil\n    }\n    err := json.Unmarshal(data, &n.Time)\n    n.Valid = (err == nil)\n    return err\n}\n\nfunc (n NullTime) MarshalJSON() ([]byte, error) {\n    if !n.Valid {\n        return []byte(\"null\"), nil\n    }\n    return json.Marshal(n.Time)\n}\n\n{{if $sqlite}}\n// isCommandOnTableDenied is always false, SQLite doesn't have\n// privileges.\nfunc isCommandOnTableDenied(err error) bool {\n    return false\n}\n{{else}}\nfunc isCommandOnTableDenied(err error) bool {\n    e, ok := err.(*mysql.MySQLError)\n    if !ok {\n        return false\n    }\n    return e.Number == 1142\n}\n{{end}}\n"
+	TableTemplate  = "package {{.DB.Name}}\n\nimport (\n    {{if .NeedsTime}}\"time\"{{end}}\n    \"database/sql\"\n    {{if .Enums}}\"database/sql/driver\"{{end}}\n    {{if .NeedsJSON}}\"encoding/json\"{{end}}\n    \"log\"\n    \"fmt\"\n    {{if .HasSet}}\"strings\"{{end}}\n    {{if .DecimalImport}}\n\n    \"{{.DecimalImport}}\"{{end}}\n)\n\n{{$db_name := .DB.Name | camelize | export}}\n{{$tbl := .Tbl}}\n{{$dialect := .DB.Dialect}}\n{{$tbl_name := .Tbl.Name | camelize | pluralize | export}}\n{{$datatype :=  $tbl_name | singularize }}\n\nconst (\n    create{{$tbl_name}}SQL   = {{createQuery $dialect $tbl}}\n\n    {{if $tbl.Pk}}retrieve{{$tbl_name}}SQL = {{retrieveQuery $dialect $tbl}}\n    {{end}}\n    {{if .CreateRefreshed}}refreshCreated{{$tbl_name}}SQL = {{refreshQuery $dialect $tbl .CreateRefreshed}}\n    {{end}}\n    {{if .UpdateRefreshed}}refreshUpdated{{$tbl_name}}SQL = {{refreshQuery $dialect $tbl .UpdateRefreshed}}\n    {{end}}\n    {{if .UpdateColumns}}update{{$tbl_name}}SQL   = {{updateQuery $dialect $tbl}}\n    {{end}}\n\n    delete{{$tbl_name}}SQL   = {{deleteQuery $dialect $tbl}}\n\n    list{{$tbl_name}}SQL     = {{listQuery $dialect $tbl}}\n\n    {{range $tbl.Indices}}{{$idxname := .KeyName | camelize | export}}\n    list{{$tbl_name}}Idx{{$idxname}}SQL = {{listIndex $dialect $tbl .}}\n    {{end}}\n)\n\n// {{$tbl_name}} provides operations on {{$datatype}}\n// stored in {{$db_name}}.{{if $tbl.Comment}}\n//\n{{doc $tbl.Comment}}{{end}}\ntype {{$tbl_name}} struct {\n    db   Querier\n    Name string\n\n    create   *sql.Stmt\n    {{if .CreateRefreshed}}refreshCreated *sql.Stmt {{end}}\n    {{if .UpdateRefreshed}}refreshUpdated *sql.Stmt {{end}}\n    {{if $tbl.Pk}}retrieve *sql.Stmt {{end}}\n    {{if .UpdateColumns}}update   *sql.Stmt {{end}}\n    delete   *sql.Stmt\n    list     *sql.Stmt\n\n    {{range $tbl.Indices}}{{$idxname := .KeyName | camelize | export}}\n    idx{{.KeyName | camelize | export}} *sql.Stmt{{end}}\n}\n\nfunc new{{$tbl_name}}(db Querier) (*{{$tbl_name}}, error) {\n    var err error\n    tbl := &{{$tbl_name}}{db: db, Name: \"{{$tbl.Name}}\"}\n\n    bindings := []struct {\n        query string\n        stmt  **sql.Stmt\n    }{\n        {query: create{{$tbl_name}}SQL, stmt: &tbl.create},\n        {{if .CreateRefreshed}}{query: refreshCreated{{$tbl_name}}SQL, stmt: &tbl.refreshCreated},{{end}}\n        {{if .UpdateRefreshed}}{query: refreshUpdated{{$tbl_name}}SQL, stmt: &tbl.refreshUpdated},{{end}}\n        {{if $tbl.Pk}}{query: retrieve{{$tbl_name}}SQL, stmt: &tbl.retrieve},{{end}}\n        {{if .UpdateColumns}}{query: update{{$tbl_name}}SQL, stmt: &tbl.update},{{end}}\n        {query: delete{{$tbl_name}}SQL, stmt: &tbl.delete},\n        {query: list{{$tbl_name}}SQL, stmt: &tbl.list},\n        // indices {{range $tbl.Indices}}{{$idxname := .KeyName | camelize | export}}\n        {query: list{{$tbl_name}}Idx{{$idxname}}SQL, stmt: &tbl.idx{{.KeyName | camelize | export}} }, {{end}}\n    }\n\n    for _, bind := range bindings {\n        (*bind.stmt), err = db.Prepare(bind.query)\n        switch {\n        case isCommandOnTableDenied(err):\n            log.Printf(\"unauthorized to perform query: %q\", bind.query)\n            return nil, nil // code trying to use this stmt should panic if they're not authorized\n        case err != nil:\n            return nil, fmt.Errorf(\"preparing query: %v, query:\\n%s\", err, bind.query)\n        }\n    }\n\n    return tbl, err\n}\n\n\n\n// {{$datatype}} represents a row in table {{$tbl_name}}.{{if $tbl.Comment}}\n//\n{{doc $tbl.Comment}}{{end}}\ntype {{$datatype}} struct { {{range $tbl.Columns}}{{if .Comment}}\n    {{doc .Comment}}{{end}}\n    {{.Name | camelize | export}} {{col_to_go_type $datatype .}} {{end}}\n}\n\n{{template \"enums\" .Enums}}\n\n// ensures that {{$datatype}} implements the Updater interface.\nvar _ Updater = &{{$datatype}}{}\n\nfunc (d {{$datatype}}) cols() []string {\n    return []string{ {{range $tbl.Columns}}\n        \"{{.Name}}\",{{end}}\n    }\n}\n\nfunc (d {{$datatype}}) fields() []interface{}{\n    return []interface{}{ {{range $tbl.Columns}}\n        &d.{{.Name | camelize | export}}, {{end}}\n    }\n}\n\n// insertFields are the fields that Create writes, leaving out those\n// the database assigns.\nfunc (d {{$datatype}}) insertFields() []interface{}{\n    return []interface{}{ {{range .InsertColumns}}\n        &d.{{.Name | camelize | export}}, {{end}}\n    }\n}\n\n{{if .UpdateColumns}}\n// updateFields are the fields that Update writes, leaving out those\n// the database assigns.\nfunc (d {{$datatype}}) updateFields() []interface{}{\n    return []interface{}{ {{range .UpdateColumns}}\n        &d.{{.Name | camelize | export}}, {{end}}\n    }\n}\n{{end}}\n\n// FieldByColName returns the field in {{$datatype}} that represents the\n// column named `col`.\nfunc (d *{{$datatype}}) FieldByColName(col string) (interface{}, error) {\n    switch col { {{range $tbl.Columns}}\n    case \"{{.Name}}\":\n        return &d.{{.Name | camelize | export}}, nil{{end}}\n    default:\n        return nil, fmt.Errorf(\"invalid column %q\", col)\n    }\n}\n\n// Validate checks the constraints of table {{$tbl.Name}} that can be\n// verified without querying the database. It returns a *ValidationError\n// for the first column that breaks one.\nfunc (d {{$datatype}}) Validate() error { {{range .Validations}}\n    if {{.Cond}} {\n        return &ValidationError{Table: \"{{$tbl.Name}}\", Column: \"{{.Column}}\", Reason: {{printf \"%q\" .Reason}}}\n    }{{end}}\n    return nil\n}\n\n\n{{if $tbl.Pk}}\n{{$pklen := len $tbl.Pk.Columns}}\n{{$key := printf \"%sKey\" $datatype}}\n{{if gt $pklen 1}}\n// {{$key}} is the primary key of {{$datatype}}.\ntype {{$key}} struct { {{range $tbl.Pk.Columns}}\n    {{.Name | camelize | export}} {{col_to_go_type $datatype .}}{{end}}\n}\n\n{{if not .HasKeyField}}\n// Key returns the primary key of d.\nfunc (d {{$datatype}}) Key() {{$key}} {\n    return {{$key}}{ {{range $tbl.Pk.Columns}}\n        {{.Name | camelize | export}}: d.{{.Name | camelize | export}},{{end}}\n    }\n}\n{{end}}\n{{end}}\n\n// pkFields are the fields of the primary key, which find the row.\nfunc (d {{$datatype}}) pkFields() []interface{}{\n    return []interface{}{ {{range $tbl.Pk.Columns}}\n        &d.{{.Name | camelize | export}}, {{end}}\n    }\n}\n\n// Create a new {{$datatype}}.{{triggers_doc $tbl \"INSERT\"}}\nfunc (tbl *{{$tbl_name}}) Create(d *{{$datatype}}) error {\n\n    {{ $stamp := .HasCreatedAt}}\n    {{if $stamp}}\n    {{if $stamp.Nullable}}\n    d.CreatedAt = NewTime(time.Now())\n    {{else}}\n    d.CreatedAt = time.Now().UTC().Truncate(time.Second)\n    {{end}}\n    {{end}}\n\n    {{with .PkAutoIncrement}}\n    res, err := tbl.create.Exec(d.insertFields()...)\n    if err != nil {\n        return err\n    }\n\n    id, err := res.LastInsertId()\n    if err != nil {\n        return err\n    }\n\n    d.{{.Name | camelize | export}} = {{col_to_go_type $datatype .}}(id)\n    {{else}}\n    if _, err := tbl.create.Exec(d.insertFields()...); err != nil {\n        return err\n    }\n    {{end}}\n    {{if .CreateRefreshed}}\n    // the database or triggers have set these columns\n    return Scan(tbl.refreshCreated.QueryRow(d.pkFields()...), d, []string{ {{range .CreateRefreshed}}\n        \"{{.Name}}\",{{end}}\n    })\n    {{else}}\n    return nil\n    {{end}}\n}\n\n{{if eq $pklen 1}}\n{{$col := index $tbl.Pk.Columns 0}}\n// Retrieve an existing {{$datatype}} by ID.\nfunc (tbl *{{$tbl_name}}) Retrieve(id {{col_to_go_type $datatype $col}}) (*{{$datatype}}, bool, error) {\n\n    rs, err := tbl.retrieve.Query(id)\n{{else}}\n// Retrieve an existing {{$datatype}} by its primary key.\nfunc (tbl *{{$tbl_name}}) Retrieve({{idx_list_args $datatype $tbl.Pk}}) (*{{$datatype}}, bool, error) {\n\n    rs, err := tbl.retrieve.Query({{idx_query_args $tbl.Pk}})\n{{end}}\n    switch err {\n    default:\n        return nil, false, err\n    case sql.ErrNoRows:\n        return nil, false, nil\n    case nil:\n        defer rs.Close()\n    }\n    if !rs.Next() {\n        return nil, false, nil\n    }\n    d := &{{$datatype}}{}\n    return d, true, Scan(rs, d, d.cols())\n}\n{{if gt $pklen 1}}\n// RetrieveKey retrieves an existing {{$datatype}} by its key.\nfunc (tbl *{{$tbl_name}}) RetrieveKey(k {{$key}}) (*{{$datatype}}, bool, error) {\n    return tbl.Retrieve({{range $i, $col := $tbl.Pk.Columns}}{{if $i}}, {{end}}k.{{$col.Name | camelize | export}}{{end}})\n}\n{{end}}\n\n{{/* a table that's all key has nothing to update */}}\n{{if .UpdateColumns}}\n// Update an existing {{$datatype}} by its primary key.{{triggers_doc $tbl \"UPDATE\"}}\nfunc (tbl *{{$tbl_name}}) Update(d *{{$datatype}}) error {\n    {{ $stamp := .HasUpdatedAt}}\n    {{if $stamp}}\n    {{if $stamp.Nullable}}\n    d.UpdatedAt = NewTime(time.Now())\n    {{else}}\n    d.UpdatedAt = time.Now().UTC().Truncate(time.Second)\n    {{end}}\n    {{end}}\n\n    {{if .UpdateRefreshed}}\n    if _, err := tbl.update.Exec(append(d.updateFields(), d.pkFields()...)...); err != nil {\n        return err\n    }\n\n    // the database or triggers have set these columns\n    return Scan(tbl.refreshUpdated.QueryRow(d.pkFields()...), d, []string{ {{range .UpdateRefreshed}}\n        \"{{.Name}}\",{{end}}\n    })\n    {{else}}\n    _, err := tbl.update.Exec(append(d.updateFields(), d.pkFields()...)...)\n    return err\n    {{end}}\n}\n\n{{end}}\n\n// Delete an existing {{$datatype}} by its primary key.{{triggers_doc $tbl \"DELETE\"}}\nfunc (tbl *{{$tbl_name}}) Delete(d *{{$datatype}}) error {\n    _, err := tbl.delete.Exec(d.pkFields()...)\n    return err\n}\n\n{{else}}\n\n// Create a new {{$datatype}}.{{triggers_doc $tbl \"INSERT\"}}\nfunc (tbl *{{$tbl_name}}) Create(d *{{$datatype}}) error {\n    {{ $stamp := .HasCreatedAt}}\n    {{if $stamp}}\n    {{if $stamp.Nullable}}\n    d.CreatedAt = NewTime(time.Now())\n    {{else}}\n    d.CreatedAt = time.Now().UTC().Truncate(time.Second)\n    {{end}}\n    {{end}}\n    _, err := tbl.create.Exec(d.insertFields()...)\n    return err\n}\n\n{{if .UpdateColumns}}\n// Update an existing {{$datatype}} by its fields (all fields must match, NULL matching NULL).{{triggers_doc $tbl \"UPDATE\"}}\nfunc (tbl *{{$tbl_name}}) Update(d *{{$datatype}}) error {\n    // the row is found by the fields it has before the update\n    where := d.fields()\n    {{ $stamp := .HasUpdatedAt}}\n    {{if $stamp}}\n    {{if $stamp.Nullable}}\n    d.UpdatedAt = NewTime(time.Now())\n    {{else}}\n    d.UpdatedAt = time.Now().UTC().Truncate(time.Second)\n    {{end}}\n    {{end}}\n    _, err := tbl.update.Exec(append(d.updateFields(), where...)...)\n    return err\n}\n\n{{end}}\n\n// Delete an existing {{$datatype}} by its fields (all fields must match, NULL matching NULL).{{triggers_doc $tbl \"DELETE\"}}\nfunc (tbl *{{$tbl_name}}) Delete(d *{{$datatype}}) error {\n    _, err := tbl.delete.Exec(d.fields()...)\n    return err\n}\n\n{{end}}\n\n// List all {{$datatype}}s starting from an offset. Limited to 10k rows.\nfunc (tbl *{{$tbl_name}}) List(offset int) ([]{{$datatype}}, error) {\n    var list []{{$datatype}}\n\n    rows, err := tbl.list.Query(offset)\n    switch err {\n    default:\n        return nil, err\n    case sql.ErrNoRows:\n        return list, nil\n    case nil:\n        defer rows.Close()\n    }\n\n    for rows.Next() {\n        d := {{$datatype}}{}\n        if err := Scan(rows, &d, d.cols()); err != nil {\n            return list, err\n        }\n        list = append(list, d)\n    }\n\n    return list, rows.Err()\n}\n\n{{range $tbl.Indices}}{{$idxname := .KeyName | camelize | export}}\n// ListBy{{$idxname}} finds all {{$datatype}}s that match the query\n// on the index `{{.KeyName}}`, starting at `offset`, limited to 10k rows.\nfunc (tbl *{{$tbl_name}}) ListBy{{$idxname}}({{idx_list_args $datatype .}}, offset int) ([]{{$datatype}}, error) {\n    var list []{{$datatype}}\n\n    rows, err := tbl.idx{{$idxname}}.Query({{. | idx_query_args}}, offset)\n    switch err {\n    default:\n        return nil, err\n    case sql.ErrNoRows:\n        return list, nil\n    case nil:\n        defer rows.Close()\n    }\n\n    for rows.Next() {\n        d := {{$datatype}}{}\n        if err := Scan(rows, &d, d.cols()); err != nil {\n            return list, err\n        }\n        list = append(list, d)\n    }\n\n    return list, rows.Err()\n}\n{{end}}\n"
+	ViewTemplate   = "package {{.DB.Name}}\n\nimport (\n    {{if .NeedsTime}}\"time\"{{end}}\n    \"database/sql\"\n    {{if .Enums}}\"database/sql/driver\"{{end}}\n    {{if .NeedsJSON}}\"encoding/json\"{{end}}\n    \"log\"\n    \"fmt\"\n    {{if .HasSet}}\"strings\"{{end}}\n    {{if .DecimalImport}}\n\n    \"{{.DecimalImport}}\"{{end}}\n)\n\n{{$db_name := .DB.Name | camelize | export}}\n{{$view := .View}}\n{{$dialect := .DB.Dialect}}\n{{$view_name := .View.Name | camelize | pluralize | export}}\n{{$datatype :=  $view_name | singularize }}\n\nconst (\n    list{{$view_name}}SQL      = {{viewListQuery $dialect $view}}\n\n    list{{$view_name}}WhereSQL = {{viewListWhereQuery $dialect $view}}\n)\n\n// {{$view_name}} provides read-only operations on {{$datatype}}\n// found in the view {{$view.Name}} of {{$db_name}}.\ntype {{$view_name}} struct {\n    db   Querier\n    Name string\n\n    list *sql.Stmt\n}\n\nfunc new{{$view_name}}(db Querier) (*{{$view_name}}, error) {\n    var err error\n    view := &{{$view_name}}{db: db, Name: \"{{$view.Name}}\"}\n\n    bindings := []struct {\n        query string\n        stmt  **sql.Stmt\n    }{\n        {query: list{{$view_name}}SQL, stmt: &view.list},\n    }\n\n    for _, bind := range bindings {\n        (*bind.stmt), err = db.Prepare(bind.query)\n        switch {\n        case isCommandOnTableDenied(err):\n            log.Printf(\"unauthorized to perform query: %q\", bind.query)\n            return nil, nil // code trying to use this stmt should panic if they're not authorized\n        case err != nil:\n            return nil, fmt.Errorf(\"preparing query: %v, query:\\n%s\", err, bind.query)\n        }\n    }\n\n    return view, err\n}\n\n// {{$datatype}} represents a row in view {{$view_name}}.\ntype {{$datatype}} struct { {{range $view.Columns}}{{if .Comment}}\n    {{doc .Comment}}{{end}}\n    {{.Name | camelize | export}} {{col_to_go_type $datatype .}} {{end}}\n}\n\n{{template \"enums\" .Enums}}\n\n// ensures that {{$datatype}} implements the Updater interface.\nvar _ Updater = &{{$datatype}}{}\n\nfunc (d {{$datatype}}) cols() []string {\n    return []string{ {{range $view.Columns}}\n        \"{{.Name}}\",{{end}}\n    }\n}\n\nfunc (d {{$datatype}}) fields() []interface{}{\n    return []interface{}{ {{range $view.Columns}}\n        &d.{{.Name | camelize | export}}, {{end}}\n    }\n}\n\n// FieldByColName returns the field in {{$datatype}} that represents the\n// column named `col`.\nfunc (d *{{$datatype}}) FieldByColName(col string) (interface{}, error) {\n    switch col { {{range $view.Columns}}\n    case \"{{.Name}}\":\n        return &d.{{.Name | camelize | export}}, nil{{end}}\n    default:\n        return nil, fmt.Errorf(\"invalid column %q\", col)\n    }\n}\n\n// List all {{$datatype}}s starting from an offset. Limited to 10k rows.\nfunc (view *{{$view_name}}) List(offset int) ([]{{$datatype}}, error) {\n    rows, err := view.list.Query(offset)\n    switch err {\n    default:\n        return nil, err\n    case sql.ErrNoRows:\n        return nil, nil\n    case nil:\n        defer rows.Close()\n    }\n    return scan{{$view_name}}(rows)\n}\n\n// ListWhere finds all {{$datatype}}s that match the SQL condition\n// `where`, such as \"{{(index $view.Columns 0).Name}} = ?\", starting at `offset`, limited\n// to 10k rows. The `args` are the parameters of `where`.\nfunc (view *{{$view_name}}) ListWhere(where string, offset int, args ...interface{}) ([]{{$datatype}}, error) {\n    query := fmt.Sprintf(list{{$view_name}}WhereSQL, where)\n    rows, err := view.db.Query(query, append(args, offset)...)\n    switch err {\n    default:\n        return nil, err\n    case sql.ErrNoRows:\n        return nil, nil\n    case nil:\n        defer rows.Close()\n    }\n    return scan{{$view_name}}(rows)\n}\n\nfunc scan{{$view_name}}(rows *sql.Rows) ([]{{$datatype}}, error) {\n    var list []{{$datatype}}\n    for rows.Next() {\n        d := {{$datatype}}{}\n        if err := Scan(rows, &d, d.cols()); err != nil {\n            return list, err\n        }\n        list = append(list, d)\n    }\n    return list, rows.Err()\n}\n"
+	EnumTemplate   = "{{range .}}{{$enum := .}}\n{{if .IsSet}}\n// {{.Name}} is the set of values held by column {{.Column.Name}}.\ntype {{.Name}} []{{.Member}}\n\n// {{.Member}} is a value allowed in column {{.Column.Name}}.\ntype {{.Member}} string\n\nconst ({{range .Consts}}\n    {{.Name}} {{$enum.Member}} = {{printf \"%q\" .Value}}{{end}}\n)\n\n// Valid tells if m is allowed in column {{.Column.Name}}.\nfunc (m {{.Member}}) Valid() bool {\n    switch m {\n    case {{range $i, $c := .Consts}}{{if $i}}, {{end}}{{$c.Name}}{{end}}:\n        return true\n    }\n    return false\n}\n\n// Valid tells if all the members of s are allowed in column {{.Column.Name}}.\nfunc (s {{.Name}}) Valid() bool {\n    for _, m := range s {\n        if !m.Valid() {\n            return false\n        }\n    }\n    return true\n}\n\nfunc (s *{{.Name}}) Scan(value interface{}) error {\n    var str string\n    switch v := value.(type) {\n    case []byte:\n        str = string(v)\n    case string:\n        str = v\n    default:\n        return fmt.Errorf(\"can't scan %T into {{.Name}}\", value)\n    }\n    set := {{.Name}}{}\n    if str != \"\" {\n        for _, m := range strings.Split(str, \",\") {\n            set = append(set, {{.Member}}(m))\n        }\n    }\n    if !set.Valid() {\n        return fmt.Errorf(\"invalid {{.Name}}: %q\", str)\n    }\n    *s = set\n    return nil\n}\n\nfunc (s {{.Name}}) Value() (driver.Value, error) {\n    if !s.Valid() {\n        return nil, fmt.Errorf(\"invalid {{.Name}}: %q\", s)\n    }\n    strs := make([]string, 0, len(s))\n    for _, m := range s {\n        strs = append(strs, string(m))\n    }\n    return strings.Join(strs, \",\"), nil\n}\n{{else}}\n// {{.Name}} is a value allowed in column {{.Column.Name}}.\ntype {{.Name}} string\n\nconst ({{range .Consts}}\n    {{.Name}} {{$enum.Name}} = {{printf \"%q\" .Value}}{{end}}\n)\n\n// Valid tells if e is allowed in column {{.Column.Name}}.\nfunc (e {{.Name}}) Valid() bool {\n    switch e {\n    case {{range $i, $c := .Consts}}{{if $i}}, {{end}}{{$c.Name}}{{end}}:\n        return true\n    }\n    return false\n}\n\nfunc (e *{{.Name}}) Scan(value interface{}) error {\n    var str string\n    switch v := value.(type) {\n    case []byte:\n        str = string(v)\n    case string:\n        str = v\n    default:\n        return fmt.Errorf(\"can't scan %T into {{.Name}}\", value)\n    }\n    if !{{.Name}}(str).Valid() {\n        return fmt.Errorf(\"invalid {{.Name}}: %q\", str)\n    }\n    *e = {{.Name}}(str)\n    return nil\n}\n\nfunc (e {{.Name}}) Value() (driver.Value, error) {\n    if !e.Valid() {\n        return nil, fmt.Errorf(\"invalid {{.Name}}: %q\", string(e))\n    }\n    return string(e), nil\n}\n{{end}}\n{{end}}\n"
 )
 
 //go:generate embed file -var ClientTestTemplate -source client_test.go.tmpl
@@ -15,7 +19,7 @@ const (
 //go:generate embed file -var TableTestTemplate -source table_test.go.tmpl
 
 const (
-	ClientTestTemplate = "package {{.Name}}\n\n{{$db_name := .Name | camelize | export}}\n\nimport (\n    \"database/sql\"\n    \"testing\"\n    \"os\"\n    \"log\"\n)\n\nvar (\n    openDb Querier\n)\n\nvar resetDB func(t *testing.T)\n\nfunc TestMain(m *testing.M) {\n    log.SetPrefix(\"{{.Name}} tests: \")\n    log.SetFlags(0)\n    dsnEnv := \"TEST_DB_DSN\"\n    dsn := os.Getenv(dsnEnv)\n    if dsn == \"\" {\n        log.Fatalf(\"can't setup tests: need a DSN in env var %q\", dsnEnv)\n    }\n\n    db, err := sql.Open(\"mysql\", os.Getenv(\"TEST_DB_DSN\"))\n    if err != nil {\n        log.Fatalf(\"can't setup DB for tests: %v\", err)\n    }\n    tx, err := db.Begin()\n    if err != nil {\n        log.Fatalf(\"can't setup TX for tests: %v\", err)\n    }\n    openDb = tx\n\n    resetDB = func(t *testing.T) {\n        if tx != nil {\n            err = tx.Rollback()\n            if err != nil {\n                t.Fatalf(\"can't reset TX for test: %v\", err)\n            }\n        }\n        tx, err = db.Begin()\n        if err != nil {\n            t.Fatalf(\"can't create TX for test: %v\", err)\n        }\n        openDb = tx\n    }\n\n    retCode := m.Run()\n\n    if err := tx.Rollback(); err != nil {\n        log.Fatalf(\"failed to rollback TX after tests: %v\", err)\n    }\n    _ = db.Close()\n\n    os.Exit(retCode)\n}\n\nfunc TestCanConnectClient(t *testing.T) {\n    _, err := NewDB(openDb)\n    if err != nil {\n        t.Fatalf(\"couldn't create client: %v\", err)\n    }\n}\n\n"
-	CommonTestTemplate = "package {{.Name}}\n\nimport (\n    \"encoding/json\"\n    \"reflect\"\n    \"testing\"\n    \"time\"\n)\n\nfunc TestNullString(t *testing.T) {\n    tests := []struct {\n        input NullString\n        json  string\n    }{\n        {\n            input: NullString{Valid: false, String: \"\"},\n            json:  \"null\",\n        },\n        {\n            input: NullString{Valid: true, String: \"\"},\n            json:  `\"\"`,\n        },\n        {\n            input: NullString{Valid: true, String: \"something\"},\n            json:  `\"something\"`,\n        },\n    }\n\n    for _, tt := range tests {\n        data, err := json.Marshal(tt.input)\n        if err != nil {\n            t.Fatalf(\"json.Marshal(%v): %v\", tt.input, err)\n        }\n        if string(data) != tt.json {\n            t.Fatalf(\"json.Marshal(%v)=%v, expect %v\", tt.input, string(data), tt.json)\n        }\n        got := NullString{}\n\n        err = json.Unmarshal([]byte(tt.json), &got)\n        if err != nil {\n            t.Fatalf(\"json.Unmarshal(%v, %v): %v\", tt.json, got, err)\n        }\n\n        if !reflect.DeepEqual(tt.input, got) {\n            t.Fatalf(\"want %v, got %v\", tt.input, got)\n        }\n    }\n}\n\nfunc TestNullInt64(t *testing.T) {\n    tests := []struct {\n        input NullInt64\n        json  string\n    }{\n        {\n            input: NullInt64{Valid: false, Int64: 0},\n            json:  \"null\",\n        },\n        {\n            input: NullInt64{Valid: true, Int64: 0},\n            json:  `0`,\n        },\n        {\n            input: NullInt64{Valid: true, Int64: 42},\n            json:  `42`,\n        },\n    }\n\n    for _, tt := range tests {\n        data, err := json.Marshal(tt.input)\n        if err != nil {\n            t.Fatalf(\"json.Marshal(%v): %v\", tt.input, err)\n        }\n        if string(data) != tt.json {\n            t.Fatalf(\"json.Marshal(%v)=%v, expect %v\", tt.input, string(data), tt.json)\n        }\n        got := NullInt64{}\n\n        err = json.Unmarshal([]byte(tt.json), &got)\n        if err != nil {\n            t.Fatalf(\"json.Unmarshal(%v, %v): %v\", tt.json, got, err)\n        }\n\n        if !reflect.DeepEqual(tt.input, got) {\n            t.Fatalf(\"want %v, got %v\", tt.input, got)\n        }\n    }\n}\n\nfunc TestNullFloat64(t *testing.T) {\n    tests := []struct {\n        input NullFloat64\n        json  string\n    }{\n        {\n            input: NullFloat64{Valid: false, Float64: 0},\n            json:  \"null\",\n        },\n        {\n            input: NullFloat64{Valid: true, Float64: 0},\n            json:  `0`,\n        },\n        {\n            input: NullFloat64{Valid: true, Float64: 42},\n            json:  `42`,\n        },\n        {\n            input: NullFloat64{Valid: true, Float64: 42.1},\n            json:  `42.1`,\n        },\n    }\n\n    for _, tt := range tests {\n        data, err := json.Marshal(tt.input)\n        if err != nil {\n            t.Fatalf(\"json.Marshal(%v): %v\", tt.input, err)\n        }\n        if string(data) != tt.json {\n            t.Fatalf(\"json.Marshal(%v)=%v, expect %v\", tt.input, string(data), tt.json)\n        }\n        got := NullFloat64{}\n\n        err = json.Unmarshal([]byte(tt.json), &got)\n        if err != nil {\n            t.Fatalf(\"json.Unmarshal(%v, %v): %v\", tt.json, got, err)\n        }\n\n        if !reflect.DeepEqual(tt.input, got) {\n            t.Fatalf(\"want %v, got %v\", tt.input, got)\n        }\n    }\n}\n\nfunc TestNullBool(t *testing.T) {\n    tests := []struct {\n        input NullBool\n        json  string\n    }{\n        {\n            input: NullBool{Valid: false, Bool: false},\n            json:  \"null\",\n        },\n        {\n            input: NullBool{Valid: true, Bool: true},\n            json:  `true`,\n        },\n        {\n            input: NullBool{Valid: true, Bool: false},\n            json:  `false`,\n        },\n    }\n\n    for _, tt := range tests {\n        data, err := json.Marshal(tt.input)\n        if err != nil {\n            t.Fatalf(\"json.Marshal(%v): %v\", tt.input, err)\n        }\n        if string(data) != tt.json {\n            t.Fatalf(\"json.Marshal(%v)=%v, expect %v\", tt.input, string(data), tt.json)\n        }\n        got := NullBool{}\n\n        err = json.Unmarshal([]byte(tt.json), &got)\n        if err != nil {\n            t.Fatalf(\"json.Unmarshal(%v, %v): %v\", tt.json, got, err)\n        }\n\n        if !reflect.DeepEqual(tt.input, got) {\n            t.Fatalf(\"want %v, got %v\", tt.input, got)\n        }\n    }\n}\n\nfunc TestNullTime(t *testing.T) {\n    tests := []struct {\n        input NullTime\n        json  string\n    }{\n        {\n            input: NullTime{Valid: false, Time: time.Time{}},\n            json:  \"null\",\n        },\n        {\n            input: NullTime{Valid: true, Time: time.Date(2000, 1, 1, 1, 1, 1, 1, time.UTC)},\n            json:  `\"2000-01-01T01:01:01.000000001Z\"`,\n        },\n    }\n\n    for _, tt := range tests {\n        data, err := json.Marshal(tt.input)\n        if err != nil {\n            t.Fatalf(\"json.Marshal(%v): %v\", tt.input, err)\n        }\n        if string(data) != tt.json {\n            t.Fatalf(\"json.Marshal(%v)=%v, expect %v\", tt.input, string(data), tt.json)\n        }\n        got := NullTime{}\n\n        err = json.Unmarshal([]byte(tt.json), &got)\n        if err != nil {\n            t.Fatalf(\"json.Unmarshal(%v, %v): %v\", tt.json, got, err)\n        }\n\n        if !reflect.DeepEqual(tt.input, got) {\n            t.Fatalf(\"want %v, got %v\", tt.input, got)\n        }\n    }\n}\n"
+	ClientTestTemplate = "package {{.Name}}\n\n{{$db_name := .Name | camelize | export}}\n\nimport (\n    \"database/sql\"\n    \"testing\"\n    \"os\"\n    \"log\"\n    {{if eq .Dialect.Driver \"sqlite3\"}}\n\n    _ \"github.com/mattn/go-sqlite3\"{{end}}\n)\n\nvar (\n    openDb Querier\n)\n\nvar resetDB func(t *testing.T)\n\nfunc TestMain(m *testing.M) {\n    log.SetPrefix(\"{{.Name}} tests: \")\n    log.SetFlags(0)\n    dsnEnv := \"TEST_DB_DSN\"\n    dsn := os.Getenv(dsnEnv)\n    if dsn == \"\" {\n        log.Fatalf(\"can't setup tests: need a DSN in env var %q\", dsnEnv)\n    }\n\n    db, err := sql.Open(\"{{.Dialect.Driver}}\", dsn)\n    if err != nil {\n        log.Fatalf(\"can't setup DB for tests: %v\", err)\n    }\n    tx, err := db.Begin()\n    if err != nil {\n        log.Fatalf(\"can't setup TX for tests: %v\", err)\n    }\n    openDb = tx\n\n    resetDB = func(t *testing.T) {\n        if tx != nil {\n            err = tx.Rollback()\n            if err != nil {\n                t.Fatalf(\"can't reset TX for test: %v\", err)\n            }\n        }\n        tx, err = db.Begin()\n        if err != nil {\n            t.Fatalf(\"can't create TX for test: %v\", err)\n        }\n        openDb = tx\n    }\n\n    retCode := m.Run()\n\n    if err := tx.Rollback(); err != nil {\n        log.Fatalf(\"failed to rollback TX after tests: %v\", err)\n    }\n    _ = db.Close()\n\n    os.Exit(retCode)\n}\n\nfunc TestCanConnectClient(t *testing.T) {\n    _, err := NewDB(openDb)\n    if err != nil {\n        t.Fatalf(\"couldn't create client: %v\", err)\n    }\n}\n\n"
+	CommonTestTemplate = "package {{.Name}}\n\nimport (\n    \"encoding/json\"\n    \"reflect\"\n    \"testing\"\n    \"time\"\n)\n\nfunc TestNullString(t *testing.T) {\n    tests := []struct {\n        input NullString\n        json  string\n    }{\n        {\n            input: NullString{Valid: false, String: \"\"},\n            json:  \"null\",\n        },\n        {\n            input: NullString{Valid: true, String: \"\"},\n            json:  `\"\"`,\n        },\n        {\n            input: NullString{Valid: true, String: \"something\"},\n            json:  `\"something\"`,\n        },\n    }\n\n    for _, tt := range tests {\n        data, err := json.Marshal(tt.input)\n        if err != nil {\n            t.Fatalf(\"json.Marshal(%v): %v\", tt.input, err)\n        }\n        if string(data) != tt.json {\n            t.Fatalf(\"json.Marshal(%v)=%v, expect %v\", tt.input, string(data), tt.json)\n        }\n        got := NullString{}\n\n        err = json.Unmarshal([]byte(tt.json), &got)\n        if err != nil {\n            t.Fatalf(\"json.Unmarshal(%v, %v): %v\", tt.json, got, err)\n        }\n\n        if !reflect.DeepEqual(tt.input, got) {\n            t.Fatalf(\"want %v, got %v\", tt.input, got)\n        }\n    }\n}\n\nfunc TestNullInt64(t *testing.T) {\n    tests := []struct {\n        input NullInt64\n        json  string\n    }{\n        {\n            input: NullInt64{Valid: false, Int64: 0},\n            json:  \"null\",\n        },\n        {\n            input: NullInt64{Valid: true, Int64: 0},\n            json:  `0`,\n        },\n        {\n            input: NullInt64{Valid: true, Int64: 42},\n            json:  `42`,\n        },\n    }\n\n    for _, tt := range tests {\n        data, err := json.Marshal(tt.input)\n        if err != nil {\n            t.Fatalf(\"json.Marshal(%v): %v\", tt.input, err)\n        }\n        if string(data) != tt.json {\n            t.Fatalf(\"json.Marshal(%v)=%v, expect %v\", tt.input, string(data), tt.json)\n        }\n        got := NullInt64{}\n\n        err = json.Unmarshal([]byte(tt.json), &got)\n        if err != nil {\n            t.Fatalf(\"json.Unmarshal(%v, %v): %v\", tt.json, got, err)\n        }\n\n        if !reflect.DeepEqual(tt.input, got) {\n            t.Fatalf(\"want %v, got %v\", tt.input, got)\n        }\n    }\n}\n\nfunc TestNullFloat64(t *testing.T) {\n    tests := []struct {\n        input NullFloat64\n        json  string\n    }{\n        {\n            input: NullFloat64{Valid: false, Float64: 0},\n            json:  \"null\",\n        },\n        {\n            input: NullFloat64{Valid: true, Float64: 0},\n            json:  `0`,\n        },\n        {\n            input: NullFloat64{Valid: true, Float64: 42},\n            json:  `42`,\n        },\n        {\n            input: NullFloat64{Valid: true, Float64: 42.1},\n            json:  `42.1`,\n        },\n    }\n\n    for _, tt := range tests {\n        data, err := json.Marshal(tt.input)\n        if err != nil {\n            t.Fatalf(\"json.Marshal(%v): %v\", tt.input, err)\n        }\n        if string(data) != tt.json {\n            t.Fatalf(\"json.Marshal(%v)=%v, expect %v\", tt.input, string(data), tt.json)\n        }\n        got := NullFloat64{}\n\n        err = json.Unmarshal([]byte(tt.json), &got)\n        if err != nil {\n            t.Fatalf(\"json.Unmarshal(%v, %v): %v\", tt.json, got, err)\n        }\n\n        if !reflect.DeepEqual(tt.input, got) {\n            t.Fatalf(\"want %v, got %v\", tt.input, got)\n        }\n    }\n}\n\nfunc TestNullBool(t *testing.T) {\n    tests := []struct {\n        input NullBool\n        json  string\n    }{\n        {\n            input: NullBool{Valid: false, Bool: false},\n            json:  \"null\",\n        },\n        {\n            input: NullBool{Valid: true, Bool: true},\n            json:  `true`,\n        },\n        {\n            input: NullBool{Valid: true, Bool: false},\n            json:  `false`,\n        },\n    }\n\n    for _, tt := range tests {\n        data, err := json.Marshal(tt.input)\n        if err != nil {\n            t.Fatalf(\"json.Marshal(%v): %v\", tt.input, err)\n        }\n        if string(data) != tt.json {\n            t.Fatalf(\"json.Marshal(%v)=%v, expect %v\", tt.input, string(data), tt.json)\n        }\n        got := NullBool{}\n\n        err = json.Unmarshal([]byte(tt.json), &got)\n        if err != nil {\n            t.Fatalf(\"json.Unmarshal(%v, %v): %v\", tt.json, got, err)\n        }\n\n        if !reflect.DeepEqual(tt.input, got) {\n            t.Fatalf(\"want %v, got %v\", tt.input, got)\n        }\n    }\n}\n\nfunc TestNullTime(t *testing.T) {\n    tests := []struct {\n        input NullTime\n        json  string\n    }{\n        {\n            input: NullTime{Valid: false, Time: time.Time{}},\n            json:  \"null\",\n        },\n        {\n            input: NullTime{Valid: true, Time: time.Date(2000, 1, 1, 1, 1, 1, 1, time.UTC)},\n            json:  `\"2000-01-01T01:01:01.000000001Z\"`,\n        },\n    }\n\n    for _, tt := range tests {\n        data, err := json.Marshal(tt.input)\n        if err != nil {\n            t.Fatalf(\"json.Marshal(%v): %v\", tt.input, err)\n        }\n        if string(data) != tt.json {\n            t.Fatalf(\"json.Marshal(%v)=%v, expect %v\", tt.input, string(data), tt.json)\n        }\n        got := NullTime{}\n\n        err = json.Unmarshal([]byte(tt.json), &got)\n        if err != nil {\n            t.Fatalf(\"json.Unmarshal(%v, %v): %v\", tt.json, got, err)\n        }\n\n        if !reflect.DeepEqual(tt.input, got) {\n            t.Fatalf(\"want %v, got %v\", tt.input, got)\n        }\n    }\n}\n{{if decimal_generated}}\nfunc TestDecimalJSON(t *testing.T) {\n    tests := []struct {\n        input string\n        json  string\n    }{\n        {\"-12.50\", `-12.50`},\n        {\"+3\", `3`},\n        {\".5\", `0.5`},\n        {\"5.\", `5`},\n        {\"-.5\", `-0.5`},\n        {\"007\", `7`},\n        {\"0.00\", `0.00`},\n        {\"1e3\", `1000`},\n        {\"1.25E-3\", `0.00125`},\n        {\"-12.5e1\", `-125`},\n    }\n\n    for _, tt := range tests {\n        d, err := ParseDecimal(tt.input)\n        if err != nil {\n            t.Fatalf(\"ParseDecimal(%q): %v\", tt.input, err)\n        }\n        data, err := json.Marshal(d)\n        if err != nil {\n            t.Fatalf(\"json.Marshal(%v): %v\", d, err)\n        }\n        if string(data) != tt.json {\n            t.Fatalf(\"json.Marshal(%q)=%v, expect %v\", tt.input, string(data), tt.json)\n        }\n        got := Decimal{}\n\n        err = json.Unmarshal(data, &got)\n        if err != nil {\n            t.Fatalf(\"json.Unmarshal(%v, %v): %v\", string(data), got, err)\n        }\n\n        if !reflect.DeepEqual(d, got) {\n            t.Fatalf(\"want %v, got %v\", d, got)\n        }\n\n        // like those with exponents\n        if json.Valid([]byte(tt.input)) {\n            err = json.Unmarshal([]byte(tt.input), &got)\n            if err != nil || got != d {\n                t.Fatalf(\"json.Unmarshal(%v)=%v, %v, want %v\", tt.input, got, err, d)\n            }\n        }\n    }\n\n    for _, input := range []string{\"\", \"-\", \".\", \"1.2.3\", \"--1\", \"1e\", \"1e99999\", \"0x10\"} {\n        if d, err := ParseDecimal(input); err == nil {\n            t.Errorf(\"ParseDecimal(%q)=%v, want an error\", input, d)\n        }\n    }\n}\n{{end}}\n"
 	TableTestTemplate  = "package {{.DB.Name}}\n\n"
 )
@@ -0,0 +1,152 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aybabtme/sequel/reflector"
+)
+
+// validation is a condition on the fields of a row that, when true,
+// means the database would reject the row.
+type validation struct {
+	Column string
+	Cond   string
+	Reason string
+}
+
+// validations lists what the generated Validate method checks: the
+// constraints of tbl that can be verified without the database.
+func validations(tbl reflector.Table) []validation {
+	var vals []validation
+
+	for _, col := range tbl.Columns {
+		field := "d." + export(camelize(col.Name))
+
+		switch {
+		case col.Type == reflector.SQLBytes && !col.Nullable:
+			vals = append(vals, validation{
+				Column: col.Name,
+				Cond:   field + " == nil",
+				Reason: "can't be NULL",
+			})
+		case col.Type == reflector.SQLEnum || col.Type == reflector.SQLSet:
+			guard := ""
+			if col.Nullable {
+				guard = field + " != nil && "
+			}
+			reason := "isn't one of "
+			if col.Type == reflector.SQLSet {
+				reason = "holds values other than "
+			}
+			vals = append(vals, validation{
+				Column: col.Name,
+				Cond:   guard + "!" + field + ".Valid()",
+				Reason: reason + strings.Join(quoteAll(col.Values), ", "),
+			})
+		}
+
+		if col.Length == 0 {
+			continue
+		}
+		switch col.Type {
+		case reflector.SQLBit:
+			if col.Length >= 64 {
+				break
+			}
+			val := field
+			if col.Nullable {
+				val = field + " != nil && *" + field
+			}
+			vals = append(vals, validation{
+				Column: col.Name,
+				Cond:   fmt.Sprintf("%s >= 1<<%d", val, col.Length),
+				Reason: fmt.Sprintf("can't hold more than %d bits", col.Length),
+			})
+		case reflector.SQLString:
+			val, guard := fieldValue(col)
+			vals = append(vals, validation{
+				Column: col.Name,
+				Cond:   guard + fmt.Sprintf("charLen(%s) > %d", val, col.Length),
+				Reason: fmt.Sprintf("can't be longer than %d characters", col.Length),
+			})
+		case reflector.SQLBytes:
+			vals = append(vals, validation{
+				Column: col.Name,
+				Cond:   fmt.Sprintf("len(%s) > %d", field, col.Length),
+				Reason: fmt.Sprintf("can't be longer than %d bytes", col.Length),
+			})
+		}
+	}
+
+	for _, c := range tbl.Constraints {
+		vals = append(vals, checkValidations(tbl, c)...)
+	}
+
+	return vals
+}
+
+// checkValidations verifies CHECK constraints that compare numeric
+// columns to constants. Other constraints are left to the database.
+func checkValidations(tbl reflector.Table, c reflector.Constraint) []validation {
+	cmps, ok := c.Comparisons()
+	if !ok {
+		return nil
+	}
+
+	goOps := map[string]string{"<": "<", "<=": "<=", ">": ">", ">=": ">=", "=": "==", "<>": "!="}
+
+	var vals []validation
+	for _, cmp := range cmps {
+		col := tbl.Has(cmp.Column)
+		if col == nil || (col.Type != reflector.SQLInteger && col.Type != reflector.SQLFloat) {
+			return nil
+		}
+		if isBoolInteger(*col) {
+			return nil
+		}
+		val, guard := fieldValue(*col)
+		if col.Type == reflector.SQLInteger {
+			// the constant may not be an integer
+			val = "float64(" + val + ")"
+		}
+		vals = append(vals, validation{
+			Column: col.Name,
+			Cond:   guard + fmt.Sprintf("!(%s %s %s)", val, goOps[cmp.Op], cmp.Value),
+			Reason: fmt.Sprintf("must be %s %s, by constraint %s", cmp.Op, cmp.Value, c.Name),
+		})
+	}
+	return vals
+}
+
+// fieldValue is the expression holding the value of the column's
+// field, and a guard to prepend to conditions so they're skipped when
+// the value is NULL.
+func fieldValue(col reflector.Column) (val, guard string) {
+	field := "d." + export(camelize(col.Name))
+	if !col.Nullable {
+		return field, ""
+	}
+	guard = field + ".Valid && "
+	switch col.Type {
+	case reflector.SQLString:
+		return field + ".String", guard
+	case reflector.SQLInteger:
+		if columnToGoType("", col) == "NullUint64" {
+			return field + ".Uint64", guard
+		}
+		return field + ".Int64", guard
+	case reflector.SQLFloat:
+		return field + ".Float64", guard
+	}
+	return field, ""
+}
+
+func quoteAll(strs []string) []string {
+	quoted := make([]string, 0, len(strs))
+	for _, str := range strs {
+		quoted = append(quoted, strconv.Quote(str))
+	}
+	return quoted
+}
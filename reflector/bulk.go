@@ -0,0 +1,341 @@
+package reflector
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+Bulk!
+*/
+
+// DescribeMySQLBulk loads the same schema as DescribeMySQL, but reads
+// the columns, indices, foreign keys and triggers of all tables at
+// once from information_schema, rather than querying each table. The
+// number of queries doesn't grow with the number of tables, which
+// matters on slow links to large databases.
+func DescribeMySQLBulk(db *sql.DB, dbname string) (*DBSchema, error) {
+	return DescribeMySQLContext(context.Background(), db, dbname, DescribeOptions{Bulk: true})
+}
+
+func (db *DBSchema) loadBulk(q queryer, f *TableFilter, l *lenience) error {
+	if err := db.loadVariables(q); err != nil {
+		return fmt.Errorf("loading variables: %w", err)
+	}
+	if err := db.loadBulkTables(q, f, l); err != nil {
+		return fmt.Errorf("loading tables: %w", err)
+	}
+	if err := db.loadRoutines(q, l); err != nil {
+		return fmt.Errorf("loading routines: %w", err)
+	}
+	return nil
+}
+
+// loadBulkTables loads the tables and views that f keeps, then each of
+// their parts for all of them, grouping the rows by table.
+func (db *DBSchema) loadBulkTables(q queryer, f *TableFilter, l *lenience) error {
+	rows, err := q.Query(`
+SELECT TABLE_NAME, TABLE_TYPE, TABLE_COMMENT
+FROM information_schema.TABLES
+WHERE TABLE_SCHEMA = ?
+ORDER BY TABLE_NAME`, db.Name)
+	if err != nil {
+		return fmt.Errorf("showing tables, %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, typ, comment string
+		if err := rows.Scan(&name, &typ, &comment); err != nil {
+			return err
+		}
+		if !f.Match(name) {
+			continue
+		}
+		if typ == "VIEW" {
+			db.Views = append(db.Views, View{Name: name})
+			continue
+		}
+		db.Tables = append(db.Tables, Table{Name: name, Comment: comment})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tables := make(map[string]*Table, len(db.Tables))
+	for i := range db.Tables {
+		tables[db.Tables[i].Name] = &db.Tables[i]
+	}
+	views := make(map[string]*View, len(db.Views))
+	for i := range db.Views {
+		views[db.Views[i].Name] = &db.Views[i]
+	}
+
+	if err := loadBulkColumns(q, db.Name, tables, views, db.quotesDefaults(), l); err != nil {
+		return err
+	}
+	if err := loadBulkIndices(q, db.Name, tables, l); err != nil {
+		return err
+	}
+	for _, tbl := range tables {
+		sort.Sort(indexByKeyName(tbl.Indices))
+		tbl.collectUniqueConstraints()
+	}
+
+	if err := loadBulkForeignKeys(q, db.Name, tables); err != nil {
+		return err
+	}
+	if err := loadBulkTriggers(q, db.Name, tables); err != nil {
+		return err
+	}
+	if err := loadBulkViewDefinitions(q, db.Name, views); err != nil {
+		return err
+	}
+
+	// leave out the tables dropped by a lenient load
+	kept := db.Tables[:0]
+	for _, tbl := range db.Tables {
+		if _, ok := tables[tbl.Name]; ok {
+			kept = append(kept, tbl)
+		}
+	}
+	db.Tables = kept
+
+	return db.loadChecks(q)
+}
+
+// loadBulkColumns loads the columns of the tables and views. If quoted,
+// the defaults are like those of MariaDB's information_schema, which
+// unlike `describe` quotes literals and spells no default as NULL.
+func loadBulkColumns(q queryer, schema string, tables map[string]*Table, views map[string]*View, quoted bool, l *lenience) error {
+	rows, err := q.Query(`
+SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_KEY, COLUMN_DEFAULT, EXTRA, COLUMN_COMMENT
+FROM information_schema.COLUMNS
+WHERE TABLE_SCHEMA = ?
+ORDER BY TABLE_NAME, ORDINAL_POSITION`, schema)
+	if err != nil {
+		return fmt.Errorf("showing columns, %w", err)
+	}
+	defer rows.Close()
+
+	for i := 0; rows.Next(); i++ {
+		var (
+			tblname, typeName, nullable string
+			col                         Column
+		)
+		err := rows.Scan(
+			&tblname,
+			&col.Name,
+			&typeName,
+			&nullable,
+			&col.Key,
+			&col.Default,
+			&col.Extra,
+			&col.Comment,
+		)
+		if err != nil {
+			return fmt.Errorf("scanning column %d, %w", i, err)
+		}
+		if quoted {
+			col.Default = unquoteDefault(col.Default)
+		}
+		if err := col.parse(typeName, nullable); err != nil {
+			if err := l.warnColumn(tblname, col.Name, err); err != nil {
+				return err
+			}
+		}
+		if tbl, ok := tables[tblname]; ok {
+			tbl.Columns = append(tbl.Columns, col)
+		} else if view, ok := views[tblname]; ok {
+			view.Columns = append(view.Columns, col)
+		}
+	}
+	return rows.Err()
+}
+
+// loadBulkIndices sets the indices of the tables. A lenient load drops,
+// from tables, those with an index that can't be understood, like the
+// functional indices of MySQL 8, which have no column.
+// quotesDefaults tells if the server, per its variables, is MariaDB
+// 10.2.7 or later, whose information_schema quotes the defaults.
+func (db *DBSchema) quotesDefaults() bool {
+	for _, v := range db.Variables {
+		if v.Name != "version" {
+			continue
+		}
+		version := valueText(v.Type, v.Value)
+		if version == nil || !strings.Contains(strings.ToLower(*version), "mariadb") {
+			return false
+		}
+		var major, minor, patch int
+		fmt.Sscanf(*version, "%d.%d.%d", &major, &minor, &patch)
+		return major > 10 ||
+			major == 10 && (minor > 2 || minor == 2 && patch >= 7)
+	}
+	return false
+}
+
+// unquoteDefault turns a default of MariaDB's information_schema into
+// what `describe` shows: a literal without its quotes, NULL as none, and
+// anything else, like numbers and expressions, as is.
+func unquoteDefault(def interface{}) interface{} {
+	b, ok := def.([]byte)
+	switch {
+	case !ok:
+		return def
+	case string(b) == "NULL":
+		return nil
+	case len(b) >= 2 && b[0] == '\'' && b[len(b)-1] == '\'':
+		return []byte(strings.Replace(string(b[1:len(b)-1]), "''", "'", -1))
+	}
+	return def
+}
+
+func loadBulkIndices(q queryer, schema string, tables map[string]*Table, l *lenience) error {
+	// the columns of `show indexes`
+	rows, err := q.Query(`
+SELECT TABLE_NAME, NON_UNIQUE, INDEX_NAME, SEQ_IN_INDEX, COLUMN_NAME, COALESCE(COLLATION, ''),
+       COALESCE(CARDINALITY, 0), SUB_PART, PACKED, NULLABLE, INDEX_TYPE, COMMENT, INDEX_COMMENT
+FROM information_schema.STATISTICS
+WHERE TABLE_SCHEMA = ?
+ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`, schema)
+	if err != nil {
+		return fmt.Errorf("showing indices, %w", err)
+	}
+	defer rows.Close()
+
+	parts := make(map[string][]IndexPart)
+	for i := 0; rows.Next(); i++ {
+		idx := IndexPart{}
+		// the table is scanned first, and known even if the rest fails
+		err := idx.scan(nil, rows)
+		tbl, ok := tables[idx.Table]
+		if !ok && (err == nil || l != nil) {
+			// not described, or already dropped
+			continue
+		}
+		if err == nil {
+			err = idx.bindColumn(tbl)
+		}
+		if err != nil {
+			if l == nil {
+				return fmt.Errorf("scanning index %d, %w", i, err)
+			}
+			if err := l.warnTable(idx.Table, fmt.Errorf("scanning index %q, %w", idx.KeyName, err)); err != nil {
+				return err
+			}
+			delete(tables, idx.Table)
+			delete(parts, idx.Table)
+			continue
+		}
+		parts[idx.Table] = append(parts[idx.Table], idx)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for name, tblParts := range parts {
+		tbl := tables[name]
+		tbl.Pk, tbl.Indices = indicesFromParts(tblParts)
+	}
+	return nil
+}
+
+func loadBulkForeignKeys(q queryer, schema string, tables map[string]*Table) error {
+	rows, err := q.Query(`
+SELECT k.TABLE_NAME, k.CONSTRAINT_NAME, k.COLUMN_NAME,
+       k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME,
+       r.DELETE_RULE, r.UPDATE_RULE
+FROM information_schema.KEY_COLUMN_USAGE k
+JOIN information_schema.REFERENTIAL_CONSTRAINTS r
+  ON  r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA
+  AND r.CONSTRAINT_NAME   = k.CONSTRAINT_NAME
+  AND r.TABLE_NAME        = k.TABLE_NAME
+WHERE k.TABLE_SCHEMA = ?
+ORDER BY k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION`, schema)
+	if err != nil {
+		return fmt.Errorf("showing foreign keys, %w", err)
+	}
+	defer rows.Close()
+
+	parts := make(map[string][]foreignKeyPart)
+	for i := 0; rows.Next(); i++ {
+		var (
+			tblname string
+			part    foreignKeyPart
+		)
+		err := rows.Scan(
+			&tblname,
+			&part.Name,
+			&part.Column,
+			&part.RefTable,
+			&part.RefColumn,
+			&part.OnDelete,
+			&part.OnUpdate,
+		)
+		if err != nil {
+			return fmt.Errorf("scanning foreign key %d, %w", i, err)
+		}
+		parts[tblname] = append(parts[tblname], part)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for name, tblParts := range parts {
+		if tbl, ok := tables[name]; ok {
+			tbl.ForeignKeys = foreignKeysFromParts(tblParts)
+			sort.Sort(foreignKeysByName(tbl.ForeignKeys))
+		}
+	}
+	return nil
+}
+
+func loadBulkTriggers(q queryer, schema string, tables map[string]*Table) error {
+	rows, err := q.Query(`
+SELECT EVENT_OBJECT_TABLE, TRIGGER_NAME, ACTION_TIMING, EVENT_MANIPULATION, ACTION_STATEMENT
+FROM information_schema.TRIGGERS
+WHERE EVENT_OBJECT_SCHEMA = ?
+ORDER BY EVENT_OBJECT_TABLE, ACTION_TIMING DESC, EVENT_MANIPULATION, ACTION_ORDER`, schema)
+	if err != nil {
+		return fmt.Errorf("showing triggers, %w", err)
+	}
+	defer rows.Close()
+
+	for i := 0; rows.Next(); i++ {
+		trg := Trigger{}
+		err := rows.Scan(&trg.Table, &trg.Name, &trg.Timing, &trg.Event, &trg.Body)
+		if err != nil {
+			return fmt.Errorf("scanning trigger %d, %w", i, err)
+		}
+		if tbl, ok := tables[trg.Table]; ok {
+			tbl.Triggers = append(tbl.Triggers, trg)
+		}
+	}
+	return rows.Err()
+}
+
+func loadBulkViewDefinitions(q queryer, schema string, views map[string]*View) error {
+	rows, err := q.Query(`
+SELECT TABLE_NAME, VIEW_DEFINITION
+FROM information_schema.VIEWS
+WHERE TABLE_SCHEMA = ?`, schema)
+	if err != nil {
+		return fmt.Errorf("showing definitions of views, %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, def string
+		if err := rows.Scan(&name, &def); err != nil {
+			return err
+		}
+		if view, ok := views[name]; ok {
+			view.Definition = def
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,637 @@
+package reflector
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBulkLoadsLikeDescribe(t *testing.T) {
+	db, fake := openFakeMySQL(t, newFakeSchema(12))
+	defer db.Close()
+
+	want := &DBSchema{Name: "fake", Dialect: DialectMySQL}
+	if err := want.load(context.Background(), db, DescribeOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	perTable := fake.reset()
+
+	got := &DBSchema{Name: "fake", Dialect: DialectMySQL}
+	if err := got.loadBulk(db, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	bulk := fake.reset()
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bulk loaded\n%v\nwant\n%v", got, want)
+	}
+	if bulk >= perTable {
+		t.Fatalf("bulk ran %d queries, describing ran %d", bulk, perTable)
+	}
+}
+
+func TestDescribeKeepsColumnOrder(t *testing.T) {
+	schema := &fakeSchema{tables: []fakeTable{{
+		name: "accounts",
+		columns: [][]driver.Value{
+			{"name", "varchar(255)", "NO", "", nil, "", ""},
+			{"user_uuid", "char(36)", "NO", "PRI", nil, "", ""},
+			{"id", "int(11)", "NO", "", nil, "", ""},
+			{"org_id", "int(11)", "NO", "", nil, "", ""},
+		},
+		indices: [][]driver.Value{
+			{"accounts", int64(0), "PRIMARY", int64(1), "user_uuid", "A", int64(10), nil, nil, "", "BTREE", "", ""},
+		},
+	}}}
+	db, _ := openFakeMySQL(t, schema)
+	defer db.Close()
+
+	for _, opts := range []DescribeOptions{{}, {Bulk: true}} {
+		got, err := DescribeMySQLContext(context.Background(), db, "fake", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tbl := got.Tables[0]
+		var names []string
+		for _, col := range tbl.Columns {
+			names = append(names, col.Name)
+		}
+		if want := []string{"name", "user_uuid", "id", "org_id"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("with %+v, columns are %v, want %v", opts, names, want)
+		}
+		if !tbl.InPk("user_uuid") || tbl.InPk("id") {
+			t.Errorf("with %+v, primary key is %v, want user_uuid", opts, tbl.Pk)
+		}
+	}
+}
+
+func TestBulkLoadsMariaDBDefaults(t *testing.T) {
+	schema := &fakeSchema{
+		version: "10.6.12-MariaDB-1:10.6.12+maria~ubu2004",
+		tables: []fakeTable{{
+			name: "items",
+			columns: [][]driver.Value{
+				{"id", "int(11)", "NO", "PRI", nil, "", ""},
+				{"name", "varchar(20)", "NO", "", "it's", "", ""},
+				{"qty", "int(11)", "YES", "", nil, "", ""},
+				{"price", "int(11)", "NO", "", "0", "", ""},
+				{"added_at", "datetime", "NO", "", "current_timestamp()", "", ""},
+			},
+			// literals quoted, no default as NULL
+			bulkColumns: [][]driver.Value{
+				{"id", "int(11)", "NO", "PRI", nil, "", ""},
+				{"name", "varchar(20)", "NO", "", "'it''s'", "", ""},
+				{"qty", "int(11)", "YES", "", "NULL", "", ""},
+				{"price", "int(11)", "NO", "", "0", "", ""},
+				{"added_at", "datetime", "NO", "", "current_timestamp()", "", ""},
+			},
+			indices: [][]driver.Value{
+				{"items", int64(0), "PRIMARY", int64(1), "id", "A", int64(10), nil, nil, "", "BTREE", "", ""},
+			},
+		}},
+	}
+	db, _ := openFakeMySQL(t, schema)
+	defer db.Close()
+
+	want, err := DescribeMySQLContext(context.Background(), db, "fake", DescribeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DescribeMySQLContext(context.Background(), db, "fake", DescribeOptions{Bulk: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Tables, want.Tables) {
+		t.Fatalf("bulk loaded\n%v\nwant\n%v", got, want)
+	}
+	if name := got.Tables[0].Has("name"); name.Default != "it's" {
+		t.Errorf("name defaults to %#v, want it unquoted", name.Default)
+	}
+}
+
+func TestConcurrentLoadsLikeSequential(t *testing.T) {
+	db, _ := openFakeMySQL(t, newFakeSchema(30))
+	defer db.Close()
+
+	want := &DBSchema{Name: "fake", Dialect: DialectMySQL}
+	if err := want.load(context.Background(), db, DescribeOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls, last int
+	got := &DBSchema{Name: "fake", Dialect: DialectMySQL}
+	opts := DescribeOptions{
+		Workers: 8,
+		Progress: func(table string, done, total int) {
+			calls++
+			if done != last+1 || total != 30 {
+				t.Errorf("progress on %q is %d/%d after %d", table, done, total, last)
+			}
+			last = done
+		},
+	}
+	if err := got.load(context.Background(), db, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("concurrently loaded\n%v\nwant\n%v", got, want)
+	}
+	if calls != 30 {
+		t.Errorf("progress called %d times, want 30", calls)
+	}
+}
+
+func TestConcurrentLoadCollectsErrors(t *testing.T) {
+	schema := newFakeSchema(10)
+	schema.missing = []string{"gone1", "gone2"}
+	db, _ := openFakeMySQL(t, schema)
+	defer db.Close()
+
+	got := &DBSchema{Name: "fake", Dialect: DialectMySQL}
+	err := got.loadTables(context.Background(), db, DescribeOptions{Workers: 4}, nil)
+	errs, ok := err.(TableErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("err=%v, want an error for each missing table", err)
+	}
+	for i, name := range schema.missing {
+		if !strings.Contains(errs[i].Error(), name) {
+			t.Errorf("error %d is %v, want it about %q", i, errs[i], name)
+		}
+	}
+	if len(got.Tables) != 10 {
+		t.Errorf("loaded %d tables, want the 10 others", len(got.Tables))
+	}
+}
+
+func TestDescribeContextIsCanceled(t *testing.T) {
+	db, fake := openFakeMySQL(t, newFakeSchema(10))
+	defer db.Close()
+	fake.latency = time.Hour
+
+	for _, opts := range []DescribeOptions{{}, {Workers: 4}, {Bulk: true}} {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err := DescribeMySQLContext(ctx, db, "fake", opts)
+		cancel()
+		if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+			t.Errorf("with %+v, err=%v, want the deadline exceeded", opts, err)
+		}
+	}
+}
+
+func TestDescribeManyDatabases(t *testing.T) {
+	db, _ := openFakeServer(t, map[string]*fakeSchema{
+		"alpha": newFakeSchema(3),
+		"beta":  newFakeSchema(5),
+		"gamma": newFakeSchema(1),
+	})
+	defer db.Close()
+
+	f, err := NewTableFilter(nil, []string{"gamma"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := ListMySQLDatabases(context.Background(), db, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"alpha", "beta"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("databases=%v, want %v", names, want)
+	}
+
+	for i, want := range []int{3, 5} {
+		for _, opts := range []DescribeOptions{{Workers: 2}, {Bulk: true}} {
+			schema, err := DescribeMySQLContext(context.Background(), db, names[i], opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if schema.Name != names[i] || len(schema.Tables) != want {
+				t.Errorf("with %+v, %q has %d tables, want %d", opts, schema.Name, len(schema.Tables), want)
+			}
+		}
+	}
+}
+
+func BenchmarkDescribe(b *testing.B) {
+	for _, n := range []int{10, 100} {
+		db, fake := openFakeMySQL(b, newFakeSchema(n))
+		fake.latency = 100 * time.Microsecond
+
+		b.Run(fmt.Sprintf("per-table/%d", n), func(b *testing.B) {
+			fake.reset()
+			for i := 0; i < b.N; i++ {
+				schema := &DBSchema{Name: "fake"}
+				if err := schema.load(context.Background(), db, DescribeOptions{}, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(fake.reset())/float64(b.N), "queries/op")
+		})
+		b.Run(fmt.Sprintf("concurrent/%d", n), func(b *testing.B) {
+			fake.reset()
+			for i := 0; i < b.N; i++ {
+				schema := &DBSchema{Name: "fake"}
+				if err := schema.load(context.Background(), db, DescribeOptions{Workers: 8}, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(fake.reset())/float64(b.N), "queries/op")
+		})
+		b.Run(fmt.Sprintf("bulk/%d", n), func(b *testing.B) {
+			fake.reset()
+			for i := 0; i < b.N; i++ {
+				schema := &DBSchema{Name: "fake"}
+				if err := schema.loadBulk(db, nil, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(fake.reset())/float64(b.N), "queries/op")
+		})
+		db.Close()
+	}
+}
+
+/*
+A fake MySQL, answering the queries of the reflector from memory.
+*/
+
+type fakeSchema struct {
+	tables  []fakeTable
+	views   []fakeTable
+	missing []string // tables listed, but gone when described
+	version string   // of the server, if shown
+
+	routines [][]driver.Value // like ROUTINES
+	params   [][]driver.Value // like PARAMETERS
+}
+
+type fakeTable struct {
+	name, comment, definition string
+	columns                   [][]driver.Value // like `describe`, with the comment last
+	bulkColumns               [][]driver.Value // like COLUMNS, if unlike `describe`
+	indices                   [][]driver.Value // like `show indexes`
+	fks                       [][]driver.Value // like KEY_COLUMN_USAGE, without the table
+	triggers                  [][]driver.Value // like TRIGGERS, without the table
+	sqliteFks                 [][]driver.Value // like SQLite's `pragma foreign_key_list`
+	sqliteColumns             [][]driver.Value // like SQLite's `pragma table_info`
+}
+
+// all is the tables, then the views, in a new slice: appending to
+// s.tables would race when tables are described concurrently.
+func (s *fakeSchema) all() []fakeTable {
+	return append(append([]fakeTable(nil), s.tables...), s.views...)
+}
+
+// newFakeSchema has n tables, each referencing the previous one, and a
+// view.
+func newFakeSchema(n int) *fakeSchema {
+	s := &fakeSchema{}
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("t%03d", i)
+		tbl := fakeTable{
+			name:    name,
+			comment: "Table " + name + ".",
+			columns: [][]driver.Value{
+				{"id", "bigint(20) unsigned", "NO", "PRI", nil, "auto_increment", ""},
+				{"name", "varchar(255)", "NO", "MUL", "", "", "Shown to users."},
+				{"parent_id", "bigint(20) unsigned", "YES", "MUL", nil, "", ""},
+				{"kind", "enum('a','b')", "NO", "", "a", "", ""},
+			},
+			indices: [][]driver.Value{
+				{name, int64(0), "PRIMARY", int64(1), "id", "A", int64(10), nil, nil, "", "BTREE", "", ""},
+				{name, int64(1), "name_idx", int64(1), "name", "A", int64(10), nil, nil, "", "BTREE", "", ""},
+				{name, int64(1), "name_idx", int64(2), "kind", "A", int64(10), nil, nil, "", "BTREE", "", ""},
+				{name, int64(1), "parent_id", int64(1), "parent_id", "A", int64(10), nil, nil, "YES", "BTREE", "", ""},
+			},
+		}
+		if i > 0 {
+			tbl.fks = [][]driver.Value{
+				{name + "_ibfk_1", "parent_id", fmt.Sprintf("t%03d", i-1), "id", "CASCADE", "NO ACTION"},
+			}
+		}
+		if i%5 == 0 {
+			tbl.triggers = [][]driver.Value{
+				{name + "_audit", "AFTER", "INSERT", "INSERT INTO audit VALUES (NEW.id)"},
+			}
+		}
+		s.tables = append(s.tables, tbl)
+	}
+	s.views = append(s.views, fakeTable{
+		name:       "names",
+		definition: "select `name` from `t000`",
+		columns: [][]driver.Value{
+			{"name", "varchar(255)", "NO", "", nil, "", ""},
+		},
+	})
+	return s
+}
+
+// answer returns the columns and rows of the query.
+func (s *fakeSchema) answer(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+	var (
+		rows [][]driver.Value
+		arg  string
+	)
+	if len(args) == 1 {
+		arg = fmt.Sprint(args[0])
+	}
+	each := func(tables []fakeTable, f func(fakeTable)) {
+		for _, tbl := range tables {
+			if arg == "" || tbl.name == arg {
+				f(tbl)
+			}
+		}
+	}
+	// the bulk queries have a first column for the table
+	cols := func(n int) []string {
+		if arg == "" {
+			n++
+		}
+		return make([]string, n)
+	}
+	prefix := func(tbl fakeTable, list [][]driver.Value, n int) {
+		for _, row := range list {
+			row = row[:n]
+			if arg == "" {
+				row = append([]driver.Value{tbl.name}, row...)
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	switch q := strings.TrimSpace(query); {
+	case q == "show variables":
+		rows = [][]driver.Value{{"autocommit", "ON"}}
+		if s.version != "" {
+			rows = append(rows, []driver.Value{"version", s.version})
+		}
+		return []string{"Variable_name", "Value"}, rows, nil
+
+	case strings.HasPrefix(q, "show full tables"):
+		tables := s.tables
+		if !strings.Contains(q, "!= 'VIEW'") {
+			tables = s.views
+		}
+		for _, tbl := range tables {
+			rows = append(rows, []driver.Value{tbl.name, "BASE TABLE"})
+		}
+		if strings.Contains(q, "!= 'VIEW'") {
+			for _, name := range s.missing {
+				rows = append(rows, []driver.Value{name, "BASE TABLE"})
+			}
+		}
+		return []string{"Tables_in_fake", "Table_type"}, rows, nil
+
+	case strings.HasPrefix(q, "describe "), strings.HasPrefix(q, "show indexes in "):
+		// like `schema`.`table`
+		name := strings.Trim(q[strings.LastIndex(q, ".")+1:], "`")
+		for _, tbl := range s.all() {
+			if tbl.name != name {
+				continue
+			}
+			if strings.HasPrefix(q, "describe ") {
+				for _, col := range tbl.columns {
+					rows = append(rows, col[:6])
+				}
+				return []string{"Field", "Type", "Null", "Key", "Default", "Extra"}, rows, nil
+			}
+			return make([]string, 13), tbl.indices, nil
+		}
+		return nil, nil, fmt.Errorf("table %q doesn't exist", name)
+
+	case strings.HasPrefix(q, "pragma table_info("):
+		name := strings.Trim(q[len("pragma table_info("):len(q)-1], `"`)
+		each(s.tables, func(tbl fakeTable) {
+			if tbl.name == name {
+				rows = tbl.sqliteColumns
+			}
+		})
+		return []string{"cid", "name", "type", "notnull", "dflt_value", "pk"}, rows, nil
+
+	case strings.HasPrefix(q, "pragma foreign_key_list("):
+		name := strings.Trim(q[len("pragma foreign_key_list("):len(q)-1], `"`)
+		each(s.tables, func(tbl fakeTable) {
+			if tbl.name == name {
+				rows = tbl.sqliteFks
+			}
+		})
+		return []string{"id", "seq", "table", "from", "to", "on_update", "on_delete", "match"}, rows, nil
+
+	case strings.Contains(q, "'CHECK_CONSTRAINTS'"):
+		return []string{"unused"}, nil, nil
+
+	case strings.Contains(q, "information_schema.ROUTINES"):
+		return make([]string, 4), s.routines, nil
+
+	case strings.Contains(q, "information_schema.PARAMETERS"):
+		return make([]string, 5), s.params, nil
+
+	case strings.Contains(q, "TABLE_COMMENT != ''"):
+		for _, tbl := range s.tables {
+			rows = append(rows, []driver.Value{tbl.name, tbl.comment})
+		}
+		return []string{"TABLE_NAME", "TABLE_COMMENT"}, rows, nil
+
+	case strings.Contains(q, "COLUMN_COMMENT != ''"):
+		for _, tbl := range s.all() {
+			for _, col := range tbl.columns {
+				if col[6] != "" {
+					rows = append(rows, []driver.Value{tbl.name, col[0], col[6]})
+				}
+			}
+		}
+		return []string{"TABLE_NAME", "COLUMN_NAME", "COLUMN_COMMENT"}, rows, nil
+
+	case strings.Contains(q, "information_schema.TABLES"):
+		for _, tbl := range s.tables {
+			rows = append(rows, []driver.Value{tbl.name, "BASE TABLE", tbl.comment})
+		}
+		for _, tbl := range s.views {
+			rows = append(rows, []driver.Value{tbl.name, "VIEW", ""})
+		}
+		return make([]string, 3), rows, nil
+
+	case strings.Contains(q, "information_schema.COLUMNS"):
+		each(s.all(), func(tbl fakeTable) {
+			if tbl.bulkColumns != nil {
+				prefix(tbl, tbl.bulkColumns, 7)
+			} else {
+				prefix(tbl, tbl.columns, 7)
+			}
+		})
+		return cols(7), rows, nil
+
+	case strings.Contains(q, "information_schema.STATISTICS"):
+		each(s.tables, func(tbl fakeTable) { rows = append(rows, tbl.indices...) })
+		return make([]string, 13), rows, nil
+
+	case strings.Contains(q, "information_schema.KEY_COLUMN_USAGE"):
+		each(s.tables, func(tbl fakeTable) { prefix(tbl, tbl.fks, 6) })
+		return cols(6), rows, nil
+
+	case strings.Contains(q, "information_schema.TRIGGERS"):
+		each(s.tables, func(tbl fakeTable) { prefix(tbl, tbl.triggers, 4) })
+		return cols(4), rows, nil
+
+	case strings.Contains(q, "information_schema.VIEWS"):
+		each(s.views, func(tbl fakeTable) { prefix(tbl, [][]driver.Value{{tbl.definition}}, 1) })
+		return cols(1), rows, nil
+	}
+	return nil, nil, fmt.Errorf("unexpected query %q", query)
+}
+
+// fakeMySQL counts the queries it answers, each taking latency.
+type fakeMySQL struct {
+	schemas map[string]*fakeSchema
+	latency time.Duration
+
+	mu      sync.Mutex
+	queries int
+}
+
+// reset returns the number of queries answered since the last reset.
+func (f *fakeMySQL) reset() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := f.queries
+	f.queries = 0
+	return n
+}
+
+var (
+	fakeOnce sync.Once
+	fakeMu   sync.Mutex
+	fakeDBs  = make(map[string]*fakeMySQL)
+)
+
+// answer finds the schema of the query, in its first argument or its
+// first quoted name, and answers from it.
+func (f *fakeMySQL) answer(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+	if strings.Contains(query, "information_schema.SCHEMATA") {
+		var rows [][]driver.Value
+		for name := range f.schemas {
+			rows = append(rows, []driver.Value{name})
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i][0].(string) < rows[j][0].(string) })
+		return []string{"SCHEMA_NAME"}, rows, nil
+	}
+
+	var name string
+	if len(args) > 0 {
+		name, args = fmt.Sprint(args[0]), args[1:]
+	} else if parts := strings.Split(query, "`"); len(parts) > 2 {
+		name = parts[1]
+	}
+	schema, ok := f.schemas[name]
+	if !ok {
+		// the queries about the server
+		for _, schema = range f.schemas {
+			break
+		}
+	}
+	return schema.answer(query, args)
+}
+
+// openFakeMySQL opens a server with the single database "fake".
+func openFakeMySQL(tb testing.TB, schema *fakeSchema) (*sql.DB, *fakeMySQL) {
+	return openFakeServer(tb, map[string]*fakeSchema{"fake": schema})
+}
+
+func openFakeServer(tb testing.TB, schemas map[string]*fakeSchema) (*sql.DB, *fakeMySQL) {
+	fakeOnce.Do(func() { sql.Register("fakemysql", fakeDriver{}) })
+
+	fake := &fakeMySQL{schemas: schemas}
+	fakeMu.Lock()
+	dsn := fmt.Sprintf("fake%d", len(fakeDBs))
+	fakeDBs[dsn] = fake
+	fakeMu.Unlock()
+
+	db, err := sql.Open("fakemysql", dsn)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return db, fake
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	fakeMu.Lock()
+	defer fakeMu.Unlock()
+	return fakeConn{fakeDBs[dsn]}, nil
+}
+
+type fakeConn struct{ fake *fakeMySQL }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.fake, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return nil, fmt.Errorf("no transactions") }
+
+type fakeStmt struct {
+	fake  *fakeMySQL
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("read only")
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.run(context.Background(), args)
+}
+
+func (s fakeStmt) QueryContext(ctx context.Context, named []driver.NamedValue) (driver.Rows, error) {
+	args := make([]driver.Value, len(named))
+	for i, arg := range named {
+		args[i] = arg.Value
+	}
+	return s.run(ctx, args)
+}
+
+func (s fakeStmt) run(ctx context.Context, args []driver.Value) (driver.Rows, error) {
+	s.fake.mu.Lock()
+	s.fake.queries++
+	s.fake.mu.Unlock()
+	select {
+	case <-time.After(s.fake.latency):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	cols, rows, err := s.fake.answer(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{cols: cols, rows: rows}, nil
+}
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	for i, v := range r.rows[0] {
+		// drivers send text as bytes
+		if s, ok := v.(string); ok {
+			v = []byte(s)
+		}
+		dest[i] = v
+	}
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,86 @@
+package reflector
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestLoadComments(t *testing.T) {
+	schema := &fakeSchema{
+		tables: []fakeTable{{
+			name:    "orders",
+			comment: "Orders of users.\nKept */ forever.",
+			columns: [][]driver.Value{
+				{"id", "int(11)", "NO", "PRI", nil, "", "Set by */ the DB."},
+				{"total", "decimal(10,2)", "NO", "", nil, "", "In cents.\nTaxes included."},
+				{"note", "text", "YES", "", nil, "", ""},
+			},
+		}},
+		views: []fakeTable{{
+			name: "totals",
+			columns: [][]driver.Value{
+				{"total", "decimal(10,2)", "NO", "", nil, "", "Summed."},
+			},
+		}},
+	}
+	db, _ := openFakeMySQL(t, schema)
+	defer db.Close()
+
+	id := Column{Name: "id"}
+	got := &DBSchema{
+		Name: "fake",
+		Tables: []Table{{
+			Name:    "orders",
+			Columns: []Column{id, {Name: "total"}, {Name: "note"}},
+			Pk:      &Index{KeyName: "PRIMARY", Columns: []Column{id}, Parts: []IndexPart{{Column: id}}},
+		}},
+		Views: []View{{Name: "totals", Columns: []Column{{Name: "total"}}}},
+	}
+	if err := got.loadComments(db); err != nil {
+		t.Fatal(err)
+	}
+
+	tbl := got.Tables[0]
+	if want := "Orders of users.\nKept */ forever."; tbl.Comment != want {
+		t.Errorf("table comment is %q, want %q", tbl.Comment, want)
+	}
+	tests := []struct {
+		col  Column
+		want string
+	}{
+		{tbl.Columns[0], "Set by */ the DB."},
+		{tbl.Pk.Columns[0], "Set by */ the DB."},
+		{tbl.Pk.Parts[0].Column, "Set by */ the DB."},
+		{tbl.Columns[1], "In cents.\nTaxes included."},
+		{tbl.Columns[2], ""},
+		{got.Views[0].Columns[0], "Summed."},
+	}
+	for _, tt := range tests {
+		if tt.col.Comment != tt.want {
+			t.Errorf("comment of %q is %q, want %q", tt.col.Name, tt.col.Comment, tt.want)
+		}
+	}
+}
+
+func TestTableSetComment(t *testing.T) {
+	name := Column{Name: "name"}
+	tbl := Table{
+		Columns: []Column{{Name: "id"}, name},
+		Indices: []Index{{
+			KeyName: "name_idx",
+			Columns: []Column{name},
+			Parts:   []IndexPart{{ColumnName: "name", Column: name}},
+		}},
+	}
+	tbl.setComment("name", "Shown to users.")
+	tbl.setComment("missing", "Nowhere.")
+
+	if tbl.Columns[0].Comment != "" {
+		t.Errorf("commented the column id with %q", tbl.Columns[0].Comment)
+	}
+	for _, col := range []Column{tbl.Columns[1], tbl.Indices[0].Columns[0], tbl.Indices[0].Parts[0].Column} {
+		if col.Comment != "Shown to users." {
+			t.Errorf("comment of %q is %q", col.Name, col.Comment)
+		}
+	}
+}
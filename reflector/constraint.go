@@ -0,0 +1,152 @@
+package reflector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+Constraints!
+*/
+
+type ConstraintType int
+
+const (
+	startConstraint ConstraintType = iota
+
+	ConstraintCheck
+	ConstraintUnique
+
+	stopConstraint
+)
+
+type Constraint struct {
+	Name    string         `json:"name"`
+	Type    ConstraintType `json:"type"`
+	Columns []string       `json:"columns,omitempty"` // The columns of a UNIQUE constraint.
+	Clause  string         `json:"clause,omitempty"`  // The expression of a CHECK constraint.
+}
+
+func (c Constraint) String() string {
+	switch c.Type {
+	case ConstraintCheck:
+		return fmt.Sprintf("%q \tcheck %s", c.Name, c.Clause)
+	case ConstraintUnique:
+		return fmt.Sprintf("%q \tunique (%s)", c.Name, strings.Join(c.Columns, ", "))
+	}
+	return fmt.Sprintf("%q \t%v", c.Name, c.Type)
+}
+
+// Comparison is a check of a column against a numeric constant, such
+// as `qty > 0`.
+type Comparison struct {
+	Column string
+	Op     string // One of <, <=, >, >=, = and <>.
+	Value  string // The constant, as written in the clause.
+}
+
+var (
+	checkBetween  = regexp.MustCompile(`(?i)^(\w+)\s+BETWEEN\s+(\S+)\s+AND\s+(\S+)$`)
+	checkColFirst = regexp.MustCompile(`^(\w+)\s*(<=|>=|<>|!=|<|>|=)\s*(\S+)$`)
+	checkColLast  = regexp.MustCompile(`^(\S+)\s*(<=|>=|<>|!=|<|>|=)\s*(\w+)$`)
+	checkAnd      = regexp.MustCompile(`(?i)\s+AND\s+`)
+
+	flippedOps = map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<=", "=": "=", "<>": "<>"}
+)
+
+// Comparisons breaks the clause of a CHECK constraint into the
+// comparisons it is made of. It returns false unless the clause is
+// only comparisons between columns and numbers, joined by AND or
+// expressed with BETWEEN.
+func (c Constraint) Comparisons() ([]Comparison, bool) {
+	if c.Type != ConstraintCheck {
+		return nil, false
+	}
+	clause := strings.NewReplacer("`", "", `"`, "").Replace(c.Clause)
+	clause = trimParens(clause)
+
+	// BETWEEN has its own AND, so it's only understood alone
+	if m := checkBetween.FindStringSubmatch(clause); m != nil {
+		if !isNumber(m[2]) || !isNumber(m[3]) {
+			return nil, false
+		}
+		return []Comparison{
+			{Column: m[1], Op: ">=", Value: m[2]},
+			{Column: m[1], Op: "<=", Value: m[3]},
+		}, true
+	}
+
+	var cmps []Comparison
+	for _, part := range checkAnd.Split(clause, -1) {
+		part = trimParens(part)
+		if m := checkColFirst.FindStringSubmatch(part); m != nil && isNumber(m[3]) && !isNumber(m[1]) {
+			cmps = append(cmps, Comparison{Column: m[1], Op: normalizeOp(m[2]), Value: m[3]})
+			continue
+		}
+		if m := checkColLast.FindStringSubmatch(part); m != nil && isNumber(m[1]) && !isNumber(m[3]) {
+			cmps = append(cmps, Comparison{Column: m[3], Op: flippedOps[normalizeOp(m[2])], Value: m[1]})
+			continue
+		}
+		return nil, false
+	}
+	return cmps, len(cmps) != 0
+}
+
+// trimParens removes the parentheses wrapping all of str.
+func trimParens(str string) string {
+	for {
+		str = strings.TrimSpace(str)
+		if len(str) < 2 || str[0] != '(' || str[len(str)-1] != ')' {
+			return str
+		}
+		// `(a) AND (b)` isn't wrapped
+		depth := 0
+		for i, r := range str {
+			switch r {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth == 0 && i != len(str)-1 {
+				return str
+			}
+		}
+		str = str[1 : len(str)-1]
+	}
+}
+
+func normalizeOp(op string) string {
+	if op == "!=" {
+		return "<>"
+	}
+	return op
+}
+
+func isNumber(str string) bool {
+	_, err := strconv.ParseFloat(str, 64)
+	return err == nil
+}
+
+// collectUniqueConstraints records the unique indices of the table as
+// constraints.
+func (tbl *Table) collectUniqueConstraints() {
+	for _, idx := range tbl.Indices {
+		if idx.NonUnique {
+			continue
+		}
+		c := Constraint{Name: idx.KeyName, Type: ConstraintUnique}
+		for _, col := range idx.Columns {
+			c.Columns = append(c.Columns, col.Name)
+		}
+		tbl.Constraints = append(tbl.Constraints, c)
+	}
+}
+
+type constraintsByName []Constraint
+
+func (b constraintsByName) Len() int           { return len(b) }
+func (b constraintsByName) Less(i, j int) bool { return b[i].Name < b[j].Name }
+func (b constraintsByName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
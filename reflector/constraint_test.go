@@ -0,0 +1,59 @@
+package reflector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConstraintComparisons(t *testing.T) {
+	tests := []struct {
+		clause string
+		want   []Comparison
+		ok     bool
+	}{
+		{
+			clause: "(`qty` > 0)",
+			want:   []Comparison{{Column: "qty", Op: ">", Value: "0"}},
+			ok:     true,
+		},
+		{
+			clause: "((price >= 0) AND (price <= 99.99))",
+			want: []Comparison{
+				{Column: "price", Op: ">=", Value: "0"},
+				{Column: "price", Op: "<=", Value: "99.99"},
+			},
+			ok: true,
+		},
+		{
+			clause: "`rating` between 1 and 5",
+			want: []Comparison{
+				{Column: "rating", Op: ">=", Value: "1"},
+				{Column: "rating", Op: "<=", Value: "5"},
+			},
+			ok: true,
+		},
+		{
+			clause: "(10 > `level`)",
+			want:   []Comparison{{Column: "level", Op: "<", Value: "10"}},
+			ok:     true,
+		},
+		{
+			clause: "(`starts_at` < `ends_at`)",
+			ok:     false,
+		},
+		{
+			clause: "((a > 0) OR (b > 0))",
+			ok:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		got, ok := Constraint{Type: ConstraintCheck, Clause: tt.clause}.Comparisons()
+		if ok != tt.ok {
+			t.Fatalf("Comparisons(%q) ok=%v, want %v", tt.clause, ok, tt.ok)
+		}
+		if ok && !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("Comparisons(%q)=%v, want %v", tt.clause, got, tt.want)
+		}
+	}
+}
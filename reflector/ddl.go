@@ -0,0 +1,1652 @@
+package reflector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+DDL!
+*/
+
+// DescribeMySQLDDL builds the schema described by the MySQL migrations
+// in dirname, without connecting to a database. The `.sql` files are
+// applied in the order of their names. CREATE TABLE, CREATE INDEX,
+// ALTER TABLE, RENAME TABLE, DROP TABLE and DROP INDEX statements are
+// understood, the others are skipped.
+func DescribeMySQLDDL(dirname, dbname string) (*DBSchema, error) {
+	filenames, err := filepath.Glob(filepath.Join(dirname, "*.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("listing DDL files, %w", err)
+	}
+	sort.Strings(filenames)
+
+	ddl := newDDLSchema()
+	for _, filename := range filenames {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("reading DDL file, %w", err)
+		}
+		if err := ddl.parse(filepath.Base(filename), string(src)); err != nil {
+			return nil, err
+		}
+	}
+
+	schema := &DBSchema{
+		Name:    dbname,
+		Dialect: DialectMySQL,
+	}
+	return schema, ddl.build(schema)
+}
+
+// ddlSchema accumulates the tables created by DDL statements, until
+// they're built into a DBSchema.
+type ddlSchema struct {
+	tables map[string]*ddlTable
+}
+
+// ddlTable is a table as declared, with its columns in order and its
+// keys not yet bound to them.
+type ddlTable struct {
+	name    string
+	comment string
+	columns []Column
+	keys    []ddlKey
+	fks     []ForeignKey
+	checks  []Constraint
+
+	// counters naming the constraints, like MySQL does
+	fkSeq    int
+	checkSeq int
+}
+
+// ddlKey is an index or the primary key of a table.
+type ddlKey struct {
+	name      string
+	primary   bool
+	unique    bool
+	indexType IndexType
+	comment   string
+	parts     []ddlKeyPart
+}
+
+type ddlKeyPart struct {
+	column  string
+	subPart *int
+}
+
+func newDDLSchema() *ddlSchema {
+	return &ddlSchema{tables: make(map[string]*ddlTable)}
+}
+
+// build sets the tables of schema to those declared so far.
+func (s *ddlSchema) build(schema *DBSchema) error {
+	var names []string
+	for name := range s.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tbl, err := s.tables[name].build()
+		if err != nil {
+			return fmt.Errorf("loading table %q, %w", name, err)
+		}
+		schema.Tables = append(schema.Tables, tbl)
+	}
+	return nil
+}
+
+// build turns the declaration into a Table, as DescribeMySQL would
+// load it.
+func (t *ddlTable) build() (Table, error) {
+	tbl := Table{
+		Name:        t.name,
+		Comment:     t.comment,
+		Columns:     make([]Column, len(t.columns)),
+		ForeignKeys: append([]ForeignKey(nil), t.fks...),
+	}
+	copy(tbl.Columns, t.columns)
+
+	// the keys `describe` shows, in order of precedence
+	for i := range tbl.Columns {
+		col := &tbl.Columns[i]
+		var key string
+		for _, k := range t.keys {
+			if k.primary && k.has(col.Name) {
+				col.Nullable = false
+				key = "PRI"
+			}
+		}
+		for _, k := range t.keys {
+			if key == "" && k.unique && len(k.parts) == 1 && k.parts[0].column == col.Name {
+				key = "UNI"
+			}
+		}
+		for _, k := range t.keys {
+			if key == "" && k.parts[0].column == col.Name {
+				key = "MUL"
+			}
+		}
+		col.Key = []byte(key)
+	}
+
+	var parts []IndexPart
+	for _, k := range t.keys {
+		for i, p := range k.parts {
+			part := IndexPart{
+				Table:        t.name,
+				NonUnique:    !k.unique,
+				KeyName:      k.name,
+				SeqInIndex:   i + 1,
+				ColumnName:   p.column,
+				IsAscending:  true,
+				SubPart:      p.subPart,
+				IndexType:    k.indexType,
+				IndexComment: k.comment,
+			}
+			if col := tbl.Has(p.column); col != nil {
+				part.CanBeNull = col.Nullable
+			}
+			if err := part.bindColumn(&tbl); err != nil {
+				return tbl, err
+			}
+			parts = append(parts, part)
+		}
+	}
+	tbl.Pk, tbl.Indices = indicesFromParts(parts)
+
+	sort.Sort(indexByKeyName(tbl.Indices))
+
+	tbl.collectUniqueConstraints()
+	tbl.Constraints = append(tbl.Constraints, t.checks...)
+	sort.Sort(constraintsByName(tbl.Constraints))
+
+	sort.Sort(foreignKeysByName(tbl.ForeignKeys))
+
+	return tbl, nil
+}
+
+func (k ddlKey) has(colname string) bool {
+	for _, p := range k.parts {
+		if p.column == colname {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Parsing!
+*/
+
+// ddlParser reads the statements of one DDL file.
+type ddlParser struct {
+	schema   *ddlSchema
+	filename string
+	src      string
+	toks     []ddlToken
+	i        int
+}
+
+// parse applies the statements of src, read from filename, to the
+// schema.
+func (s *ddlSchema) parse(filename, src string) error {
+	src = replaceDelimiters(src)
+	toks, err := lexDDL(src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	p := &ddlParser{schema: s, filename: filename, src: src, toks: toks}
+	for !p.done() {
+		start := p.i
+		if err := p.statement(); err != nil {
+			return fmt.Errorf("%s:%d: %w", filename, p.line(start), err)
+		}
+	}
+	return nil
+}
+
+func (p *ddlParser) line(i int) int {
+	if i >= len(p.toks) {
+		i = len(p.toks) - 1
+	}
+	if i < 0 {
+		return 1
+	}
+	return strings.Count(p.src[:p.toks[i].pos], "\n") + 1
+}
+
+func (p *ddlParser) done() bool { return p.i >= len(p.toks) }
+
+// peek returns the next token, or an empty one at the end of the file.
+func (p *ddlParser) peek() ddlToken {
+	if p.done() {
+		return ddlToken{kind: ddlPunct, pos: len(p.src), end: len(p.src)}
+	}
+	return p.toks[p.i]
+}
+
+func (p *ddlParser) next() ddlToken {
+	tok := p.peek()
+	if !p.done() {
+		p.i++
+	}
+	return tok
+}
+
+// accept consumes the next tokens if they're the keywords, in order.
+func (p *ddlParser) accept(keywords ...string) bool {
+	if p.i+len(keywords) > len(p.toks) {
+		return false
+	}
+	for j, kw := range keywords {
+		if !p.toks[p.i+j].is(kw) {
+			return false
+		}
+	}
+	p.i += len(keywords)
+	return true
+}
+
+func (p *ddlParser) acceptPunct(punct string) bool {
+	if p.peek().isPunct(punct) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *ddlParser) expect(keywords ...string) error {
+	if !p.accept(keywords...) {
+		return fmt.Errorf("expected %s, got %s", strings.ToUpper(strings.Join(keywords, " ")), p.describe(p.peek()))
+	}
+	return nil
+}
+
+func (p *ddlParser) expectPunct(punct string) error {
+	if !p.acceptPunct(punct) {
+		return fmt.Errorf("expected %q, got %s", punct, p.describe(p.peek()))
+	}
+	return nil
+}
+
+func (p *ddlParser) describe(tok ddlToken) string {
+	if tok.pos == len(p.src) {
+		return "end of file"
+	}
+	return strconv.Quote(p.src[tok.pos:tok.end])
+}
+
+// endOfStatement tells if the next token ends the statement.
+func (p *ddlParser) endOfStatement() bool {
+	return p.done() || p.peek().isPunct(";")
+}
+
+// skipStatement consumes tokens up to the end of the statement,
+// which is past the BEGIN ... END blocks of triggers and routines.
+func (p *ddlParser) skipStatement() {
+	for depth := 0; !p.done(); {
+		tok := p.next()
+		switch {
+		case tok.isPunct(";") && depth == 0:
+			return
+		case tok.is("begin") && !p.peek().isPunct(";") && !p.peek().is("work"):
+			// not a transaction
+			depth++
+		case tok.is("end") && depth > 0 && !p.peek().is("if", "loop", "while", "repeat", "case"):
+			depth--
+		}
+	}
+}
+
+// skipClause consumes tokens up to the next comma or closing paren
+// outside of parens, or the end of the statement.
+func (p *ddlParser) skipClause() error {
+	for !p.endOfStatement() {
+		tok := p.peek()
+		if tok.isPunct(",") || tok.isPunct(")") {
+			return nil
+		}
+		if tok.isPunct("(") {
+			if _, err := p.parens(); err != nil {
+				return err
+			}
+			continue
+		}
+		p.next()
+	}
+	return nil
+}
+
+// parens consumes balanced parens and returns their source, parens
+// included.
+func (p *ddlParser) parens() (string, error) {
+	open := p.peek()
+	if err := p.expectPunct("("); err != nil {
+		return "", err
+	}
+	for depth := 1; ; {
+		if p.endOfStatement() {
+			return "", fmt.Errorf("unbalanced parens")
+		}
+		tok := p.next()
+		switch {
+		case tok.isPunct("("):
+			depth++
+		case tok.isPunct(")"):
+			depth--
+			if depth == 0 {
+				return p.src[open.pos:tok.end], nil
+			}
+		}
+	}
+}
+
+// ident reads an identifier, quoted or not.
+func (p *ddlParser) ident() (string, error) {
+	tok := p.peek()
+	if tok.kind != ddlWord && tok.kind != ddlIdent {
+		return "", fmt.Errorf("expected a name, got %s", p.describe(tok))
+	}
+	p.i++
+	return tok.text, nil
+}
+
+// tableName reads the name of a table, dropping its database.
+func (p *ddlParser) tableName() (string, error) {
+	name, err := p.ident()
+	if err != nil {
+		return "", err
+	}
+	if p.acceptPunct(".") {
+		return p.ident()
+	}
+	return name, nil
+}
+
+// str reads a string literal.
+func (p *ddlParser) str() (string, error) {
+	tok := p.next()
+	if tok.kind != ddlString {
+		return "", fmt.Errorf("expected a string, got %s", p.describe(tok))
+	}
+	return tok.text, nil
+}
+
+func (p *ddlParser) table(name string) (*ddlTable, error) {
+	tbl, ok := p.schema.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("table %q doesn't exist", name)
+	}
+	return tbl, nil
+}
+
+/*
+Statements!
+*/
+
+func (p *ddlParser) statement() error {
+	switch {
+	case p.acceptPunct(";"):
+		return nil
+
+	case p.accept("create"):
+		p.accept("or", "replace")
+		switch {
+		case p.accept("temporary"):
+			// not part of the schema
+		case p.accept("table"):
+			return p.createTable()
+		case p.accept("index"):
+			return p.createIndex(ddlKey{indexType: IndexBtree})
+		case p.accept("unique", "index"):
+			return p.createIndex(ddlKey{unique: true, indexType: IndexBtree})
+		case p.accept("fulltext", "index"):
+			return p.createIndex(ddlKey{indexType: IndexFulltext})
+		case p.accept("spatial", "index"):
+			return p.createIndex(ddlKey{indexType: IndexRtree})
+		}
+
+	case p.accept("alter", "table"), p.accept("alter", "ignore", "table"):
+		return p.alterTable()
+
+	case p.accept("drop", "table"), p.accept("drop", "temporary", "table"):
+		return p.dropTable()
+
+	case p.accept("drop", "index"):
+		return p.dropIndex()
+
+	case p.accept("rename", "table"):
+		return p.renameTables()
+	}
+	p.skipStatement()
+	return nil
+}
+
+func (p *ddlParser) endStatement() error {
+	if !p.endOfStatement() {
+		return fmt.Errorf("unexpected %s", p.describe(p.peek()))
+	}
+	p.acceptPunct(";")
+	return nil
+}
+
+func (p *ddlParser) createTable() error {
+	ifNotExists := p.accept("if", "not", "exists")
+	name, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	if _, ok := p.schema.tables[name]; ok {
+		if ifNotExists {
+			p.skipStatement()
+			return nil
+		}
+		return fmt.Errorf("table %q already exists", name)
+	}
+
+	if p.accept("like") || p.peek().isPunct("(") && p.i+1 < len(p.toks) && p.toks[p.i+1].is("like") {
+		return p.createTableLike(name)
+	}
+
+	tbl := &ddlTable{name: name}
+	if err := p.expectPunct("("); err != nil {
+		return err
+	}
+	for {
+		if err := p.tableElement(tbl, false); err != nil {
+			return err
+		}
+		if !p.acceptPunct(",") {
+			break
+		}
+	}
+	if err := p.expectPunct(")"); err != nil {
+		return err
+	}
+	if err := p.tableOptions(tbl); err != nil {
+		return err
+	}
+	if p.accept("as") || p.accept("select") || p.accept("ignore") || p.accept("replace") {
+		return fmt.Errorf("CREATE TABLE ... SELECT isn't supported")
+	}
+	if err := tbl.check(); err != nil {
+		return err
+	}
+	p.schema.tables[name] = tbl
+	return p.endStatement()
+}
+
+func (p *ddlParser) createTableLike(name string) error {
+	paren := p.acceptPunct("(")
+	p.accept("like")
+	orig, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	if paren {
+		if err := p.expectPunct(")"); err != nil {
+			return err
+		}
+	}
+	src, err := p.table(orig)
+	if err != nil {
+		return err
+	}
+	tbl := &ddlTable{
+		name:    name,
+		comment: src.comment,
+		columns: append([]Column(nil), src.columns...),
+		checks:  append([]Constraint(nil), src.checks...),
+	}
+	for _, k := range src.keys {
+		k.parts = append([]ddlKeyPart(nil), k.parts...)
+		tbl.keys = append(tbl.keys, k)
+	}
+	// foreign keys aren't copied, checks are renamed
+	for i := range tbl.checks {
+		tbl.checkSeq++
+		tbl.checks[i].Name = fmt.Sprintf("%s_chk_%d", name, tbl.checkSeq)
+	}
+	p.schema.tables[name] = tbl
+	return p.endStatement()
+}
+
+// tableOptions reads the options following the definition of a
+// table, keeping its comment.
+func (p *ddlParser) tableOptions(tbl *ddlTable) error {
+	for !p.endOfStatement() && !p.peek().is("as", "select", "ignore", "replace") {
+		switch {
+		case p.accept("comment"):
+			p.acceptPunct("=")
+			comment, err := p.str()
+			if err != nil {
+				return err
+			}
+			tbl.comment = comment
+		case p.accept("partition", "by"):
+			// partitions run to the end of the statement
+			for !p.endOfStatement() {
+				p.next()
+			}
+		case p.peek().isPunct("("):
+			if _, err := p.parens(); err != nil {
+				return err
+			}
+		default:
+			p.next()
+		}
+	}
+	return nil
+}
+
+func (p *ddlParser) createIndex(key ddlKey) error {
+	var err error
+	if key.name, err = p.ident(); err != nil {
+		return err
+	}
+	if err := p.indexType(&key); err != nil {
+		return err
+	}
+	if err := p.expect("on"); err != nil {
+		return err
+	}
+	name, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	tbl, err := p.table(name)
+	if err != nil {
+		return err
+	}
+	if err := p.keyParts(&key); err != nil {
+		return err
+	}
+	if err := p.indexOptions(&key); err != nil {
+		return err
+	}
+	// ALGORITHM and LOCK don't change the index
+	if err := tbl.addKey(key); err != nil {
+		return err
+	}
+	p.skipStatement()
+	return nil
+}
+
+func (p *ddlParser) dropTable() error {
+	ifExists := p.accept("if", "exists")
+	for {
+		name, err := p.tableName()
+		if err != nil {
+			return err
+		}
+		if _, ok := p.schema.tables[name]; !ok && !ifExists {
+			return fmt.Errorf("table %q doesn't exist", name)
+		}
+		delete(p.schema.tables, name)
+		if !p.acceptPunct(",") {
+			break
+		}
+	}
+	p.skipStatement()
+	return nil
+}
+
+func (p *ddlParser) dropIndex() error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("on"); err != nil {
+		return err
+	}
+	tblname, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	tbl, err := p.table(tblname)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(name, "PRIMARY") {
+		err = tbl.dropPrimaryKey()
+	} else {
+		err = tbl.dropKey(name)
+	}
+	if err != nil {
+		return err
+	}
+	p.skipStatement()
+	return nil
+}
+
+func (p *ddlParser) renameTables() error {
+	for {
+		from, err := p.tableName()
+		if err != nil {
+			return err
+		}
+		if err := p.expect("to"); err != nil {
+			return err
+		}
+		to, err := p.tableName()
+		if err != nil {
+			return err
+		}
+		if err := p.schema.renameTable(from, to); err != nil {
+			return err
+		}
+		if !p.acceptPunct(",") {
+			break
+		}
+	}
+	return p.endStatement()
+}
+
+func (s *ddlSchema) renameTable(from, to string) error {
+	tbl, ok := s.tables[from]
+	if !ok {
+		return fmt.Errorf("table %q doesn't exist", from)
+	}
+	if _, ok := s.tables[to]; ok {
+		return fmt.Errorf("table %q already exists", to)
+	}
+	delete(s.tables, from)
+	tbl.name = to
+	s.tables[to] = tbl
+	for _, other := range s.tables {
+		for i := range other.fks {
+			if other.fks[i].RefTable == from {
+				other.fks[i].RefTable = to
+			}
+		}
+	}
+	return nil
+}
+
+func (p *ddlParser) alterTable() error {
+	name, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	tbl, err := p.table(name)
+	if err != nil {
+		return err
+	}
+	for !p.endOfStatement() {
+		if err := p.alterSpec(tbl); err != nil {
+			return err
+		}
+		if !p.acceptPunct(",") {
+			break
+		}
+	}
+	if err := tbl.check(); err != nil {
+		return err
+	}
+	return p.endStatement()
+}
+
+func (p *ddlParser) alterSpec(tbl *ddlTable) error {
+	switch {
+	case p.accept("add"):
+		if p.accept("column") || !p.peek().is("constraint", "primary", "unique",
+			"index", "key", "fulltext", "spatial", "foreign", "check") {
+			return p.addColumns(tbl)
+		}
+		return p.tableElement(tbl, true)
+
+	case p.accept("drop"):
+		return p.dropSpec(tbl)
+
+	case p.accept("modify"):
+		p.accept("column")
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		return p.changeColumn(tbl, name, name)
+
+	case p.accept("change"):
+		p.accept("column")
+		old, err := p.ident()
+		if err != nil {
+			return err
+		}
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		return p.changeColumn(tbl, old, name)
+
+	case p.accept("rename", "column"):
+		old, err := p.ident()
+		if err != nil {
+			return err
+		}
+		if err := p.expect("to"); err != nil {
+			return err
+		}
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		i := tbl.column(old)
+		if i < 0 {
+			return fmt.Errorf("column %q doesn't exist", old)
+		}
+		tbl.columns[i].Name = name
+		tbl.renameColumn(old, name)
+		return nil
+
+	case p.accept("rename", "index"), p.accept("rename", "key"):
+		old, err := p.ident()
+		if err != nil {
+			return err
+		}
+		if err := p.expect("to"); err != nil {
+			return err
+		}
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		for i := range tbl.keys {
+			if tbl.keys[i].name == old {
+				tbl.keys[i].name = name
+				return nil
+			}
+		}
+		return fmt.Errorf("index %q doesn't exist", old)
+
+	case p.accept("rename"):
+		if !p.accept("to") {
+			p.accept("as")
+		}
+		name, err := p.tableName()
+		if err != nil {
+			return err
+		}
+		return p.schema.renameTable(tbl.name, name)
+
+	case p.accept("alter"):
+		p.accept("column")
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		i := tbl.column(name)
+		if i < 0 {
+			return fmt.Errorf("column %q doesn't exist", name)
+		}
+		col := &tbl.columns[i]
+		switch {
+		case p.accept("set", "default"):
+			return p.columnDefault(col)
+		case p.accept("drop", "default"):
+			col.Default = nil
+			col.Extra = []byte(removeWord(string(col.Extra.([]byte)), "DEFAULT_GENERATED"))
+			return nil
+		}
+		// visibility
+		return p.skipClause()
+
+	case p.accept("comment"):
+		p.acceptPunct("=")
+		comment, err := p.str()
+		if err != nil {
+			return err
+		}
+		tbl.comment = comment
+		return nil
+
+	case p.accept("partition", "by"):
+		for !p.endOfStatement() {
+			p.next()
+		}
+		return nil
+	}
+	// table options, ALGORITHM, LOCK and the like don't change the
+	// schema
+	return p.skipClause()
+}
+
+func (p *ddlParser) addColumns(tbl *ddlTable) error {
+	if !p.acceptPunct("(") {
+		col, err := p.columnDefinition(tbl)
+		if err != nil {
+			return err
+		}
+		return p.placeColumn(tbl, col, -1)
+	}
+	for {
+		if err := p.tableElement(tbl, true); err != nil {
+			return err
+		}
+		if !p.acceptPunct(",") {
+			break
+		}
+	}
+	return p.expectPunct(")")
+}
+
+// placeColumn adds col to tbl, in place of column i if it isn't -1, at
+// the position given by FIRST or AFTER if any.
+func (p *ddlParser) placeColumn(tbl *ddlTable, col Column, i int) error {
+	if i < 0 && tbl.column(col.Name) >= 0 {
+		return fmt.Errorf("column %q already exists", col.Name)
+	}
+	pos := len(tbl.columns)
+	switch {
+	case p.accept("first"):
+		pos = 0
+	case p.accept("after"):
+		after, err := p.ident()
+		if err != nil {
+			return err
+		}
+		if pos = tbl.column(after); pos < 0 {
+			return fmt.Errorf("column %q doesn't exist", after)
+		}
+		pos++
+	case i >= 0:
+		tbl.columns[i] = col
+		return nil
+	}
+	if i >= 0 {
+		tbl.columns = append(tbl.columns[:i], tbl.columns[i+1:]...)
+		if pos > i {
+			pos--
+		}
+	}
+	tbl.columns = append(tbl.columns, Column{})
+	copy(tbl.columns[pos+1:], tbl.columns[pos:])
+	tbl.columns[pos] = col
+	return nil
+}
+
+func (p *ddlParser) changeColumn(tbl *ddlTable, old, name string) error {
+	i := tbl.column(old)
+	if i < 0 {
+		return fmt.Errorf("column %q doesn't exist", old)
+	}
+	if name != old && tbl.column(name) >= 0 {
+		return fmt.Errorf("column %q already exists", name)
+	}
+	// the definition starts with the column's new name
+	p.i--
+	col, err := p.columnDefinition(tbl)
+	if err != nil {
+		return err
+	}
+	tbl.renameColumn(old, name)
+	return p.placeColumn(tbl, col, i)
+}
+
+func (p *ddlParser) dropSpec(tbl *ddlTable) error {
+	switch {
+	case p.accept("primary", "key"):
+		return tbl.dropPrimaryKey()
+
+	case p.accept("index"), p.accept("key"):
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		return tbl.dropKey(name)
+
+	case p.accept("foreign", "key"):
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		for i, fk := range tbl.fks {
+			if fk.Name == name {
+				tbl.fks = append(tbl.fks[:i], tbl.fks[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("foreign key %q doesn't exist", name)
+
+	case p.accept("check"), p.accept("constraint"):
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		for i, c := range tbl.checks {
+			if c.Name == name {
+				tbl.checks = append(tbl.checks[:i], tbl.checks[i+1:]...)
+				return nil
+			}
+		}
+		for i, fk := range tbl.fks {
+			if fk.Name == name {
+				tbl.fks = append(tbl.fks[:i], tbl.fks[i+1:]...)
+				return nil
+			}
+		}
+		return tbl.dropKey(name)
+	}
+
+	p.accept("column")
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	i := tbl.column(name)
+	if i < 0 {
+		return fmt.Errorf("column %q doesn't exist", name)
+	}
+	tbl.columns = append(tbl.columns[:i], tbl.columns[i+1:]...)
+
+	// indices lose the column, and go away with their last one
+	keys := tbl.keys[:0]
+	for _, k := range tbl.keys {
+		parts := k.parts[:0]
+		for _, part := range k.parts {
+			if part.column != name {
+				parts = append(parts, part)
+			}
+		}
+		k.parts = parts
+		if len(k.parts) != 0 {
+			keys = append(keys, k)
+		}
+	}
+	tbl.keys = keys
+	return nil
+}
+
+/*
+Table elements!
+*/
+
+// tableElement reads a column, index or constraint definition. Columns
+// are placed with FIRST and AFTER when altering a table.
+func (p *ddlParser) tableElement(tbl *ddlTable, altering bool) error {
+	var constraint string
+	if p.accept("constraint") {
+		if !p.peek().is("primary", "unique", "foreign", "check") {
+			name, err := p.ident()
+			if err != nil {
+				return err
+			}
+			constraint = name
+		}
+	}
+
+	switch {
+	case p.accept("primary", "key"):
+		key := ddlKey{name: "PRIMARY", primary: true, unique: true, indexType: IndexBtree}
+		return p.keyDefinition(tbl, key, false)
+
+	case p.accept("unique"):
+		if !p.accept("index") {
+			p.accept("key")
+		}
+		key := ddlKey{name: constraint, unique: true, indexType: IndexBtree}
+		return p.keyDefinition(tbl, key, true)
+
+	case p.accept("foreign", "key"):
+		return p.foreignKey(tbl, constraint)
+
+	case p.accept("check"):
+		return p.checkConstraint(tbl, constraint)
+
+	case constraint != "":
+		return fmt.Errorf("unexpected %s", p.describe(p.peek()))
+
+	case p.accept("index"), p.accept("key"):
+		return p.keyDefinition(tbl, ddlKey{indexType: IndexBtree}, true)
+
+	case p.accept("fulltext"), p.accept("spatial"):
+		key := ddlKey{indexType: IndexFulltext}
+		if p.toks[p.i-1].is("spatial") {
+			key.indexType = IndexRtree
+		}
+		if !p.accept("index") {
+			p.accept("key")
+		}
+		return p.keyDefinition(tbl, key, true)
+	}
+
+	col, err := p.columnDefinition(tbl)
+	if err != nil {
+		return err
+	}
+	if altering {
+		return p.placeColumn(tbl, col, -1)
+	}
+	if tbl.column(col.Name) >= 0 {
+		return fmt.Errorf("column %q already exists", col.Name)
+	}
+	tbl.columns = append(tbl.columns, col)
+	return nil
+}
+
+// keyDefinition reads the rest of an index definition, named if named
+// is set and a name is there.
+func (p *ddlParser) keyDefinition(tbl *ddlTable, key ddlKey, named bool) error {
+	if named && !p.peek().isPunct("(") && !p.peek().is("using") {
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		key.name = name
+	}
+	if err := p.indexType(&key); err != nil {
+		return err
+	}
+	if err := p.keyParts(&key); err != nil {
+		return err
+	}
+	if err := p.indexOptions(&key); err != nil {
+		return err
+	}
+	return tbl.addKey(key)
+}
+
+func (p *ddlParser) indexType(key *ddlKey) error {
+	if !p.accept("using") {
+		return nil
+	}
+	switch {
+	case p.accept("btree"):
+		key.indexType = IndexBtree
+	case p.accept("hash"):
+		key.indexType = IndexHash
+	case p.accept("rtree"):
+		key.indexType = IndexRtree
+	default:
+		return fmt.Errorf("unknown index type %s", p.describe(p.peek()))
+	}
+	return nil
+}
+
+func (p *ddlParser) keyParts(key *ddlKey) error {
+	if err := p.expectPunct("("); err != nil {
+		return err
+	}
+	for {
+		if p.peek().isPunct("(") {
+			return fmt.Errorf("functional key parts aren't supported")
+		}
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		part := ddlKeyPart{column: name}
+		if p.acceptPunct("(") {
+			n, err := p.integer()
+			if err != nil {
+				return err
+			}
+			part.subPart = &n
+			if err := p.expectPunct(")"); err != nil {
+				return err
+			}
+		}
+		if !p.accept("asc") {
+			p.accept("desc")
+		}
+		key.parts = append(key.parts, part)
+		if !p.acceptPunct(",") {
+			break
+		}
+	}
+	return p.expectPunct(")")
+}
+
+func (p *ddlParser) indexOptions(key *ddlKey) error {
+	for {
+		switch {
+		case p.peek().is("using"):
+			if err := p.indexType(key); err != nil {
+				return err
+			}
+		case p.accept("comment"):
+			comment, err := p.str()
+			if err != nil {
+				return err
+			}
+			key.comment = comment
+		case p.accept("key_block_size"):
+			p.acceptPunct("=")
+			p.next()
+		case p.accept("with", "parser"):
+			p.next()
+		case p.accept("visible"), p.accept("invisible"):
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *ddlParser) integer() (int, error) {
+	tok := p.next()
+	n, err := strconv.Atoi(tok.text)
+	if tok.kind != ddlNumber || err != nil {
+		return 0, fmt.Errorf("expected an integer, got %s", p.describe(tok))
+	}
+	return n, nil
+}
+
+func (p *ddlParser) foreignKey(tbl *ddlTable, name string) error {
+	var index string
+	if !p.peek().isPunct("(") {
+		var err error
+		if index, err = p.ident(); err != nil {
+			return err
+		}
+	}
+	key := ddlKey{indexType: IndexBtree}
+	if err := p.keyParts(&key); err != nil {
+		return err
+	}
+	fk := ForeignKey{Name: name, OnDelete: "NO ACTION", OnUpdate: "NO ACTION"}
+	for _, part := range key.parts {
+		fk.Columns = append(fk.Columns, part.column)
+	}
+	if err := p.references(&fk); err != nil {
+		return err
+	}
+	if len(fk.Columns) != len(fk.RefColumns) {
+		return fmt.Errorf("foreign key has %d columns, references %d", len(fk.Columns), len(fk.RefColumns))
+	}
+	if fk.Name == "" {
+		tbl.fkSeq++
+		fk.Name = fmt.Sprintf("%s_ibfk_%d", tbl.name, tbl.fkSeq)
+	}
+	for _, other := range tbl.fks {
+		if other.Name == fk.Name {
+			return fmt.Errorf("foreign key %q already exists", fk.Name)
+		}
+	}
+	tbl.fks = append(tbl.fks, fk)
+
+	// MySQL indexes the columns of foreign keys, unless an index
+	// already starts with them
+	for _, k := range tbl.keys {
+		if k.startsWith(fk.Columns) {
+			return nil
+		}
+	}
+	switch {
+	case name != "":
+		key.name = name
+	case index != "":
+		key.name = index
+	}
+	return tbl.addKey(key)
+}
+
+func (k ddlKey) startsWith(cols []string) bool {
+	if len(k.parts) < len(cols) {
+		return false
+	}
+	for i, col := range cols {
+		if k.parts[i].column != col {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *ddlParser) references(fk *ForeignKey) error {
+	if err := p.expect("references"); err != nil {
+		return err
+	}
+	var err error
+	if fk.RefTable, err = p.tableName(); err != nil {
+		return err
+	}
+	ref := ddlKey{}
+	if err := p.keyParts(&ref); err != nil {
+		return err
+	}
+	for _, part := range ref.parts {
+		fk.RefColumns = append(fk.RefColumns, part.column)
+	}
+	if p.accept("match") {
+		p.next()
+	}
+	for p.accept("on") {
+		var rule *string
+		switch {
+		case p.accept("delete"):
+			rule = &fk.OnDelete
+		case p.accept("update"):
+			rule = &fk.OnUpdate
+		default:
+			return fmt.Errorf("expected DELETE or UPDATE, got %s", p.describe(p.peek()))
+		}
+		switch {
+		case p.accept("cascade"):
+			*rule = "CASCADE"
+		case p.accept("restrict"):
+			*rule = "RESTRICT"
+		case p.accept("set", "null"):
+			*rule = "SET NULL"
+		case p.accept("set", "default"):
+			*rule = "SET DEFAULT"
+		case p.accept("no", "action"):
+			*rule = "NO ACTION"
+		default:
+			return fmt.Errorf("unknown rule %s", p.describe(p.peek()))
+		}
+	}
+	return nil
+}
+
+func (p *ddlParser) checkConstraint(tbl *ddlTable, name string) error {
+	clause, err := p.parens()
+	if err != nil {
+		return err
+	}
+	p.accept("not")
+	p.accept("enforced")
+	if name == "" {
+		tbl.checkSeq++
+		name = fmt.Sprintf("%s_chk_%d", tbl.name, tbl.checkSeq)
+	}
+	tbl.checks = append(tbl.checks, Constraint{Name: name, Type: ConstraintCheck, Clause: clause})
+	return nil
+}
+
+/*
+Column definitions!
+*/
+
+// typeAliases are the names MySQL accepts for types it describes with
+// other names.
+var typeAliases = map[string]string{
+	"integer":   "int",
+	"int1":      "tinyint",
+	"int2":      "smallint",
+	"int3":      "mediumint",
+	"int4":      "int",
+	"int8":      "bigint",
+	"middleint": "mediumint",
+	"float4":    "float",
+	"float8":    "double",
+	"real":      "double",
+	"dec":       "decimal",
+	"numeric":   "decimal",
+	"fixed":     "decimal",
+	"bool":      "tinyint(1)",
+	"boolean":   "tinyint(1)",
+	"nchar":     "char",
+	"nvarchar":  "varchar",
+	"character": "char",
+}
+
+// columnDefinition reads a column's name, type and attributes, as
+// `describe` would show them.
+func (p *ddlParser) columnDefinition(tbl *ddlTable) (Column, error) {
+	var (
+		col   Column
+		extra []string
+		err   error
+	)
+	if col.Name, err = p.ident(); err != nil {
+		return col, err
+	}
+
+	typeName, err := p.columnType()
+	if err != nil {
+		return col, err
+	}
+	serial := typeName == "serial"
+	if serial {
+		typeName = "bigint unsigned"
+	}
+	col.TypeName = typeName
+	if col.Type, err = parseSQLTypeName(typeName); err != nil {
+		return col, err
+	}
+	col.parseTypeDetails(typeName)
+	if col.Type == SQLEnum || col.Type == SQLSet {
+		col.Values = parseEnumValues(typeName)
+	}
+	col.Nullable = true
+	col.Extra = []byte{}
+	if serial {
+		// an alias for all of this
+		col.Nullable = false
+		extra = append(extra, "auto_increment")
+		if err := tbl.addKey(ddlKey{unique: true, indexType: IndexBtree, parts: []ddlKeyPart{{column: col.Name}}}); err != nil {
+			return col, err
+		}
+	}
+
+	for !p.endOfStatement() && !p.peek().isPunct(",") && !p.peek().isPunct(")") &&
+		!p.peek().is("first", "after") {
+		switch {
+		case p.accept("not", "null"):
+			col.Nullable = false
+		case p.accept("null"):
+			col.Nullable = true
+		case p.accept("default"):
+			if err := p.columnDefault(&col); err != nil {
+				return col, err
+			}
+		case p.accept("auto_increment"):
+			extra = append(extra, "auto_increment")
+		case p.accept("on", "update"):
+			expr, err := p.currentTimestamp()
+			if err != nil {
+				return col, err
+			}
+			extra = append(extra, "on update "+expr)
+		case p.accept("generated", "always", "as"), p.accept("as"):
+			if _, err := p.parens(); err != nil {
+				return col, err
+			}
+			generated := "VIRTUAL GENERATED"
+			if p.accept("stored") || p.accept("persistent") {
+				generated = "STORED GENERATED"
+			} else {
+				p.accept("virtual")
+			}
+			extra = append(extra, generated)
+		case p.accept("comment"):
+			if col.Comment, err = p.str(); err != nil {
+				return col, err
+			}
+		case p.accept("unique"):
+			p.accept("key")
+			key := ddlKey{unique: true, indexType: IndexBtree, parts: []ddlKeyPart{{column: col.Name}}}
+			if err := tbl.addKey(key); err != nil {
+				return col, err
+			}
+		case p.accept("primary", "key"), p.accept("key"):
+			key := ddlKey{name: "PRIMARY", primary: true, unique: true, indexType: IndexBtree, parts: []ddlKeyPart{{column: col.Name}}}
+			if err := tbl.addKey(key); err != nil {
+				return col, err
+			}
+		case p.accept("constraint"):
+			var name string
+			if !p.peek().is("check") {
+				if name, err = p.ident(); err != nil {
+					return col, err
+				}
+			}
+			if err := p.expect("check"); err != nil {
+				return col, err
+			}
+			if err := p.checkConstraint(tbl, name); err != nil {
+				return col, err
+			}
+		case p.accept("check"):
+			if err := p.checkConstraint(tbl, ""); err != nil {
+				return col, err
+			}
+		case p.peek().is("references"):
+			// MySQL ignores foreign keys declared on columns
+			var fk ForeignKey
+			if err := p.references(&fk); err != nil {
+				return col, err
+			}
+		case p.accept("collate"), p.accept("charset"), p.accept("character", "set"),
+			p.accept("column_format"), p.accept("storage"), p.accept("srid"):
+			p.next()
+		case p.accept("visible"), p.accept("invisible"), p.accept("binary"),
+			p.accept("ascii"), p.accept("unicode"):
+		default:
+			return col, fmt.Errorf("unexpected %s in definition of column %q", p.describe(p.peek()), col.Name)
+		}
+	}
+	col.Extra = []byte(strings.Join(append(strings.Fields(string(col.Extra.([]byte))), extra...), " "))
+	return col, nil
+}
+
+// columnType reads a type, like `int(10) unsigned` or `enum('a','b')`,
+// and returns it as `describe` would name it.
+func (p *ddlParser) columnType() (string, error) {
+	tok := p.next()
+	if tok.kind != ddlWord {
+		return "", fmt.Errorf("expected a type, got %s", p.describe(tok))
+	}
+	name := strings.ToLower(tok.text)
+	switch {
+	case name == "double" && p.accept("precision"):
+	case name == "national":
+		return p.columnType()
+	case name == "long" && p.accept("varbinary"):
+		name = "mediumblob"
+	case name == "long":
+		p.accept("varchar")
+		name = "mediumtext"
+	case name == "char" && p.accept("varying"), name == "character" && p.accept("varying"):
+		name = "varchar"
+	}
+	if alias, ok := typeAliases[name]; ok {
+		name = alias
+	}
+	if p.peek().isPunct("(") {
+		args, err := p.parens()
+		if err != nil {
+			return "", err
+		}
+		name += args
+	}
+	for {
+		switch {
+		case p.accept("unsigned"):
+			name += " unsigned"
+		case p.accept("zerofill"):
+			// implies unsigned
+			if !strings.HasSuffix(name, " unsigned") {
+				name += " unsigned"
+			}
+			name += " zerofill"
+		case p.accept("signed"):
+		default:
+			return name, nil
+		}
+	}
+}
+
+// columnDefault reads the DEFAULT of col, setting DEFAULT_GENERATED in
+// its extras when it's an expression.
+func (p *ddlParser) columnDefault(col *Column) error {
+	tok := p.peek()
+	var (
+		lit  string
+		expr string
+	)
+	switch {
+	case p.accept("null"):
+		col.Default = nil
+		return nil
+	case p.accept("true"):
+		lit = "1"
+	case p.accept("false"):
+		lit = "0"
+	case tok.kind == ddlString, tok.kind == ddlNumber:
+		p.next()
+		lit = tok.text
+	case tok.isPunct("-"), tok.isPunct("+"):
+		p.next()
+		num := p.next()
+		if num.kind != ddlNumber {
+			return fmt.Errorf("expected a number, got %s", p.describe(num))
+		}
+		lit = strings.TrimPrefix(tok.text, "+") + num.text
+	case tok.isPunct("("):
+		src, err := p.parens()
+		if err != nil {
+			return err
+		}
+		expr = src[1 : len(src)-1]
+	default:
+		src, err := p.currentTimestamp()
+		if err != nil {
+			return err
+		}
+		expr = src
+	}
+
+	if expr != "" {
+		col.Default = expr
+		col.Extra = []byte(strings.TrimSpace(removeWord(string(col.Extra.([]byte)), "DEFAULT_GENERATED") + " DEFAULT_GENERATED"))
+		return nil
+	}
+	col.Extra = []byte(removeWord(string(col.Extra.([]byte)), "DEFAULT_GENERATED"))
+
+	var (
+		v   interface{}
+		err error
+	)
+	if col.Type == SQLInteger && col.Unsigned {
+		v, err = strconv.ParseUint(lit, 10, 64)
+	} else {
+		v, err = col.Type.ParseBytes([]byte(lit))
+	}
+	if err != nil {
+		// like zero dates, kept as written
+		v = lit
+	}
+	col.Default = v
+	return nil
+}
+
+// currentTimestamp reads CURRENT_TIMESTAMP or one of its synonyms,
+// returning the name MySQL reports.
+func (p *ddlParser) currentTimestamp() (string, error) {
+	tok := p.next()
+	if !tok.is("current_timestamp", "now", "localtime", "localtimestamp") {
+		return "", fmt.Errorf("unsupported expression %s", p.describe(tok))
+	}
+	expr := "CURRENT_TIMESTAMP"
+	if p.peek().isPunct("(") {
+		args, err := p.parens()
+		if err != nil {
+			return "", err
+		}
+		if args != "()" {
+			expr += args
+		}
+	}
+	return expr, nil
+}
+
+func removeWord(str, word string) string {
+	var words []string
+	for _, w := range strings.Fields(str) {
+		if w != word {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+/*
+Table edits!
+*/
+
+// column returns the position of the column named name, or -1.
+func (t *ddlTable) column(name string) int {
+	for i, col := range t.columns {
+		if strings.EqualFold(col.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// addKey adds an index to the table, naming it after its first column
+// if it has no name.
+func (t *ddlTable) addKey(key ddlKey) error {
+	if key.primary {
+		for _, k := range t.keys {
+			if k.primary {
+				return fmt.Errorf("multiple primary keys")
+			}
+		}
+	}
+	if key.name == "" {
+		key.name = key.parts[0].column
+		for n := 2; t.hasKey(key.name); n++ {
+			key.name = fmt.Sprintf("%s_%d", key.parts[0].column, n)
+		}
+	}
+	if t.hasKey(key.name) {
+		return fmt.Errorf("index %q already exists", key.name)
+	}
+	t.keys = append(t.keys, key)
+	return nil
+}
+
+func (t *ddlTable) hasKey(name string) bool {
+	for _, k := range t.keys {
+		if strings.EqualFold(k.name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *ddlTable) dropKey(name string) error {
+	for i, k := range t.keys {
+		if strings.EqualFold(k.name, name) {
+			t.keys = append(t.keys[:i], t.keys[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("index %q doesn't exist", name)
+}
+
+func (t *ddlTable) dropPrimaryKey() error {
+	for i, k := range t.keys {
+		if k.primary {
+			t.keys = append(t.keys[:i], t.keys[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("table %q has no primary key", t.name)
+}
+
+// renameColumn updates the indices and foreign keys of a column that
+// was renamed.
+func (t *ddlTable) renameColumn(old, name string) {
+	for _, k := range t.keys {
+		for j := range k.parts {
+			if k.parts[j].column == old {
+				k.parts[j].column = name
+			}
+		}
+	}
+	for _, fk := range t.fks {
+		for j := range fk.Columns {
+			if fk.Columns[j] == old {
+				fk.Columns[j] = name
+			}
+		}
+	}
+}
+
+// check verifies that the indices and foreign keys of the table are on
+// its columns.
+func (t *ddlTable) check() error {
+	for _, k := range t.keys {
+		for _, part := range k.parts {
+			if t.column(part.column) < 0 {
+				return fmt.Errorf("column %q doesn't exist for index %q", part.column, k.name)
+			}
+		}
+	}
+	for _, fk := range t.fks {
+		for _, col := range fk.Columns {
+			if t.column(col) < 0 {
+				return fmt.Errorf("column %q doesn't exist for foreign key %q", col, fk.Name)
+			}
+		}
+	}
+	return nil
+}
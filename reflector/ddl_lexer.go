@@ -0,0 +1,175 @@
+package reflector
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ddlTokenKind tells apart the tokens of DDL statements.
+type ddlTokenKind int
+
+const (
+	ddlWord   ddlTokenKind = iota // A keyword or an unquoted identifier.
+	ddlIdent                      // A `quoted` identifier.
+	ddlString                     // A 'string' literal, or a b'bit' or x'hex' one.
+	ddlNumber
+	ddlPunct
+)
+
+type ddlToken struct {
+	kind ddlTokenKind
+	text string // Unquoted, for identifiers and strings.
+	pos  int    // Offsets of the token in the source.
+	end  int
+}
+
+// is tells if the token is one of the keywords.
+func (tok ddlToken) is(keywords ...string) bool {
+	if tok.kind != ddlWord {
+		return false
+	}
+	for _, kw := range keywords {
+		if strings.EqualFold(tok.text, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+func (tok ddlToken) isPunct(p string) bool {
+	return tok.kind == ddlPunct && tok.text == p
+}
+
+// lexDDL splits SQL source into tokens, dropping comments.
+func lexDDL(src string) ([]ddlToken, error) {
+	var toks []ddlToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '#' || strings.HasPrefix(src[i:], "-- ") || strings.HasPrefix(src[i:], "--\n"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			i += 2 + end + 2
+
+		case c == '`':
+			text, end, err := lexQuoted(src, i, '`')
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, ddlToken{kind: ddlIdent, text: text, pos: i, end: end})
+			i = end
+
+		case c == '\'' || c == '"':
+			text, end, err := lexQuoted(src, i, c)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, ddlToken{kind: ddlString, text: text, pos: i, end: end})
+			i = end
+
+		case (c == 'b' || c == 'B' || c == 'x' || c == 'X') && i+1 < len(src) && src[i+1] == '\'':
+			// kept as written, like MySQL reports them
+			_, end, err := lexQuoted(src, i+1, '\'')
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, ddlToken{kind: ddlString, text: src[i:end], pos: i, end: end})
+			i = end
+
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			end := i
+			for end < len(src) && (src[end] >= '0' && src[end] <= '9' || src[end] == '.' ||
+				src[end] == 'e' || src[end] == 'E' ||
+				(src[end] == '-' || src[end] == '+') && (src[end-1] == 'e' || src[end-1] == 'E')) {
+				end++
+			}
+			toks = append(toks, ddlToken{kind: ddlNumber, text: src[i:end], pos: i, end: end})
+			i = end
+
+		case c == '_' || c == '$' || c == '@' || unicode.IsLetter(rune(c)) || c >= 0x80:
+			end := i
+			for end < len(src) && (src[end] == '_' || src[end] == '$' || src[end] == '@' ||
+				src[end] >= '0' && src[end] <= '9' || unicode.IsLetter(rune(src[end])) || src[end] >= 0x80) {
+				end++
+			}
+			toks = append(toks, ddlToken{kind: ddlWord, text: src[i:end], pos: i, end: end})
+			i = end
+
+		default:
+			toks = append(toks, ddlToken{kind: ddlPunct, text: string(c), pos: i, end: i + 1})
+			i++
+		}
+	}
+	return toks, nil
+}
+
+// lexQuoted reads the quoted text starting at src[start], where quote
+// is. Quotes are escaped by doubling them and, in strings, with
+// backslashes.
+func lexQuoted(src string, start int, quote byte) (string, int, error) {
+	var text []byte
+	for i := start + 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == quote && i+1 < len(src) && src[i+1] == quote:
+			text = append(text, c)
+			i++
+		case c == quote:
+			return string(text), i + 1, nil
+		case c == '\\' && quote != '`' && i+1 < len(src):
+			i++
+			switch src[i] {
+			case 'n':
+				text = append(text, '\n')
+			case 't':
+				text = append(text, '\t')
+			case '0':
+				text = append(text, 0)
+			default:
+				text = append(text, src[i])
+			}
+		default:
+			text = append(text, c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated %c at offset %d", quote, start)
+}
+
+// replaceDelimiters applies the DELIMITER commands of the mysql client,
+// which change what ends statements, so that they all end with `;`.
+// Lengths and lines are kept, for the offsets in errors.
+func replaceDelimiters(src string) string {
+	var (
+		lines = strings.SplitAfter(src, "\n")
+		delim = ";"
+	)
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && strings.EqualFold(fields[0], "delimiter") {
+			delim = fields[1]
+			lines[i] = blank(line)
+			continue
+		}
+		if delim != ";" {
+			lines[i] = strings.Replace(line, delim, ";"+strings.Repeat(" ", len(delim)-1), -1)
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// blank replaces all but the line break of line with spaces.
+func blank(line string) string {
+	trimmed := strings.TrimRight(line, "\r\n")
+	return strings.Repeat(" ", len(trimmed)) + line[len(trimmed):]
+}
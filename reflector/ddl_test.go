@@ -0,0 +1,151 @@
+package reflector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDDL(t *testing.T) {
+	migrations := []string{`
+-- users and their orders
+CREATE TABLE IF NOT EXISTS users (
+  id bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+  email varchar(255) NOT NULL COMMENT 'Where we write.',
+  active tinyint(1) NOT NULL DEFAULT '1',
+  role enum('admin','user') DEFAULT 'user',
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NULL DEFAULT NULL ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY email_idx (email)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='People.';
+
+CREATE TABLE ` + "`orders`" + ` (
+  ` + "`id`" + ` int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  user_id bigint unsigned NOT NULL,
+  total decimal(10,2) NOT NULL DEFAULT 0.00,
+  CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  CHECK (total >= 0)
+);`, `
+ALTER TABLE users ADD COLUMN name varchar(64) AFTER id, DROP COLUMN role;
+CREATE INDEX name_idx ON users (name(10));
+ALTER TABLE orders CHANGE total amount decimal(12,2) NOT NULL;
+
+DELIMITER $$
+CREATE TRIGGER touch BEFORE UPDATE ON orders FOR EACH ROW
+BEGIN
+  IF NEW.amount < 0 THEN
+    DROP TABLE users;
+  END IF;
+END$$
+DELIMITER ;
+
+CREATE TABLE tmp (id int);
+DROP TABLE tmp;
+`}
+
+	ddl := newDDLSchema()
+	for i, src := range migrations {
+		if err := ddl.parse("migration.sql", src); err != nil {
+			t.Fatalf("migration %d: %v", i, err)
+		}
+	}
+	schema := &DBSchema{}
+	if err := ddl.build(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, tbl := range schema.Tables {
+		names = append(names, tbl.Name)
+	}
+	if want := []string{"orders", "users"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("tables=%v, want %v", names, want)
+	}
+	orders, users := schema.Tables[0], schema.Tables[1]
+
+	var cols []string
+	for _, col := range users.Columns {
+		cols = append(cols, col.Name)
+	}
+	// as declared, not sorted
+	if want := []string{"id", "name", "email", "active", "created_at", "updated_at"}; !reflect.DeepEqual(cols, want) {
+		t.Errorf("users columns=%v, want %v", cols, want)
+	}
+
+	if users.Comment != "People." {
+		t.Errorf("users comment=%q", users.Comment)
+	}
+	if users.Pk == nil || users.Pk.Columns[0].Name != "id" {
+		t.Fatalf("users pk=%v", users.Pk)
+	}
+	id := users.Has("id")
+	if id.Type != SQLInteger || !id.Unsigned || id.Size != 8 || id.Nullable ||
+		string(id.Key.([]byte)) != "PRI" || string(id.Extra.([]byte)) != "auto_increment" {
+		t.Errorf("users.id=%v", id)
+	}
+	if users.Has("role") != nil {
+		t.Errorf("users.role wasn't dropped")
+	}
+	if col := users.Has("email"); col.Comment != "Where we write." || string(col.Key.([]byte)) != "UNI" || col.Length != 255 {
+		t.Errorf("users.email=%v", col)
+	}
+	if col := users.Has("active"); col.Default != int64(1) {
+		t.Errorf("users.active default=%#v", col.Default)
+	}
+	if col := users.Has("created_at"); col.Default != "CURRENT_TIMESTAMP" || string(col.Extra.([]byte)) != "DEFAULT_GENERATED" {
+		t.Errorf("users.created_at=%v", col)
+	}
+	if col := users.Has("updated_at"); !col.Nullable || col.Default != nil || string(col.Extra.([]byte)) != "on update CURRENT_TIMESTAMP" {
+		t.Errorf("users.updated_at=%v", col)
+	}
+
+	var idx []string
+	for _, i := range users.Indices {
+		idx = append(idx, i.KeyName)
+	}
+	if want := []string{"email_idx", "name_idx"}; !reflect.DeepEqual(idx, want) {
+		t.Errorf("users indices=%v, want %v", idx, want)
+	}
+	if part := users.Indices[1].Parts[0]; part.SubPart == nil || *part.SubPart != 10 || !users.Indices[1].NonUnique {
+		t.Errorf("name_idx=%#v", part)
+	}
+
+	wantFK := []ForeignKey{{
+		Name: "fk_user", Columns: []string{"user_id"},
+		RefTable: "users", RefColumns: []string{"id"},
+		OnDelete: "CASCADE", OnUpdate: "NO ACTION",
+	}}
+	if !reflect.DeepEqual(orders.ForeignKeys, wantFK) {
+		t.Errorf("orders foreign keys=%v, want %v", orders.ForeignKeys, wantFK)
+	}
+	if len(orders.Indices) != 1 || orders.Indices[0].KeyName != "fk_user" {
+		t.Errorf("orders indices=%v, want the index of fk_user", orders.Indices)
+	}
+	if col := orders.Has("amount"); col == nil || col.Type != SQLDecimal || col.Precision != 12 || col.Default != nil {
+		t.Errorf("orders.amount=%v", col)
+	}
+	wantCheck := Constraint{Name: "orders_chk_1", Type: ConstraintCheck, Clause: "(total >= 0)"}
+	if len(orders.Constraints) != 1 || !reflect.DeepEqual(orders.Constraints[0], wantCheck) {
+		t.Errorf("orders constraints=%v, want %v", orders.Constraints, wantCheck)
+	}
+}
+
+func TestParseDDLErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"CREATE TABLE t (id int,\n  PRIMARY KEY (id), PRIMARY KEY (id));", "x.sql:1: multiple primary keys"},
+		{"CREATE TABLE t (id int, KEY (nope));", `column "nope" doesn't exist for index "nope"`},
+		{"\nALTER TABLE nope ADD x int;", `x.sql:2: table "nope" doesn't exist`},
+		{"CREATE TABLE t (id int frob);", `unexpected "frob" in definition of column "id"`},
+		{"CREATE TABLE t (id widget);", `unknown type "widget"`},
+	}
+	for _, tt := range tests {
+		err := newDDLSchema().parse("x.sql", tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parse(%q)=%v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,743 @@
+package reflector
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+/*
+Diffs!
+*/
+
+type ChangeType int
+
+const (
+	startChange ChangeType = iota
+
+	ChangeAdded
+	ChangeRemoved
+	ChangeModified
+
+	stopChange
+)
+
+// SchemaDiff lists what changes from one schema to another. Renamed
+// tables, columns and indices are seen as removed, then added.
+type SchemaDiff struct {
+	Tables []TableDiff
+}
+
+type TableDiff struct {
+	Name   string
+	Change ChangeType
+	From   *Table // nil if the table is added.
+	To     *Table // nil if the table is removed.
+
+	// changes of a modified table
+	Columns     []ColumnDiff
+	Indices     []IndexDiff // The primary key is the index named PRIMARY.
+	ForeignKeys []ForeignKeyDiff
+}
+
+type ColumnDiff struct {
+	Name   string
+	Change ChangeType
+	From   *Column
+	To     *Column
+}
+
+type IndexDiff struct {
+	Name   string
+	Change ChangeType
+	From   *Index
+	To     *Index
+}
+
+type ForeignKeyDiff struct {
+	Name   string
+	Change ChangeType
+	From   *ForeignKey
+	To     *ForeignKey
+}
+
+// Diff compares the tables of two schemas, listing what must change
+// for `from` to become `to`.
+func Diff(from, to *DBSchema) *SchemaDiff {
+	diff := &SchemaDiff{}
+
+	for _, name := range unionNames(tableNames(from.Tables), tableNames(to.Tables)) {
+		f, t := findTable(from.Tables, name), findTable(to.Tables, name)
+		switch {
+		case f == nil:
+			diff.Tables = append(diff.Tables, TableDiff{Name: name, Change: ChangeAdded, To: t})
+		case t == nil:
+			diff.Tables = append(diff.Tables, TableDiff{Name: name, Change: ChangeRemoved, From: f})
+		default:
+			if td := diffTable(f, t); td.Change != 0 {
+				diff.Tables = append(diff.Tables, td)
+			}
+		}
+	}
+	return diff
+}
+
+// Empty tells if the schemas have the same tables.
+func (d *SchemaDiff) Empty() bool { return len(d.Tables) == 0 }
+
+func diffTable(from, to *Table) TableDiff {
+	td := TableDiff{Name: to.Name, From: from, To: to}
+
+	var fromCols, toCols []string
+	for _, col := range from.Columns {
+		fromCols = append(fromCols, col.Name)
+	}
+	for _, col := range to.Columns {
+		toCols = append(toCols, col.Name)
+	}
+	for _, name := range unionNames(fromCols, toCols) {
+		f, t := from.Has(name), to.Has(name)
+		if change := compare(f != nil, t != nil, f != nil && t != nil && !sameColumn(*f, *t)); change != 0 {
+			td.Columns = append(td.Columns, ColumnDiff{Name: name, Change: change, From: f, To: t})
+		}
+	}
+
+	fromIdx, toIdx := tableIndices(from), tableIndices(to)
+	for _, name := range unionNames(indexNames(fromIdx), indexNames(toIdx)) {
+		f, t := findIndex(fromIdx, name), findIndex(toIdx, name)
+		if change := compare(f != nil, t != nil, f != nil && t != nil && !sameIndex(*f, *t)); change != 0 {
+			td.Indices = append(td.Indices, IndexDiff{Name: name, Change: change, From: f, To: t})
+		}
+	}
+
+	for _, name := range unionNames(foreignKeyNames(from.ForeignKeys), foreignKeyNames(to.ForeignKeys)) {
+		f, t := findForeignKey(from.ForeignKeys, name), findForeignKey(to.ForeignKeys, name)
+		if change := compare(f != nil, t != nil, f != nil && t != nil && !sameForeignKey(*f, *t)); change != 0 {
+			td.ForeignKeys = append(td.ForeignKeys, ForeignKeyDiff{Name: name, Change: change, From: f, To: t})
+		}
+	}
+
+	if len(td.Columns) != 0 || len(td.Indices) != 0 || len(td.ForeignKeys) != 0 || from.Comment != to.Comment {
+		td.Change = ChangeModified
+	}
+	return td
+}
+
+// compare returns the change of something found in either schema, or
+// 0 if it hasn't changed.
+func compare(inFrom, inTo, modified bool) ChangeType {
+	switch {
+	case !inFrom:
+		return ChangeAdded
+	case !inTo:
+		return ChangeRemoved
+	case modified:
+		return ChangeModified
+	}
+	return 0
+}
+
+// sameColumn compares what's declared of columns, not the keys they're
+// part of, which are compared as indices.
+func sameColumn(a, b Column) bool {
+	return a.Type == b.Type &&
+		a.Nullable == b.Nullable &&
+		sameLength(a, b) &&
+		a.Size == b.Size &&
+		a.Precision == b.Precision &&
+		a.Scale == b.Scale &&
+		a.Unsigned == b.Unsigned &&
+		a.Comment == b.Comment &&
+		reflect.DeepEqual(a.Values, b.Values) &&
+		reflect.DeepEqual(a.defaultText(), b.defaultText()) &&
+		reflect.DeepEqual(extraWords(a), extraWords(b))
+}
+
+// sameLength ignores the display width of integers, which MySQL 8.0.19
+// stopped reporting, but for tinyint(1), which is used as a bool.
+func sameLength(a, b Column) bool {
+	if a.Type == SQLInteger && b.Type == SQLInteger {
+		return isBoolWidth(a) == isBoolWidth(b)
+	}
+	return a.Length == b.Length
+}
+
+func isBoolWidth(col Column) bool {
+	return col.Size == 1 && col.Length == 1
+}
+
+// extraWords are the words of the Extra of col, in any order and case,
+// as MySQL and DDL order them differently.
+func extraWords(col Column) []string {
+	words := strings.Fields(strings.ToUpper(bytesText(col.Extra)))
+	sort.Strings(words)
+	return words
+}
+
+// sameIndex ignores the statistics of indices.
+func sameIndex(a, b Index) bool {
+	if a.NonUnique != b.NonUnique || a.IndexType != b.IndexType ||
+		a.IndexComment != b.IndexComment || len(a.Parts) != len(b.Parts) {
+		return false
+	}
+	for i := range a.Parts {
+		if a.Parts[i].ColumnName != b.Parts[i].ColumnName ||
+			!reflect.DeepEqual(a.Parts[i].SubPart, b.Parts[i].SubPart) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameForeignKey(a, b ForeignKey) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// tableIndices returns the indices of tbl, with its primary key.
+func tableIndices(tbl *Table) []Index {
+	indices := tbl.Indices
+	if tbl.Pk != nil {
+		indices = append([]Index{*tbl.Pk}, indices...)
+	}
+	return indices
+}
+
+// unionNames returns the names found in either list, sorted.
+func unionNames(a, b []string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range append(append([]string(nil), a...), b...) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func tableNames(tables []Table) (names []string) {
+	for _, tbl := range tables {
+		names = append(names, tbl.Name)
+	}
+	return
+}
+
+func indexNames(indices []Index) (names []string) {
+	for _, idx := range indices {
+		names = append(names, idx.KeyName)
+	}
+	return
+}
+
+func foreignKeyNames(fks []ForeignKey) (names []string) {
+	for _, fk := range fks {
+		names = append(names, fk.Name)
+	}
+	return
+}
+
+func findTable(tables []Table, name string) *Table {
+	for i := range tables {
+		if tables[i].Name == name {
+			return &tables[i]
+		}
+	}
+	return nil
+}
+
+func findIndex(indices []Index, name string) *Index {
+	for i := range indices {
+		if indices[i].KeyName == name {
+			return &indices[i]
+		}
+	}
+	return nil
+}
+
+func findForeignKey(fks []ForeignKey, name string) *ForeignKey {
+	for i := range fks {
+		if fks[i].Name == name {
+			return &fks[i]
+		}
+	}
+	return nil
+}
+
+func (d SchemaDiff) String() string {
+	buf := bytes.NewBuffer(nil)
+	for _, td := range d.Tables {
+		fmt.Fprintf(buf, "%s table %q\n", changeSign(td.Change), td.Name)
+		if td.Change != ChangeModified {
+			continue
+		}
+		if td.From.Comment != td.To.Comment {
+			fmt.Fprintf(buf, "\t~ comment %q -> %q\n", td.From.Comment, td.To.Comment)
+		}
+		for _, cd := range td.Columns {
+			var from, to string
+			if cd.From != nil {
+				from = columnSQL(*cd.From)
+			}
+			if cd.To != nil {
+				to = columnSQL(*cd.To)
+			}
+			fmt.Fprintf(buf, "\t%s column %s\n", changeSign(cd.Change), diffText(from, to))
+		}
+		for _, id := range td.Indices {
+			var from, to string
+			if id.From != nil {
+				from = indexSQL(*id.From)
+			}
+			if id.To != nil {
+				to = indexSQL(*id.To)
+			}
+			fmt.Fprintf(buf, "\t%s index %s\n", changeSign(id.Change), diffText(from, to))
+		}
+		for _, fd := range td.ForeignKeys {
+			var from, to string
+			if fd.From != nil {
+				from = fd.From.String()
+			}
+			if fd.To != nil {
+				to = fd.To.String()
+			}
+			fmt.Fprintf(buf, "\t%s foreign key %s\n", changeSign(fd.Change), diffText(from, to))
+		}
+	}
+	return buf.String()
+}
+
+func changeSign(c ChangeType) string {
+	switch c {
+	case ChangeAdded:
+		return "+"
+	case ChangeRemoved:
+		return "-"
+	}
+	return "~"
+}
+
+// diffText shows what was, what is, or how it changed.
+func diffText(from, to string) string {
+	from = strings.Replace(from, "\t", "", -1)
+	to = strings.Replace(to, "\t", "", -1)
+	switch {
+	case from == "":
+		return to
+	case to == "":
+		return from
+	}
+	return from + " -> " + to
+}
+
+/*
+Migrations!
+*/
+
+// SQL returns the MySQL statements that turn the `from` schema into the
+// `to` one. Foreign keys are dropped first and added last, so that
+// tables can be created and altered in any order. Generated columns are
+// left out, as told by Warnings.
+func (d *SchemaDiff) SQL() []string {
+	stmts, _ := d.migration()
+	return stmts
+}
+
+// Warnings lists what SQL leaves out: the generated columns, whose
+// expression isn't known, and the indices covering them. They must be
+// migrated by hand.
+func (d *SchemaDiff) Warnings() []Warning {
+	_, warnings := d.migration()
+	return warnings
+}
+
+func (d *SchemaDiff) migration() ([]string, []Warning) {
+	var (
+		dropFKs, drops, creates, alters, addFKs []string
+		warnings                                []Warning
+	)
+	for _, td := range d.Tables {
+		name := mysqlQuote(td.Name)
+		switch td.Change {
+		case ChangeAdded:
+			left := generatedColumns(td.To.Columns)
+			warnings = append(warnings, leftOut(td.Name, left, tableIndices(td.To))...)
+			creates = append(creates, createTableSQL(td.To, left))
+			if specs := addForeignKeySpecs(td.To.ForeignKeys); len(specs) != 0 {
+				addFKs = append(addFKs, alterSQL(name, specs))
+			}
+
+		case ChangeRemoved:
+			drops = append(drops, "DROP TABLE "+name)
+
+		case ChangeModified:
+			var dropSpecs, specs, addSpecs []string
+			for _, fd := range td.ForeignKeys {
+				if fd.From != nil {
+					dropSpecs = append(dropSpecs, "DROP FOREIGN KEY "+mysqlQuote(fd.Name))
+				}
+				if fd.To != nil {
+					addSpecs = append(addSpecs, addForeignKeySpecs([]ForeignKey{*fd.To})...)
+				}
+			}
+			for _, id := range td.Indices {
+				switch {
+				case id.From == nil:
+				case id.From.IsPrimary():
+					specs = append(specs, "DROP PRIMARY KEY")
+				default:
+					specs = append(specs, "DROP INDEX "+mysqlQuote(id.Name))
+				}
+			}
+			var left []Column
+			for _, cd := range td.Columns {
+				if cd.To != nil && cd.To.ParseExtra().Generated != 0 {
+					left = append(left, *cd.To)
+					continue
+				}
+				switch cd.Change {
+				case ChangeAdded:
+					specs = append(specs, "ADD COLUMN "+columnSQL(*cd.To))
+				case ChangeRemoved:
+					specs = append(specs, "DROP COLUMN "+mysqlQuote(cd.Name))
+				case ChangeModified:
+					specs = append(specs, "MODIFY COLUMN "+columnSQL(*cd.To))
+				}
+			}
+			var added []Index
+			for _, id := range td.Indices {
+				if id.To != nil {
+					added = append(added, *id.To)
+				}
+			}
+			warnings = append(warnings, leftOut(td.Name, left, added)...)
+			for _, idx := range added {
+				if !coversAny(idx, left) {
+					specs = append(specs, "ADD "+indexSQL(idx))
+				}
+			}
+			if td.From.Comment != td.To.Comment {
+				specs = append(specs, "COMMENT = "+mysqlString(td.To.Comment))
+			}
+
+			if len(dropSpecs) != 0 {
+				dropFKs = append(dropFKs, alterSQL(name, dropSpecs))
+			}
+			if len(specs) != 0 {
+				alters = append(alters, alterSQL(name, specs))
+			}
+			if len(addSpecs) != 0 {
+				addFKs = append(addFKs, alterSQL(name, addSpecs))
+			}
+		}
+	}
+
+	var stmts []string
+	for _, list := range [][]string{dropFKs, drops, creates, alters, addFKs} {
+		stmts = append(stmts, list...)
+	}
+	return stmts, warnings
+}
+
+// WriteSQL writes the statements of SQL, each ending with `;`, after the
+// warnings as comments.
+func (d *SchemaDiff) WriteSQL(w io.Writer) error {
+	stmts, warnings := d.migration()
+	for _, warning := range warnings {
+		if _, err := fmt.Fprintf(w, "-- warning: %s\n", warning); err != nil {
+			return err
+		}
+	}
+	if len(warnings) != 0 {
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	for _, stmt := range stmts {
+		if _, err := fmt.Fprintf(w, "%s;\n\n", stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func alterSQL(name string, specs []string) string {
+	return "ALTER TABLE " + name + "\n  " + strings.Join(specs, ",\n  ")
+}
+
+// createTableSQL declares tbl, without its foreign keys, nor the `left`
+// columns and the indices covering them.
+func createTableSQL(tbl *Table, left []Column) string {
+	var defs []string
+	for _, col := range tbl.Columns {
+		if !hasColumn(left, col.Name) {
+			defs = append(defs, columnSQL(col))
+		}
+	}
+	for _, idx := range tableIndices(tbl) {
+		if !coversAny(idx, left) {
+			defs = append(defs, indexSQL(idx))
+		}
+	}
+	for _, c := range tbl.Constraints {
+		if c.Type == ConstraintCheck {
+			defs = append(defs, "CONSTRAINT "+mysqlQuote(c.Name)+" CHECK "+parenthesize(c.Clause))
+		}
+	}
+	stmt := "CREATE TABLE " + mysqlQuote(tbl.Name) + " (\n  " + strings.Join(defs, ",\n  ") + "\n)"
+	if tbl.Comment != "" {
+		stmt += " COMMENT = " + mysqlString(tbl.Comment)
+	}
+	return stmt
+}
+
+// generatedColumns are the columns computed by the database. Their
+// expression isn't known, so they can't be declared.
+func generatedColumns(cols []Column) []Column {
+	var generated []Column
+	for _, col := range cols {
+		if col.ParseExtra().Generated != 0 {
+			generated = append(generated, col)
+		}
+	}
+	return generated
+}
+
+// leftOut warns about the columns of a table left out of its migration,
+// and about the indices covering them.
+func leftOut(table string, cols []Column, indices []Index) []Warning {
+	var warnings []Warning
+	for _, col := range cols {
+		warnings = append(warnings, Warning{
+			Table:   table,
+			Column:  col.Name,
+			Message: "generated column left out, its expression is unknown",
+		})
+	}
+	for _, idx := range indices {
+		if coversAny(idx, cols) {
+			warnings = append(warnings, Warning{
+				Table:   table,
+				Message: fmt.Sprintf("index %s left out, it covers generated columns", idx.KeyName),
+			})
+		}
+	}
+	return warnings
+}
+
+func hasColumn(cols []Column, name string) bool {
+	for _, col := range cols {
+		if col.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// coversAny tells if idx covers one of cols.
+func coversAny(idx Index, cols []Column) bool {
+	for _, part := range idx.Parts {
+		if hasColumn(cols, part.ColumnName) {
+			return true
+		}
+	}
+	return false
+}
+
+func addForeignKeySpecs(fks []ForeignKey) []string {
+	var specs []string
+	for _, fk := range fks {
+		specs = append(specs, fmt.Sprintf("ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s) ON DELETE %s ON UPDATE %s",
+			mysqlQuote(fk.Name),
+			mysqlQuoteAll(fk.Columns),
+			mysqlQuote(fk.RefTable),
+			mysqlQuoteAll(fk.RefColumns),
+			orDefault(fk.OnDelete, "NO ACTION"),
+			orDefault(fk.OnUpdate, "NO ACTION"),
+		))
+	}
+	return specs
+}
+
+// columnSQL declares col, as it would be in CREATE TABLE.
+func columnSQL(col Column) string {
+	def := mysqlQuote(col.Name) + " " + columnTypeSQL(col)
+	if col.Nullable {
+		def += " NULL"
+	} else {
+		def += " NOT NULL"
+	}
+
+	extra := strings.Fields(bytesText(col.Extra))
+	if col.Default != nil {
+		def += " DEFAULT " + defaultSQL(col, hasWord(extra, "DEFAULT_GENERATED"))
+	}
+	for i := 0; i < len(extra); i++ {
+		switch strings.ToLower(extra[i]) {
+		case "auto_increment":
+			def += " AUTO_INCREMENT"
+		case "on":
+			// on update CURRENT_TIMESTAMP
+			if i+2 < len(extra) && strings.EqualFold(extra[i+1], "update") {
+				def += " ON UPDATE " + extra[i+2]
+				i += 2
+			}
+		}
+	}
+	if col.Comment != "" {
+		def += " COMMENT " + mysqlString(col.Comment)
+	}
+	return def
+}
+
+// columnTypeSQL is the type of col as declared, or as close as can be
+// told from what's known of it.
+func columnTypeSQL(col Column) string {
+	if col.TypeName != "" {
+		return col.TypeName
+	}
+	var name string
+	switch col.Type {
+	case SQLInteger:
+		name = map[int]string{1: "tinyint", 2: "smallint", 3: "mediumint", 8: "bigint"}[col.Size]
+		if name == "" {
+			name = "int"
+		}
+		if col.Length != 0 {
+			name += fmt.Sprintf("(%d)", col.Length)
+		}
+	case SQLString:
+		name = "text"
+		if col.Length != 0 {
+			name = fmt.Sprintf("varchar(%d)", col.Length)
+		}
+	case SQLBytes:
+		name = "blob"
+		if col.Length != 0 {
+			name = fmt.Sprintf("varbinary(%d)", col.Length)
+		}
+	case SQLFloat:
+		name = "double"
+	case SQLDecimal:
+		name = fmt.Sprintf("decimal(%d,%d)", col.Precision, col.Scale)
+		if col.Precision == 0 {
+			name = "decimal"
+		}
+	case SQLBool:
+		name = "tinyint(1)"
+	case SQLTime:
+		name = "datetime"
+	case SQLEnum, SQLSet:
+		var values []string
+		for _, v := range col.Values {
+			values = append(values, mysqlString(v))
+		}
+		name = fmt.Sprintf("%s(%s)", strings.ToLower(strings.TrimPrefix(col.Type.String(), "SQL")), strings.Join(values, ","))
+	case SQLJSON:
+		name = "json"
+	case SQLPoint:
+		name = "point"
+	case SQLGeometry:
+		name = "geometry"
+	case SQLBit:
+		name = fmt.Sprintf("bit(%d)", col.Length)
+	}
+	if col.Unsigned {
+		name += " unsigned"
+	}
+	return name
+}
+
+// defaultSQL writes the default of col as a literal, or as is if it's
+// an expression.
+func defaultSQL(col Column, expr bool) string {
+	text := *col.defaultText()
+	switch {
+	case (expr || col.Type == SQLTime) && strings.HasPrefix(strings.ToUpper(text), "CURRENT_TIMESTAMP"):
+		// without DEFAULT_GENERATED before MySQL 8
+		return text
+	case expr:
+		return "(" + text + ")"
+	case col.Type == SQLBit:
+		return "b'" + text + "'"
+	}
+	return mysqlString(text)
+}
+
+// indexSQL declares idx, as it would be in CREATE TABLE.
+func indexSQL(idx Index) string {
+	var cols []string
+	for _, part := range idx.Parts {
+		col := mysqlQuote(part.ColumnName)
+		if part.SubPart != nil {
+			col += fmt.Sprintf("(%d)", *part.SubPart)
+		}
+		cols = append(cols, col)
+	}
+
+	var def string
+	switch {
+	case idx.IsPrimary():
+		def = "PRIMARY KEY"
+	case idx.IndexType == IndexFulltext:
+		def = "FULLTEXT KEY " + mysqlQuote(idx.KeyName)
+	case idx.IndexType == IndexRtree:
+		def = "SPATIAL KEY " + mysqlQuote(idx.KeyName)
+	case !idx.NonUnique:
+		def = "UNIQUE KEY " + mysqlQuote(idx.KeyName)
+	default:
+		def = "KEY " + mysqlQuote(idx.KeyName)
+	}
+	def += " (" + strings.Join(cols, ", ") + ")"
+	if idx.IndexType == IndexHash {
+		def += " USING HASH"
+	}
+	if idx.IndexComment != "" {
+		def += " COMMENT " + mysqlString(idx.IndexComment)
+	}
+	return def
+}
+
+func mysqlQuote(name string) string {
+	return DialectMySQL.Quote(name)
+}
+
+func mysqlQuoteAll(names []string) string {
+	var quoted []string
+	for _, name := range names {
+		quoted = append(quoted, mysqlQuote(name))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func mysqlString(str string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", "''", "\n", `\n`).Replace(str) + "'"
+}
+
+// parenthesize wraps clause in parens, unless it already is.
+func parenthesize(clause string) string {
+	if trimParens(clause) != strings.TrimSpace(clause) {
+		return clause
+	}
+	return "(" + clause + ")"
+}
+
+func hasWord(words []string, word string) bool {
+	for _, w := range words {
+		if strings.EqualFold(w, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func orDefault(str, def string) string {
+	if str == "" {
+		return def
+	}
+	return str
+}
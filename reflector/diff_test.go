@@ -0,0 +1,252 @@
+package reflector
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	from := `
+CREATE TABLE users (
+  id int unsigned NOT NULL AUTO_INCREMENT,
+  email varchar(100) NOT NULL,
+  nick varchar(20),
+  PRIMARY KEY (id),
+  KEY email_idx (email)
+);
+CREATE TABLE posts (
+  id int unsigned NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  user_id int unsigned NOT NULL,
+  CONSTRAINT posts_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE TABLE legacy (id int);`
+
+	to := `
+CREATE TABLE users (
+  id int unsigned NOT NULL AUTO_INCREMENT,
+  email varchar(255) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY email_idx (email)
+) COMMENT 'People.';
+CREATE TABLE posts (
+  id int unsigned NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  user_id int unsigned NOT NULL,
+  topic_id int unsigned,
+  CONSTRAINT posts_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  CONSTRAINT posts_topic FOREIGN KEY (topic_id) REFERENCES topics (id)
+);
+CREATE TABLE topics (
+  id int unsigned NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  name varchar(50) NOT NULL DEFAULT 'misc' COMMENT 'Shown to users.'
+);`
+
+	fromSchema := parseTestDDL(t, from)
+	toSchema := parseTestDDL(t, to)
+
+	diff := Diff(fromSchema, toSchema)
+	want := []struct {
+		name   string
+		change ChangeType
+	}{
+		{"legacy", ChangeRemoved},
+		{"posts", ChangeModified},
+		{"topics", ChangeAdded},
+		{"users", ChangeModified},
+	}
+	if len(diff.Tables) != len(want) {
+		t.Fatalf("diff=\n%s", diff)
+	}
+	for i, w := range want {
+		if got := diff.Tables[i]; got.Name != w.name || got.Change != w.change {
+			t.Errorf("table %d is %s %q, want %s %q", i, got.Change, got.Name, w.change, w.name)
+		}
+	}
+	users := diff.Tables[3]
+	if len(users.Columns) != 3 || len(users.Indices) != 1 || len(users.ForeignKeys) != 0 {
+		t.Errorf("users diff=\n%s", diff)
+	}
+
+	// applying the migration to `from` gives `to`
+	var sql []string
+	for _, stmt := range diff.SQL() {
+		sql = append(sql, stmt+";")
+	}
+	migrated := parseTestDDL(t, from+"\n"+strings.Join(sql, "\n"))
+	if again := Diff(migrated, toSchema); !again.Empty() {
+		t.Errorf("migration:\n%s\nleaves diff:\n%s", strings.Join(sql, "\n"), again)
+	}
+	if again := Diff(toSchema, toSchema); !again.Empty() {
+		t.Errorf("schema differs from itself:\n%s", again)
+	}
+}
+
+func parseTestDDL(t *testing.T, src string) *DBSchema {
+	ddl := newDDLSchema()
+	if err := ddl.parse("test.sql", src); err != nil {
+		t.Fatal(err)
+	}
+	schema := &DBSchema{}
+	if err := ddl.build(schema); err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestDiffLeavesOutGeneratedColumns(t *testing.T) {
+	from := `
+CREATE TABLE orders (
+  id int NOT NULL PRIMARY KEY,
+  price int NOT NULL,
+  qty int NOT NULL
+);`
+	to := `
+CREATE TABLE orders (
+  id int NOT NULL PRIMARY KEY,
+  price int NOT NULL,
+  qty int NOT NULL,
+  total int AS (price * qty) STORED,
+  note varchar(10),
+  KEY total_idx (total)
+);
+CREATE TABLE lines (
+  id int NOT NULL PRIMARY KEY,
+  half int GENERATED ALWAYS AS (id / 2) VIRTUAL,
+  KEY half_idx (half)
+);`
+
+	diff := Diff(parseTestDDL(t, from), parseTestDDL(t, to))
+	sql := strings.Join(diff.SQL(), ";\n")
+	for _, unwanted := range []string{"`total`", "`half`", "total_idx", "half_idx"} {
+		if strings.Contains(sql, unwanted) {
+			t.Errorf("migration\n%s\ndeclares %s", sql, unwanted)
+		}
+	}
+	for _, want := range []string{"CREATE TABLE `lines`", "ADD COLUMN `note`"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("migration\n%s\nlacks %s", sql, want)
+		}
+	}
+
+	want := []Warning{
+		{Table: "lines", Column: "half"},
+		{Table: "lines"},
+		{Table: "orders", Column: "total"},
+		{Table: "orders"},
+	}
+	warnings := diff.Warnings()
+	if len(warnings) != len(want) {
+		t.Fatalf("warned %v, want %d warnings", warnings, len(want))
+	}
+	for i, w := range warnings {
+		if w.Table != want[i].Table || w.Column != want[i].Column || w.Message == "" {
+			t.Errorf("warning %d is %v, want about %v", i, w, want[i])
+		}
+	}
+
+	var buf strings.Builder
+	if err := diff.WriteSQL(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "-- warning: lines.half: ") {
+		t.Errorf("wrote\n%s\nwithout the warnings first", buf.String())
+	}
+}
+
+func TestDiffTimeDefaults(t *testing.T) {
+	from := `CREATE TABLE slots (id int NOT NULL PRIMARY KEY);`
+	to := `
+CREATE TABLE slots (
+  id int NOT NULL PRIMARY KEY,
+  opens time NOT NULL DEFAULT '12:30:00',
+  since year NOT NULL DEFAULT '2020',
+  at datetime(3) NOT NULL DEFAULT '2020-01-02 03:04:05.678'
+);`
+	toSchema := parseTestDDL(t, to)
+	diff := Diff(parseTestDDL(t, from), toSchema)
+	sql := strings.Join(diff.SQL(), ";\n")
+	for _, want := range []string{
+		"`opens` time NOT NULL DEFAULT '12:30:00'",
+		"`since` year NOT NULL DEFAULT '2020'",
+		"`at` datetime(3) NOT NULL DEFAULT '2020-01-02 03:04:05.678'",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("migration\n%s\nlacks %s", sql, want)
+		}
+	}
+
+	drifted := parseTestDDL(t, strings.Replace(to, "05.678", "05.679", 1))
+	if diff := Diff(drifted, toSchema); len(diff.Tables) != 1 || len(diff.Tables[0].Columns) != 1 {
+		t.Errorf("fractional seconds drift gives diff:\n%s", diff)
+	}
+}
+
+func TestDiffIgnoresIntegerWidths(t *testing.T) {
+	// like MySQL 5.7, then 8.0.19
+	from := `
+CREATE TABLE users (
+  id int(11) NOT NULL PRIMARY KEY,
+  age tinyint(3) unsigned NOT NULL,
+  admin tinyint(1) NOT NULL,
+  email varchar(100) NOT NULL
+);`
+	to := `
+CREATE TABLE users (
+  id int NOT NULL PRIMARY KEY,
+  age tinyint unsigned NOT NULL,
+  admin tinyint NOT NULL,
+  email varchar(255) NOT NULL
+);`
+	diff := Diff(parseTestDDL(t, from), parseTestDDL(t, to))
+	if len(diff.Tables) != 1 {
+		t.Fatalf("diff=\n%s", diff)
+	}
+	var names []string
+	for _, col := range diff.Tables[0].Columns {
+		names = append(names, col.Name)
+	}
+	if want := "admin email"; strings.Join(names, " ") != want {
+		t.Errorf("modified %v, want %s", names, want)
+	}
+}
+
+func TestDiffDescribedDefaults(t *testing.T) {
+	ddl := parseTestDDL(t, `
+CREATE TABLE events (
+  id int NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id)
+);`)
+	servers := []struct {
+		name                       string
+		createdExtra, updatedExtra string
+	}{
+		{"MySQL 8", "DEFAULT_GENERATED", "DEFAULT_GENERATED on update CURRENT_TIMESTAMP"},
+		{"MySQL 5.7", "", "on update CURRENT_TIMESTAMP"},
+	}
+	for _, server := range servers {
+		schema := &fakeSchema{tables: []fakeTable{{
+			name: "events",
+			columns: [][]driver.Value{
+				{"id", "int", "NO", "PRI", nil, "", ""},
+				{"created_at", "datetime", "NO", "", "CURRENT_TIMESTAMP", server.createdExtra, ""},
+				{"updated_at", "timestamp", "NO", "", "CURRENT_TIMESTAMP", server.updatedExtra, ""},
+			},
+			indices: [][]driver.Value{
+				{"events", int64(0), "PRIMARY", int64(1), "id", "A", int64(0), nil, nil, "", "BTREE", "", ""},
+			},
+		}}}
+		db, _ := openFakeMySQL(t, schema)
+		described, err := DescribeMySQLContext(context.Background(), db, "fake", DescribeOptions{})
+		db.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := Diff(described, ddl); !diff.Empty() {
+			t.Errorf("described by %s, schema differs from its DDL:\n%s", server.name, diff)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package reflector
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Errors!
+*/
+
+// UnknownTypeError is a column or parameter type that the reflector
+// doesn't know.
+type UnknownTypeError struct {
+	TypeName string
+}
+
+func (e *UnknownTypeError) Error() string {
+	return fmt.Sprintf("unknown type %q", e.TypeName)
+}
+
+// ColumnError is a column that couldn't be understood.
+type ColumnError struct {
+	Table  string
+	Column string
+	Err    error
+}
+
+func (e *ColumnError) Error() string {
+	return fmt.Sprintf("column %q of %q, %v", e.Column, e.Table, e.Err)
+}
+
+func (e *ColumnError) Unwrap() error { return e.Err }
+
+// TableError is a table, or a view, that couldn't be described.
+type TableError struct {
+	Table string
+	Err   error
+}
+
+func (e *TableError) Error() string {
+	return fmt.Sprintf("loading table %q, %v", e.Table, e.Err)
+}
+
+func (e *TableError) Unwrap() error { return e.Err }
+
+// TableErrors are the errors of each table that couldn't be described,
+// in the order of the tables.
+type TableErrors []error
+
+func (errs TableErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (errs TableErrors) Unwrap() []error { return errs }
+
+/*
+Warnings!
+*/
+
+// Warning is a problem that a lenient description worked around, by
+// leaving out or degrading the table, column or routine it names.
+type Warning struct {
+	Table   string `json:"table,omitempty"`
+	Column  string `json:"column,omitempty"`
+	Routine string `json:"routine,omitempty"`
+	Message string `json:"message"`
+	Err     error  `json:"-"` // The error worked around, to inspect with errors.As.
+}
+
+func (w Warning) String() string {
+	switch {
+	case w.Column != "":
+		return fmt.Sprintf("%s.%s: %s", w.Table, w.Column, w.Message)
+	case w.Table != "":
+		return fmt.Sprintf("%s: %s", w.Table, w.Message)
+	case w.Routine != "":
+		return fmt.Sprintf("routine %s: %s", w.Routine, w.Message)
+	}
+	return w.Message
+}
+
+// lenience collects the warnings of a lenient description. A nil
+// *lenience is strict: problems stay errors.
+type lenience struct {
+	warnings []Warning
+}
+
+// warn keeps w as a warning, or returns its error when strict.
+func (l *lenience) warn(w Warning) error {
+	if l == nil {
+		return w.Err
+	}
+	l.warnings = append(l.warnings, w)
+	return nil
+}
+
+// warnColumn keeps a column that couldn't be understood, degraded.
+func (l *lenience) warnColumn(table, column string, err error) error {
+	return l.warn(Warning{
+		Table:   table,
+		Column:  column,
+		Message: err.Error(),
+		Err:     &ColumnError{Table: table, Column: column, Err: err},
+	})
+}
+
+// warnTable leaves out a table that couldn't be described.
+func (l *lenience) warnTable(table string, err error) error {
+	return l.warn(Warning{
+		Table:   table,
+		Message: err.Error(),
+		Err:     &TableError{Table: table, Err: err},
+	})
+}
+
+// warnRoutine leaves out a routine that couldn't be understood.
+func (l *lenience) warnRoutine(routine string, err error) error {
+	return l.warn(Warning{Routine: routine, Message: err.Error(), Err: err})
+}
+
+// add keeps the warnings of another lenience, like that of a table
+// described on its own.
+func (l *lenience) add(other *lenience) {
+	if l != nil && other != nil {
+		l.warnings = append(l.warnings, other.warnings...)
+	}
+}
+
+// list is the warnings, in the order they came in.
+func (l *lenience) list() []Warning {
+	if l == nil {
+		return nil
+	}
+	return l.warnings
+}
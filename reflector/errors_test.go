@@ -0,0 +1,99 @@
+package reflector
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestDescribeLeniently(t *testing.T) {
+	schema := newFakeSchema(3)
+	schema.tables[1].columns[3][1] = "widget(3)"
+	schema.missing = []string{"gone"}
+	db, _ := openFakeMySQL(t, schema)
+	defer db.Close()
+
+	_, err := DescribeMySQLContext(context.Background(), db, "fake", DescribeOptions{})
+	var typeErr *UnknownTypeError
+	if !errors.As(err, &typeErr) || typeErr.TypeName != "widget" {
+		t.Errorf("err=%v, want an unknown type", err)
+	}
+	var colErr *ColumnError
+	if !errors.As(err, &colErr) || colErr.Table != "t001" || colErr.Column != "kind" {
+		t.Errorf("err=%v, want the column t001.kind", err)
+	}
+
+	for _, opts := range []DescribeOptions{{Lenient: true}, {Lenient: true, Workers: 2}, {Lenient: true, Bulk: true}} {
+		got, err := DescribeMySQLContext(context.Background(), db, "fake", opts)
+		if err != nil {
+			t.Fatalf("with %+v, %v", opts, err)
+		}
+		if len(got.Tables) != 3 {
+			t.Errorf("with %+v, described %d tables, want 3", opts, len(got.Tables))
+		}
+		if kind := got.Tables[1].Has("kind"); kind == nil || kind.Type != SQLBytes || kind.TypeName != "widget(3)" {
+			t.Errorf("with %+v, column is %v, want it as bytes", opts, kind)
+		}
+
+		want := []Warning{{Table: "t001", Column: "kind"}}
+		if !opts.Bulk {
+			// bulk loads don't describe tables one by one
+			want = append(want, Warning{Table: "gone"})
+		}
+		if len(got.Warnings) != len(want) {
+			t.Fatalf("with %+v, warned %v, want %v", opts, got.Warnings, want)
+		}
+		for i, w := range got.Warnings {
+			if w.Table != want[i].Table || w.Column != want[i].Column || w.Message == "" {
+				t.Errorf("with %+v, warning %d is %v, want about %v", opts, i, w, want[i])
+			}
+		}
+		if !errors.As(got.Warnings[0].Err, &typeErr) {
+			t.Errorf("with %+v, warning is %v, want an unknown type", opts, got.Warnings[0].Err)
+		}
+	}
+}
+
+func TestDescribeLenientlyDropsBadIndices(t *testing.T) {
+	schema := newFakeSchema(3)
+	// a functional index of MySQL 8, without a column
+	schema.tables[2].indices = append(schema.tables[2].indices,
+		[]driver.Value{"t002", int64(1), "lower_name", int64(1), nil, "A", int64(10), nil, nil, "", "BTREE", "", ""})
+	db, _ := openFakeMySQL(t, schema)
+	defer db.Close()
+
+	for _, opts := range []DescribeOptions{{}, {Bulk: true}} {
+		if _, err := DescribeMySQLContext(context.Background(), db, "fake", opts); err == nil {
+			t.Errorf("with %+v, described a table with a bad index", opts)
+		}
+
+		opts.Lenient = true
+		got, err := DescribeMySQLContext(context.Background(), db, "fake", opts)
+		if err != nil {
+			t.Fatalf("with %+v, %v", opts, err)
+		}
+		if len(got.Tables) != 2 || got.Tables[1].Name != "t001" || got.Tables[1].Pk == nil {
+			t.Errorf("with %+v, described %v, want t000 and t001", opts, got.Tables)
+		}
+		if len(got.Warnings) != 1 || got.Warnings[0].Table != "t002" || got.Warnings[0].Column != "" {
+			t.Errorf("with %+v, warned %v, want t002 left out", opts, got.Warnings)
+		}
+	}
+}
+
+func TestParseBytesInvalidType(t *testing.T) {
+	if _, err := stopSQLType.ParseBytes([]byte("1")); err == nil {
+		t.Error("parsed bytes of an invalid type")
+	}
+}
+
+func TestColumnParseDegrades(t *testing.T) {
+	col := Column{Default: []byte("nope")}
+	if err := col.parse("int(11)", "NO"); err == nil {
+		t.Fatal("parsed an invalid default")
+	}
+	if col.Type != SQLInteger || col.Default != nil {
+		t.Errorf("got %#v, want an int without default", col)
+	}
+}
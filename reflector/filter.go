@@ -0,0 +1,104 @@
+package reflector
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+/*
+Filters!
+*/
+
+// TableFilter selects tables and views, or databases, by name. A
+// pattern is a glob, like `audit_*`, unless it's between slashes, like
+// `/^v[0-9]+_/`, in which case it's a regular expression matching
+// anywhere in the name.
+type TableFilter struct {
+	include []func(string) bool
+	exclude []func(string) bool
+}
+
+// NewTableFilter keeps the names matching any of the include patterns,
+// or all names if there are none, unless they match one of the exclude
+// patterns.
+func NewTableFilter(include, exclude []string) (*TableFilter, error) {
+	f := &TableFilter{}
+	for _, pattern := range include {
+		match, err := compilePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, match)
+	}
+	for _, pattern := range exclude {
+		match, err := compilePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, match)
+	}
+	return f, nil
+}
+
+func compilePattern(pattern string) (func(string) bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("compiling pattern %q, %w", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("compiling pattern %q, %w", pattern, err)
+	}
+	return func(name string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}, nil
+}
+
+// Match tells if the table or view is kept. A nil filter keeps them all.
+func (f *TableFilter) Match(name string) bool {
+	if f == nil {
+		return true
+	}
+	kept := len(f.include) == 0
+	for _, match := range f.include {
+		if match(name) {
+			kept = true
+			break
+		}
+	}
+	if !kept {
+		return false
+	}
+	for _, match := range f.exclude {
+		if match(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter removes the tables and views that f doesn't keep, for schemas
+// that weren't described with the filter, like those read from JSON or
+// from migrations.
+func (db *DBSchema) Filter(f *TableFilter) {
+	tables := db.Tables[:0]
+	for _, tbl := range db.Tables {
+		if f.Match(tbl.Name) {
+			tables = append(tables, tbl)
+		}
+	}
+	db.Tables = tables
+
+	views := db.Views[:0]
+	for _, view := range db.Views {
+		if f.Match(view.Name) {
+			views = append(views, view)
+		}
+	}
+	db.Views = views
+}
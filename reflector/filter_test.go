@@ -0,0 +1,91 @@
+package reflector
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTableFilter(t *testing.T) {
+	tests := []struct {
+		include, exclude []string
+		name             string
+		want             bool
+	}{
+		{nil, nil, "users", true},
+		{[]string{"user*"}, nil, "users", true},
+		{[]string{"user*"}, nil, "orders", false},
+		{[]string{"user*", "orders"}, nil, "orders", true},
+		{nil, []string{"schema_migrations"}, "schema_migrations", false},
+		{nil, []string{"*_archive"}, "orders_archive", false},
+		{nil, []string{"*_archive"}, "orders", true},
+		{[]string{"/^(users|orders)$/"}, nil, "orders", true},
+		{[]string{"/^(users|orders)$/"}, nil, "orders_archive", false},
+		{nil, []string{"/_v[0-9]+$/"}, "orders_v2", false},
+		{[]string{"orders*"}, []string{"*_archive"}, "orders_archive", false},
+	}
+	for _, tt := range tests {
+		f, err := NewTableFilter(tt.include, tt.exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.Match(tt.name); got != tt.want {
+			t.Errorf("including %q, excluding %q, match(%q)=%v, want %v",
+				tt.include, tt.exclude, tt.name, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"[a-", "/(/"} {
+		if _, err := NewTableFilter([]string{bad}, nil); err == nil {
+			t.Errorf("pattern %q compiled", bad)
+		}
+	}
+}
+
+func TestDescribeOnlyFilteredTables(t *testing.T) {
+	schema := newFakeSchema(10)
+	// excluded tables aren't described, so they needn't exist
+	schema.missing = []string{"schema_migrations", "t001_archive"}
+	db, fake := openFakeMySQL(t, schema)
+	defer db.Close()
+
+	f, err := NewTableFilter([]string{"t00[0-4]*", "/^names$/"}, []string{"schema_migrations", "*_archive"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DescribeOptions{Tables: f}
+	got, err := DescribeMySQLContext(context.Background(), db, "fake", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered := fake.reset()
+
+	var names []string
+	for _, tbl := range got.Tables {
+		names = append(names, tbl.Name)
+	}
+	for _, view := range got.Views {
+		names = append(names, view.Name)
+	}
+	want := []string{"t000", "t001", "t002", "t003", "t004", "names"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("described %v, want %v", names, want)
+	}
+
+	all, err := DescribeMySQLContext(context.Background(), db, "fake", DescribeOptions{Tables: f, Bulk: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(all, got) {
+		t.Errorf("bulk loaded\n%v\nwant\n%v", all, got)
+	}
+
+	schema.missing = nil
+	fake.reset()
+	if _, err := DescribeMySQLContext(context.Background(), db, "fake", DescribeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if unfiltered := fake.reset(); filtered >= unfiltered {
+		t.Errorf("filtering ran %d queries, not filtering ran %d", filtered, unfiltered)
+	}
+}
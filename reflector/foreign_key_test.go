@@ -0,0 +1,44 @@
+package reflector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestForeignKeysFromParts(t *testing.T) {
+	tests := []struct {
+		parts []foreignKeyPart
+		want  []ForeignKey
+	}{
+		{
+			parts: nil,
+			want:  nil,
+		},
+		{
+			parts: []foreignKeyPart{
+				{Name: "orders_ibfk_1", Column: "user_id", RefTable: "users", RefColumn: "id", OnDelete: "CASCADE", OnUpdate: "NO ACTION"},
+			},
+			want: []ForeignKey{
+				{Name: "orders_ibfk_1", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}, OnDelete: "CASCADE", OnUpdate: "NO ACTION"},
+			},
+		},
+		{
+			parts: []foreignKeyPart{
+				{Name: "fk_line", Column: "order_id", RefTable: "lines", RefColumn: "order_id", OnDelete: "RESTRICT"},
+				{Name: "fk_line", Column: "line_no", RefTable: "lines", RefColumn: "no", OnDelete: "RESTRICT"},
+				{Name: "fk_user", Column: "user_id", RefTable: "users", RefColumn: "id"},
+			},
+			want: []ForeignKey{
+				{Name: "fk_line", Columns: []string{"order_id", "line_no"}, RefTable: "lines", RefColumns: []string{"order_id", "no"}, OnDelete: "RESTRICT"},
+				{Name: "fk_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got := foreignKeysFromParts(tt.parts)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("foreignKeysFromParts(%v)=%v, want %v", tt.parts, got, tt.want)
+		}
+	}
+}
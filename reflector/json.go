@@ -0,0 +1,243 @@
+package reflector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+JSON!
+*/
+
+// WriteJSON writes the schema as indented JSON, which ReadJSON reads
+// back.
+func (db *DBSchema) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ReadJSON reads a schema written by WriteJSON.
+func ReadJSON(r io.Reader) (*DBSchema, error) {
+	db := &DBSchema{}
+	if err := json.NewDecoder(r).Decode(db); err != nil {
+		return nil, fmt.Errorf("decoding schema, %w", err)
+	}
+	return db, nil
+}
+
+// The enums are written by name.
+
+func (t SQLType) MarshalText() ([]byte, error) { return []byte(t.String()), nil }
+
+func (t *SQLType) UnmarshalText(b []byte) error {
+	for v := startSQLType; v < stopSQLType; v++ {
+		if v.String() == string(b) {
+			*t = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown SQLType %q", b)
+}
+
+func (d Dialect) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+
+func (d *Dialect) UnmarshalText(b []byte) error {
+	for v := startDialect; v < stopDialect; v++ {
+		if v.String() == string(b) {
+			*d = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown Dialect %q", b)
+}
+
+func (t IndexType) MarshalText() ([]byte, error) { return []byte(t.String()), nil }
+
+func (t *IndexType) UnmarshalText(b []byte) error {
+	for v := startIndex; v < stopIndex; v++ {
+		if v.String() == string(b) {
+			*t = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown IndexType %q", b)
+}
+
+func (t ConstraintType) MarshalText() ([]byte, error) { return []byte(t.String()), nil }
+
+func (t *ConstraintType) UnmarshalText(b []byte) error {
+	for v := startConstraint; v < stopConstraint; v++ {
+		if v.String() == string(b) {
+			*t = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown ConstraintType %q", b)
+}
+
+// Values of interface{} fields are written as text, then parsed back
+// according to the type of their column or variable.
+
+func (col Column) MarshalJSON() ([]byte, error) {
+	type column Column
+	return json.Marshal(struct {
+		column
+		Key     string  `json:"key,omitempty"`
+		Default *string `json:"default,omitempty"`
+		Extra   string  `json:"extra,omitempty"`
+	}{
+		column:  column(col),
+		Key:     bytesText(col.Key),
+		Default: col.defaultText(),
+		Extra:   bytesText(col.Extra),
+	})
+}
+
+func (col *Column) UnmarshalJSON(b []byte) error {
+	type column Column
+	aux := struct {
+		*column
+		Key     string  `json:"key"`
+		Default *string `json:"default"`
+		Extra   string  `json:"extra"`
+	}{column: (*column)(col)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	col.Key = textBytes(aux.Key)
+	col.Extra = textBytes(aux.Extra)
+	col.Default = nil
+	if aux.Default != nil {
+		col.Default = parseValueText(col.Type, col.Unsigned, *aux.Default)
+	}
+	return nil
+}
+
+// Param has its own methods, the ones of Column would hide its mode.
+
+func (p Param) MarshalJSON() ([]byte, error) {
+	col, err := json.Marshal(p.Column)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Column json.RawMessage `json:"column"`
+		Mode   string          `json:"mode,omitempty"`
+	}{col, p.Mode})
+}
+
+func (p *Param) UnmarshalJSON(b []byte) error {
+	aux := struct {
+		Column *Column `json:"column"`
+		Mode   string  `json:"mode"`
+	}{Column: &p.Column}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	p.Mode = aux.Mode
+	return nil
+}
+
+func (v Variable) MarshalJSON() ([]byte, error) {
+	type variable Variable
+	return json.Marshal(struct {
+		variable
+		Value *string `json:"value,omitempty"`
+	}{variable(v), valueText(v.Type, v.Value)})
+}
+
+func (v *Variable) UnmarshalJSON(b []byte) error {
+	type variable Variable
+	aux := struct {
+		*variable
+		Value *string `json:"value"`
+	}{variable: (*variable)(v)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	v.Value = nil
+	if aux.Value != nil {
+		v.Value = parseValueText(v.Type, false, *aux.Value)
+	}
+	return nil
+}
+
+// bytesText is the text of the []byte values scanned from MySQL.
+func bytesText(v interface{}) string {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+	return ""
+}
+
+func textBytes(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return []byte(s)
+}
+
+// valueText is the text form of a value parsed by SQLType.ParseBytes,
+// or nil for NULL.
+func valueText(t SQLType, v interface{}) *string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		s = string(v)
+	case []string:
+		s = strings.Join(v, ",")
+	case time.Time:
+		s = v.Format("2006-01-02 15:04:05")
+	case uint64:
+		if t == SQLBit {
+			s = strconv.FormatUint(v, 2)
+		} else {
+			s = strconv.FormatUint(v, 10)
+		}
+	default:
+		s = fmt.Sprint(v)
+	}
+	return &s
+}
+
+// defaultText is valueText of the default of col, with times written
+// as its type holds them, like `12:30:00` for a time or `2020` for a year.
+func (col Column) defaultText() *string {
+	if t, ok := col.Default.(time.Time); ok {
+		s := t.Format(col.timeLayout())
+		return &s
+	}
+	return valueText(col.Type, col.Default)
+}
+
+// parseValueText reverses valueText. Text that doesn't parse as t, like
+// the expressions used as defaults, is kept as is.
+func parseValueText(t SQLType, unsigned bool, s string) interface{} {
+	if t <= startSQLType || t >= stopSQLType {
+		return s
+	}
+	if t == SQLInteger && unsigned {
+		if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return v
+		}
+		return s
+	}
+	v, err := t.ParseBytes([]byte(s))
+	if err != nil {
+		return s
+	}
+	return v
+}
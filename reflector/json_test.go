@@ -0,0 +1,51 @@
+package reflector
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	id := Column{Name: "id", Type: SQLInteger, Size: 8, Unsigned: true, Key: []byte("PRI"), Extra: []byte("auto_increment")}
+	want := &DBSchema{
+		Name:    "shop",
+		Dialect: DialectMySQL,
+		Variables: []Variable{
+			{Name: "autocommit", Type: SQLBool, Value: true},
+			{Name: "sql_safe_updates", Type: SQLBool, Value: false},
+		},
+		Tables: []Table{{
+			Name:    "orders",
+			Comment: "Paid orders.",
+			Pk:      &Index{KeyName: "PRIMARY", IndexType: IndexBtree, Columns: []Column{id}, Parts: []IndexPart{{Column: id, KeyName: "PRIMARY", SeqInIndex: 1, ColumnName: "id", IsAscending: true}}},
+			Columns: []Column{
+				id,
+				{Name: "status", Type: SQLEnum, Values: []string{"new", "paid"}, Default: "new"},
+				{Name: "total", Type: SQLDecimal, Precision: 10, Scale: 2, Default: "0.00"},
+				{Name: "paid_at", Type: SQLTime, Nullable: true, Default: "CURRENT_TIMESTAMP"},
+				{Name: "quota", Type: SQLInteger, Size: 8, Unsigned: true, Default: uint64(18446744073709551615)},
+				{Name: "flags", Type: SQLBit, Length: 3, Default: uint64(5)},
+			},
+			Constraints: []Constraint{{Name: "total_chk", Type: ConstraintCheck, Clause: "(`total` >= 0)"}},
+		}},
+		Routines: []Routine{{
+			Name:    "total_of",
+			Type:    "FUNCTION",
+			Params:  []Param{{Column: Column{Name: "order_id", Type: SQLInteger, Nullable: true}, Mode: "IN"}},
+			Returns: &Column{Type: SQLDecimal, Nullable: true},
+		}},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := want.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadJSON(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want\n%#v\ngot\n%#v", want, got)
+	}
+}
@@ -0,0 +1,471 @@
+package reflector
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+PostgreSQL!
+*/
+
+// DescribePostgres loads the tables found in the namespace `schema` of
+// the PostgreSQL database `dbname`, usually "public".
+func DescribePostgres(db *sql.DB, dbname, schema string) (*DBSchema, error) {
+
+	dbschema := &DBSchema{
+		Name:    dbname,
+		Dialect: DialectPostgres,
+	}
+
+	return dbschema, dbschema.loadPostgres(db, schema)
+}
+
+func (db *DBSchema) loadPostgres(q queryer, schema string) error {
+	if err := db.loadPostgresVariables(q); err != nil {
+		return fmt.Errorf("loading variables: %w", err)
+	}
+	if err := db.loadPostgresTables(q, schema); err != nil {
+		return fmt.Errorf("loading tables: %w", err)
+	}
+	if err := db.loadPostgresViews(q, schema); err != nil {
+		return fmt.Errorf("loading views: %w", err)
+	}
+	return nil
+}
+
+func (db *DBSchema) loadPostgresVariables(q queryer) error {
+
+	rows, err := q.Query("select name, setting from pg_settings")
+	if err != nil {
+		return fmt.Errorf("showing settings, %w", err)
+	}
+	defer rows.Close()
+
+	for i := 0; rows.Next(); i++ {
+		v := Variable{}
+		if err := v.scan(rows); err != nil {
+			return fmt.Errorf("scanning setting %d, %w", i, err)
+		}
+		db.Variables = append(db.Variables, v)
+	}
+
+	return rows.Err()
+}
+
+func (db *DBSchema) loadPostgresTables(q queryer, schema string) error {
+
+	rows, err := q.Query(`
+select table_name,
+       coalesce(obj_description(format('%I.%I', table_schema, table_name)::regclass, 'pg_class'), '')
+from information_schema.tables
+where table_schema = $1 and table_type = 'BASE TABLE'
+order by table_name`, schema)
+	if err != nil {
+		return fmt.Errorf("showing tables, %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		tbl := Table{}
+		if err := rows.Scan(&tbl.Name, &tbl.Comment); err != nil {
+			return err
+		}
+		if err := tbl.loadPostgres(q, schema); err != nil {
+			return fmt.Errorf("loading table %q, %w", tbl.Name, err)
+		}
+		db.Tables = append(db.Tables, tbl)
+	}
+
+	return rows.Err()
+}
+
+func (db *DBSchema) loadPostgresViews(q queryer, schema string) error {
+
+	rows, err := q.Query(`
+select table_name, coalesce(view_definition, '')
+from information_schema.views
+where table_schema = $1
+order by table_name`, schema)
+	if err != nil {
+		return fmt.Errorf("showing views, %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		view := View{}
+		if err := rows.Scan(&view.Name, &view.Definition); err != nil {
+			return err
+		}
+		tbl := Table{Name: view.Name}
+		if err := tbl.loadPostgresColumns(q, schema); err != nil {
+			return fmt.Errorf("loading view %q, %w", view.Name, err)
+		}
+		view.Columns = tbl.Columns
+		db.Views = append(db.Views, view)
+	}
+
+	return rows.Err()
+}
+
+func (tbl *Table) loadPostgres(q queryer, schema string) error {
+	if err := tbl.loadPostgresColumns(q, schema); err != nil {
+		return err
+	}
+
+	if err := tbl.loadPostgresIndices(q, schema); err != nil {
+		return err
+	}
+
+	sort.Sort(indexByKeyName(tbl.Indices))
+
+	tbl.collectUniqueConstraints()
+	if err := tbl.loadPostgresChecks(q, schema); err != nil {
+		return err
+	}
+
+	sort.Sort(constraintsByName(tbl.Constraints))
+
+	if err := tbl.loadPostgresForeignKeys(q, schema); err != nil {
+		return err
+	}
+
+	sort.Sort(foreignKeysByName(tbl.ForeignKeys))
+
+	return tbl.loadPostgresTriggers(q, schema)
+}
+
+func (tbl *Table) loadPostgresColumns(q queryer, schema string) error {
+	// serial, identity and generated columns are reported with the same
+	// `extra` as MySQL's auto_increment and generated columns
+	rows, err := q.Query(`
+select a.attname,
+       format_type(a.atttypid, a.atttypmod),
+       (select string_agg(quote_literal(e.enumlabel), ',' order by e.enumsortorder)
+        from pg_enum e
+        where e.enumtypid = t.oid),
+       not a.attnotnull,
+       pg_get_expr(d.adbin, d.adrelid),
+       case when a.attgenerated = 's'
+            then 'STORED GENERATED'
+            when a.attidentity <> ''
+              or coalesce(pg_get_expr(d.adbin, d.adrelid), '') like 'nextval(%'
+            then 'auto_increment'
+            else ''
+       end,
+       coalesce(col_description(a.attrelid, a.attnum), '')
+from pg_attribute a
+join pg_class c on c.oid = a.attrelid
+join pg_namespace n on n.oid = c.relnamespace
+join pg_type t on t.oid = a.atttypid
+left join pg_attrdef d on d.adrelid = a.attrelid and d.adnum = a.attnum
+where n.nspname = $1 and c.relname = $2
+  and a.attnum > 0 and not a.attisdropped
+order by a.attnum`, schema, tbl.Name)
+	if err != nil {
+		return fmt.Errorf("describing table %q, %w", tbl.Name, err)
+	}
+	defer rows.Close()
+
+	for i := 0; rows.Next(); i++ {
+		col := Column{}
+		err := col.scanPostgres(rows)
+		if err != nil {
+			return fmt.Errorf("scanning column %d, %w", i, err)
+		}
+		tbl.Columns = append(tbl.Columns, col)
+	}
+
+	return rows.Err()
+}
+
+func (tbl *Table) loadPostgresIndices(q queryer, schema string) error {
+	// primary keys are renamed PRIMARY, like MySQL reports them
+	rows, err := q.Query(`
+select case when ix.indisprimary then 'PRIMARY' else i.relname end,
+       not ix.indisunique,
+       k.n,
+       a.attname,
+       (ix.indoption[k.n::int - 1] & 1) = 0,
+       coalesce(i.reltuples, 0)::bigint,
+       am.amname
+from pg_index ix
+join pg_class t on t.oid = ix.indrelid
+join pg_class i on i.oid = ix.indexrelid
+join pg_namespace n on n.oid = t.relnamespace
+join pg_am am on am.oid = i.relam
+cross join lateral unnest(ix.indkey) with ordinality as k(attnum, n)
+join pg_attribute a on a.attrelid = t.oid and a.attnum = k.attnum
+where n.nspname = $1 and t.relname = $2
+order by i.relname, k.n`, schema, tbl.Name)
+	if err != nil {
+		return fmt.Errorf("showing indices %q, %w", tbl.Name, err)
+	}
+	defer rows.Close()
+
+	var parts []IndexPart
+	for i := 0; rows.Next(); i++ {
+		idx := IndexPart{Table: tbl.Name}
+		err := idx.scanPostgres(tbl, rows)
+		if err != nil {
+			return fmt.Errorf("scanning index %d, %w", i, err)
+		}
+		parts = append(parts, idx)
+	}
+	tbl.Pk, tbl.Indices = indicesFromParts(parts)
+
+	return rows.Err()
+}
+
+func (tbl *Table) loadPostgresForeignKeys(q queryer, schema string) error {
+	rows, err := q.Query(`
+select c.conname,
+       a.attname,
+       rt.relname,
+       ra.attname,
+       c.confdeltype,
+       c.confupdtype
+from pg_constraint c
+join pg_class t on t.oid = c.conrelid
+join pg_namespace n on n.oid = t.relnamespace
+join pg_class rt on rt.oid = c.confrelid
+cross join lateral unnest(c.conkey, c.confkey) with ordinality as k(attnum, refattnum, n)
+join pg_attribute a on a.attrelid = c.conrelid and a.attnum = k.attnum
+join pg_attribute ra on ra.attrelid = c.confrelid and ra.attnum = k.refattnum
+where c.contype = 'f' and n.nspname = $1 and t.relname = $2
+order by c.conname, k.n`, schema, tbl.Name)
+	if err != nil {
+		return fmt.Errorf("showing foreign keys %q, %w", tbl.Name, err)
+	}
+	defer rows.Close()
+
+	var parts []foreignKeyPart
+	for i := 0; rows.Next(); i++ {
+		part := foreignKeyPart{}
+		err := rows.Scan(
+			&part.Name,
+			&part.Column,
+			&part.RefTable,
+			&part.RefColumn,
+			&part.OnDelete,
+			&part.OnUpdate,
+		)
+		if err != nil {
+			return fmt.Errorf("scanning foreign key %d, %w", i, err)
+		}
+		part.OnDelete = postgresForeignKeyRule(part.OnDelete)
+		part.OnUpdate = postgresForeignKeyRule(part.OnUpdate)
+		parts = append(parts, part)
+	}
+	tbl.ForeignKeys = foreignKeysFromParts(parts)
+
+	return rows.Err()
+}
+
+func (tbl *Table) loadPostgresChecks(q queryer, schema string) error {
+	rows, err := q.Query(`
+select c.conname, pg_get_constraintdef(c.oid)
+from pg_constraint c
+join pg_class t on t.oid = c.conrelid
+join pg_namespace n on n.oid = t.relnamespace
+where c.contype = 'c' and n.nspname = $1 and t.relname = $2`, schema, tbl.Name)
+	if err != nil {
+		return fmt.Errorf("showing check constraints %q, %w", tbl.Name, err)
+	}
+	defer rows.Close()
+
+	for i := 0; rows.Next(); i++ {
+		c := Constraint{Type: ConstraintCheck}
+		if err := rows.Scan(&c.Name, &c.Clause); err != nil {
+			return fmt.Errorf("scanning check constraint %d, %w", i, err)
+		}
+		// definitions read `CHECK ((qty > 0)) NOT VALID`
+		c.Clause = strings.TrimSuffix(c.Clause, " NOT VALID")
+		c.Clause = strings.TrimSpace(strings.TrimPrefix(c.Clause, "CHECK"))
+		tbl.Constraints = append(tbl.Constraints, c)
+	}
+
+	return rows.Err()
+}
+
+// loadPostgresTriggers uses the source of the trigger's function as its
+// body, since the trigger itself only says which function to execute.
+func (tbl *Table) loadPostgresTriggers(q queryer, schema string) error {
+	rows, err := q.Query(`
+select t.trigger_name,
+       t.action_timing,
+       t.event_manipulation,
+       coalesce(p.prosrc, t.action_statement)
+from information_schema.triggers t
+join pg_class c on c.relname = t.event_object_table
+join pg_namespace n on n.oid = c.relnamespace and n.nspname = t.event_object_schema
+left join pg_trigger pt on pt.tgrelid = c.oid and pt.tgname = t.trigger_name
+left join pg_proc p on p.oid = pt.tgfoid
+where t.event_object_schema = $1 and t.event_object_table = $2
+order by t.action_timing desc, t.event_manipulation, t.action_order`, schema, tbl.Name)
+	if err != nil {
+		return fmt.Errorf("showing triggers %q, %w", tbl.Name, err)
+	}
+	defer rows.Close()
+
+	for i := 0; rows.Next(); i++ {
+		trg := Trigger{Table: tbl.Name}
+		err := rows.Scan(&trg.Name, &trg.Timing, &trg.Event, &trg.Body)
+		if err != nil {
+			return fmt.Errorf("scanning trigger %d, %w", i, err)
+		}
+		tbl.Triggers = append(tbl.Triggers, trg)
+	}
+
+	return rows.Err()
+}
+
+// postgresForeignKeyRule spells out the action codes of pg_constraint.
+func postgresForeignKeyRule(code string) string {
+	switch code {
+	case "a":
+		return "NO ACTION"
+	case "r":
+		return "RESTRICT"
+	case "c":
+		return "CASCADE"
+	case "n":
+		return "SET NULL"
+	case "d":
+		return "SET DEFAULT"
+	}
+	return code
+}
+
+func (col *Column) scanPostgres(rows *sql.Rows) error {
+	var (
+		typeName string
+		enum     sql.NullString
+		def      sql.NullString
+		extra    string
+	)
+	err := rows.Scan(
+		&col.Name,
+		&typeName,
+		&enum,
+		&col.Nullable,
+		&def,
+		&extra,
+		&col.Comment,
+	)
+	if err != nil {
+		return err
+	}
+	col.TypeName = typeName
+	if enum.Valid {
+		// listed like MySQL lists the values of enums
+		col.Type = SQLEnum
+		col.Values = parseEnumValues("enum(" + enum.String + ")")
+	} else {
+		col.Type, err = parsePostgresTypeName(typeName)
+		if err != nil {
+			return err
+		}
+		col.parseTypeDetails(typeName)
+	}
+	if extra != "" {
+		col.Extra = []byte(extra)
+	}
+	if def.Valid && extra == "" {
+		col.Default = parseDefault(col.Type, def.String)
+	}
+	return nil
+}
+
+func (idx *IndexPart) scanPostgres(tbl *Table, rows *sql.Rows) error {
+	var method string
+	err := rows.Scan(
+		&idx.KeyName,
+		&idx.NonUnique,
+		&idx.SeqInIndex,
+		&idx.ColumnName,
+		&idx.IsAscending,
+		&idx.Cardinality,
+		&method,
+	)
+	if err != nil {
+		return err
+	}
+	switch method {
+	case "btree":
+		idx.IndexType = IndexBtree
+	case "hash":
+		idx.IndexType = IndexHash
+	case "gin":
+		idx.IndexType = IndexFulltext
+	case "gist", "spgist":
+		idx.IndexType = IndexRtree
+	}
+	if col := tbl.Has(idx.ColumnName); col != nil {
+		idx.CanBeNull = col.Nullable
+	}
+	return idx.bindColumn(tbl)
+}
+
+// parsePostgresTypeName maps the names returned by `format_type`, such
+// as "character varying(255)" or "integer[]", to a SQLType. Arrays are
+// kept in their text form, as bytes.
+func parsePostgresTypeName(name string) (SQLType, error) {
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if strings.HasSuffix(name, "[]") {
+		return SQLBytes, nil
+	}
+	// drop modifiers: "numeric(10,2)", "timestamp(3) with time zone"
+	for {
+		l := strings.Index(name, "(")
+		r := strings.Index(name, ")")
+		if l < 0 || r < l {
+			break
+		}
+		name = strings.TrimSpace(name[:l] + name[r+1:])
+	}
+	name = strings.Join(strings.Fields(name), " ")
+
+	var (
+		t   SQLType
+		err error
+	)
+	switch name {
+
+	case "smallint", "integer", "bigint", "int2", "int4", "int8",
+		"smallserial", "serial", "bigserial", "oid":
+		t = SQLInteger
+
+	case "numeric", "decimal":
+		t = SQLDecimal
+
+	case "real", "double precision", "float4", "float8", "money":
+		t = SQLFloat
+
+	case "boolean", "bool":
+		t = SQLBool
+
+	case "date", "timestamp", "timestamptz",
+		"timestamp without time zone", "timestamp with time zone",
+		"time", "timetz", "time without time zone", "time with time zone":
+		t = SQLTime
+
+	case "character varying", "varchar", "character", "char", "bpchar",
+		"text", "citext", "name", "uuid", "xml",
+		"inet", "cidr", "macaddr", "interval", "tsvector":
+		t = SQLString
+
+	case "bytea", "bit", "bit varying", "varbit":
+		t = SQLBytes
+
+	case "json", "jsonb":
+		t = SQLJSON
+
+	default:
+		err = fmt.Errorf("unknown type %q", name)
+	}
+	return t, err
+}
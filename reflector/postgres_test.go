@@ -0,0 +1,40 @@
+package reflector
+
+import "testing"
+
+func TestParsePostgresTypeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want SQLType
+	}{
+		{"integer", SQLInteger},
+		{"bigint", SQLInteger},
+		{"serial", SQLInteger},
+		{"numeric(10,2)", SQLDecimal},
+		{"double precision", SQLFloat},
+		{"boolean", SQLBool},
+		{"timestamp with time zone", SQLTime},
+		{"timestamp(3) without time zone", SQLTime},
+		{"timestamptz", SQLTime},
+		{"character varying(255)", SQLString},
+		{"uuid", SQLString},
+		{"jsonb", SQLJSON},
+		{"bytea", SQLBytes},
+		{"integer[]", SQLBytes},
+		{"character varying(20)[]", SQLBytes},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePostgresTypeName(tt.name)
+		if err != nil {
+			t.Fatalf("parsePostgresTypeName(%q): %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Fatalf("parsePostgresTypeName(%q)=%v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := parsePostgresTypeName("tsrange"); err == nil {
+		t.Fatalf("want error for unknown type")
+	}
+}
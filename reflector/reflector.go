@@ -2,10 +2,13 @@ package reflector
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"text/tabwriter"
 )
 
@@ -13,31 +16,176 @@ type queryer interface {
 	Query(string, ...interface{}) (*sql.Rows, error)
 }
 
+// contextQueryer runs the queries of a queryer with QueryContext, so
+// that they're canceled with ctx.
+type contextQueryer struct {
+	ctx context.Context
+	db  interface {
+		QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	}
+}
+
+func (q contextQueryer) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return q.db.QueryContext(q.ctx, query, args...)
+}
+
 /*
 Database!
 */
 
 type DBSchema struct {
-	Name      string
-	Variables []Variable
-	Tables    []Table
+	Name      string     `json:"name"`
+	Dialect   Dialect    `json:"dialect,omitempty"`
+	Variables []Variable `json:"variables,omitempty"`
+	Tables    []Table    `json:"tables,omitempty"`
+	Views     []View     `json:"views,omitempty"`
+	Routines  []Routine  `json:"routines,omitempty"` // Stored procedures and functions, loaded for MySQL.
+	Warnings  []Warning  `json:"warnings,omitempty"` // What a lenient description left out or degraded.
+}
+
+type Dialect int
+
+const (
+	startDialect Dialect = iota
+
+	DialectMySQL
+	DialectPostgres
+	DialectSQLite
+
+	stopDialect
+)
+
+// Driver is the name under which the usual database/sql driver for
+// the dialect registers itself. Defaults to MySQL's.
+func (d Dialect) Driver() string {
+	switch d {
+	case DialectPostgres:
+		return "postgres"
+	case DialectSQLite:
+		return "sqlite3"
+	}
+	return "mysql"
+}
+
+// Quote quotes an identifier, like a table or column name, so that it
+// can be a keyword or hold any character. MySQL quotes with backticks,
+// the others with double quotes. Defaults to MySQL's.
+func (d Dialect) Quote(name string) string {
+	switch d {
+	case DialectPostgres, DialectSQLite:
+		return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+	}
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
 }
 
 func DescribeMySQL(db *sql.DB, dbname string) (*DBSchema, error) {
+	return DescribeMySQLContext(context.Background(), db, dbname, DescribeOptions{})
+}
+
+// DescribeOptions tunes how DescribeMySQLContext describes a database.
+type DescribeOptions struct {
+	// Bulk loads the schema like DescribeMySQLBulk. Workers and Progress
+	// are then unused.
+	Bulk bool
+	// Workers is how many tables are described at once over the pool of
+	// connections of the *sql.DB. One at a time if unset.
+	Workers int
+	// Progress, if set, is called after each table is described, with
+	// the number of tables done so far. Calls are never concurrent.
+	Progress func(table string, done, total int)
+	// Tables, if set, selects the tables and views to describe. The
+	// others aren't queried.
+	Tables *TableFilter
+	// Lenient leaves out the tables, views and routines that can't be
+	// described, and degrades the columns that can't be understood to
+	// bytes, adding a Warning to the schema for each rather than failing.
+	Lenient bool
+}
+
+// DescribeMySQLContext is DescribeMySQL, running every query with ctx so
+// that it can be given a deadline or canceled, and describing the tables
+// as told by opts. The schema is the same regardless of the number of
+// workers.
+//
+// The queries name the database, which needn't be the one of the
+// connection, so that a single *sql.DB can describe all the databases
+// of a server. An empty dbname is the database of the connection.
+//
+// The errors of tables and columns are a *TableError or a *ColumnError,
+// and an unknown type an *UnknownTypeError, to look for with errors.As.
+func DescribeMySQLContext(ctx context.Context, db *sql.DB, dbname string, opts DescribeOptions) (*DBSchema, error) {
 
 	schema := &DBSchema{
-		Name: dbname,
+		Name:    dbname,
+		Dialect: DialectMySQL,
+	}
+
+	q := contextQueryer{ctx: ctx, db: db}
+	if dbname == "" {
+		var current sql.NullString
+		if err := db.QueryRowContext(ctx, "select database()").Scan(&current); err != nil {
+			return nil, fmt.Errorf("selecting database, %w", err)
+		}
+		if current.String == "" {
+			return nil, fmt.Errorf("no database given, and none selected by the connection")
+		}
+		schema.Name = current.String
+	}
+	var l *lenience
+	if opts.Lenient {
+		l = &lenience{}
+	}
+	var err error
+	if opts.Bulk {
+		err = schema.loadBulk(q, opts.Tables, l)
+	} else {
+		err = schema.load(ctx, q, opts, l)
+	}
+	schema.Warnings = l.list()
+	return schema, err
+}
+
+// ListMySQLDatabases returns the sorted names of the databases of the
+// server that f keeps, leaving out those of MySQL itself.
+func ListMySQLDatabases(ctx context.Context, db *sql.DB, f *TableFilter) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT SCHEMA_NAME
+FROM information_schema.SCHEMATA
+WHERE SCHEMA_NAME NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')
+ORDER BY SCHEMA_NAME`)
+	if err != nil {
+		return nil, fmt.Errorf("showing databases, %w", err)
 	}
+	defer rows.Close()
 
-	return schema, schema.load(db)
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if f.Match(name) {
+			names = append(names, name)
+		}
+	}
+	return names, rows.Err()
 }
 
-func (db *DBSchema) load(q queryer) error {
+func (db *DBSchema) load(ctx context.Context, q queryer, opts DescribeOptions, l *lenience) error {
 	if err := db.loadVariables(q); err != nil {
-		return fmt.Errorf("loading variables: %v", err)
+		return fmt.Errorf("loading variables: %w", err)
+	}
+	if err := db.loadTables(ctx, q, opts, l); err != nil {
+		return fmt.Errorf("loading tables: %w", err)
+	}
+	if err := db.loadViews(q, opts.Tables, l); err != nil {
+		return fmt.Errorf("loading views: %w", err)
+	}
+	if err := db.loadComments(q); err != nil {
+		return fmt.Errorf("loading comments: %w", err)
 	}
-	if err := db.loadTables(q); err != nil {
-		return fmt.Errorf("loading tables: %v", err)
+	if err := db.loadRoutines(q, l); err != nil {
+		return fmt.Errorf("loading routines: %w", err)
 	}
 	return nil
 }
@@ -46,14 +194,14 @@ func (db *DBSchema) loadVariables(q queryer) error {
 
 	rows, err := q.Query("show variables")
 	if err != nil {
-		return fmt.Errorf("showing variables, %v", err)
+		return fmt.Errorf("showing variables, %w", err)
 	}
 	defer rows.Close()
 
 	for i := 0; rows.Next(); i++ {
 		v := Variable{}
 		if err := v.scan(rows); err != nil {
-			return fmt.Errorf("scanning variable %d, %v", i, err)
+			return fmt.Errorf("scanning variable %d, %w", i, err)
 		}
 		db.Variables = append(db.Variables, v)
 	}
@@ -61,23 +209,284 @@ func (db *DBSchema) loadVariables(q queryer) error {
 	return rows.Err()
 }
 
-func (db *DBSchema) loadTables(q queryer) error {
+// loadTables lists the tables, then describes them with opts.Workers
+// at once. Each table keeps its place in the list, so the schema doesn't
+// depend on which worker finishes first. The errors of all the tables
+// that couldn't be described are returned together, unless l is lenient
+// and leaves them out. No more tables are started once ctx is done.
+func (db *DBSchema) loadTables(ctx context.Context, q queryer, opts DescribeOptions, l *lenience) error {
+
+	query := fmt.Sprintf("show full tables from %s where Table_Type != 'VIEW'", mysqlQuote(db.Name))
+	names, err := listTables(q, query, opts.Tables)
+	if err != nil {
+		return fmt.Errorf("showing tables, %w", err)
+	}
+
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(names) {
+		workers = len(names)
+	}
+
+	tables := make([]Table, len(names))
+	errs := make([]error, len(names))
+	// each table warns on its own, to keep the warnings in table order
+	lenient := make([]*lenience, len(names))
+	if l != nil {
+		for i := range lenient {
+			lenient[i] = &lenience{}
+		}
+	}
+	todo := make(chan int)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done int
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range todo {
+				tables[i].Name = names[i]
+				errs[i] = tables[i].load(q, db.Name, lenient[i])
+				mu.Lock()
+				done++
+				if opts.Progress != nil {
+					opts.Progress(names[i], done, len(names))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for i := range names {
+		select {
+		case todo <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(todo)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var failed TableErrors
+	for i, err := range errs {
+		if err != nil {
+			if err := l.warnTable(names[i], err); err != nil {
+				failed = append(failed, err)
+			}
+			continue
+		}
+		l.add(lenient[i])
+		db.Tables = append(db.Tables, tables[i])
+	}
+	if len(failed) != 0 {
+		return failed
+	}
+
+	return db.loadChecks(q)
+}
+
+// listTables returns the names in the first column of the `show tables`
+// query that f keeps, reading them all before any of them is described.
+func listTables(q queryer, query string, f *TableFilter) ([]string, error) {
+	rows, err := q.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name, new(sql.RawBytes)); err != nil {
+			return nil, err
+		}
+		if f.Match(name) {
+			names = append(names, name)
+		}
+	}
+	return names, rows.Err()
+}
+
+// loadComments loads the comments of all tables and columns at once,
+// since `describe` doesn't report them.
+func (db *DBSchema) loadComments(q queryer) error {
+	rows, err := q.Query(`
+SELECT TABLE_NAME, TABLE_COMMENT
+FROM information_schema.TABLES
+WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE' AND TABLE_COMMENT != ''`, db.Name)
+	if err != nil {
+		return fmt.Errorf("showing table comments, %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tblname, comment string
+		if err := rows.Scan(&tblname, &comment); err != nil {
+			return err
+		}
+		for i := range db.Tables {
+			if db.Tables[i].Name == tblname {
+				db.Tables[i].Comment = comment
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = q.Query(`
+SELECT TABLE_NAME, COLUMN_NAME, COLUMN_COMMENT
+FROM information_schema.COLUMNS
+WHERE TABLE_SCHEMA = ? AND COLUMN_COMMENT != ''`, db.Name)
+	if err != nil {
+		return fmt.Errorf("showing column comments, %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tblname, colname, comment string
+		if err := rows.Scan(&tblname, &colname, &comment); err != nil {
+			return err
+		}
+		for i := range db.Tables {
+			if db.Tables[i].Name == tblname {
+				db.Tables[i].setComment(colname, comment)
+			}
+		}
+		for i := range db.Views {
+			if db.Views[i].Name == tblname {
+				setComment(db.Views[i].Columns, colname, comment)
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// setComment sets the comment of a column, and of its copies in the
+// indices.
+func (tbl *Table) setComment(colname, comment string) {
+	setComment(tbl.Columns, colname, comment)
+	indices := tbl.Indices
+	if tbl.Pk != nil {
+		indices = append(indices, *tbl.Pk)
+	}
+	for _, idx := range indices {
+		setComment(idx.Columns, colname, comment)
+		for i := range idx.Parts {
+			if idx.Parts[i].Column.Name == colname {
+				idx.Parts[i].Column.Comment = comment
+			}
+		}
+	}
+}
+
+func setComment(cols []Column, colname, comment string) {
+	for i := range cols {
+		if cols[i].Name == colname {
+			cols[i].Comment = comment
+		}
+	}
+}
 
-	rows, err := q.Query(`show full tables where Table_Type != 'VIEW'`)
+// loadChecks loads the CHECK constraints of all tables at once. They're
+// only reported by MySQL 8 and MariaDB, which report them differently.
+func (db *DBSchema) loadChecks(q queryer) error {
+	rows, err := q.Query(`
+SELECT COLUMN_NAME
+FROM information_schema.COLUMNS
+WHERE TABLE_SCHEMA = 'information_schema' AND TABLE_NAME = 'CHECK_CONSTRAINTS'`)
 	if err != nil {
-		return fmt.Errorf("showing tables, %v", err)
+		return fmt.Errorf("showing check constraints support, %w", err)
+	}
+	var supported, hasTableName bool
+	for rows.Next() {
+		var colname string
+		if err := rows.Scan(&colname); err != nil {
+			rows.Close()
+			return err
+		}
+		supported = true
+		hasTableName = hasTableName || strings.EqualFold(colname, "TABLE_NAME")
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || !supported {
+		return err
+	}
+
+	query := `
+SELECT tc.TABLE_NAME, cc.CONSTRAINT_NAME, cc.CHECK_CLAUSE
+FROM information_schema.TABLE_CONSTRAINTS tc
+JOIN information_schema.CHECK_CONSTRAINTS cc
+  ON  cc.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA
+  AND cc.CONSTRAINT_NAME   = tc.CONSTRAINT_NAME
+WHERE tc.TABLE_SCHEMA = ? AND tc.CONSTRAINT_TYPE = 'CHECK'`
+	if hasTableName {
+		// MariaDB names constraints per table, not per schema
+		query = `
+SELECT TABLE_NAME, CONSTRAINT_NAME, CHECK_CLAUSE
+FROM information_schema.CHECK_CONSTRAINTS
+WHERE CONSTRAINT_SCHEMA = ?`
+	}
+
+	rows, err = q.Query(query, db.Name)
+	if err != nil {
+		return fmt.Errorf("showing check constraints, %w", err)
+	}
+	defer rows.Close()
+
+	tables := make(map[string]*Table, len(db.Tables))
+	for i := range db.Tables {
+		tables[db.Tables[i].Name] = &db.Tables[i]
+	}
+	for i := 0; rows.Next(); i++ {
+		var tblname string
+		c := Constraint{Type: ConstraintCheck}
+		if err := rows.Scan(&tblname, &c.Name, &c.Clause); err != nil {
+			return fmt.Errorf("scanning check constraint %d, %w", i, err)
+		}
+		if tbl, ok := tables[tblname]; ok {
+			tbl.Constraints = append(tbl.Constraints, c)
+		}
+	}
+	for _, tbl := range tables {
+		sort.Sort(constraintsByName(tbl.Constraints))
+	}
+
+	return rows.Err()
+}
+
+func (db *DBSchema) loadViews(q queryer, f *TableFilter, l *lenience) error {
+
+	rows, err := q.Query(fmt.Sprintf("show full tables from %s where Table_Type = 'VIEW'", mysqlQuote(db.Name)))
+	if err != nil {
+		return fmt.Errorf("showing views, %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		tbl := Table{}
-		if err := rows.Scan(&tbl.Name, new(sql.RawBytes)); err != nil {
+		view := View{}
+		if err := rows.Scan(&view.Name, new(sql.RawBytes)); err != nil {
 			return err
 		}
-		if err := tbl.load(q); err != nil {
-			return fmt.Errorf("loading table %q, %v", tbl.Name, err)
+		if !f.Match(view.Name) {
+			continue
 		}
-		db.Tables = append(db.Tables, tbl)
+		if err := view.load(q, db.Name, l); err != nil {
+			if l == nil {
+				return fmt.Errorf("loading view %q, %w", view.Name, err)
+			}
+			l.warnTable(view.Name, err)
+			continue
+		}
+		db.Views = append(db.Views, view)
 	}
 
 	return rows.Err()
@@ -85,7 +494,11 @@ func (db *DBSchema) loadTables(q queryer) error {
 
 func (db DBSchema) String() string {
 	buf := bytes.NewBuffer(nil)
-	fmt.Fprintf(buf, "db %q, %d variables, %d tables\n", db.Name, len(db.Variables), len(db.Tables))
+	var fks int
+	for _, tbl := range db.Tables {
+		fks += len(tbl.ForeignKeys)
+	}
+	fmt.Fprintf(buf, "db %q, %d variables, %d tables, %d views, %d routines, %d foreign keys\n", db.Name, len(db.Variables), len(db.Tables), len(db.Views), len(db.Routines), fks)
 
 	w := tabwriter.NewWriter(buf, 8, 8, 0, ' ', 0)
 
@@ -99,6 +512,12 @@ func (db DBSchema) String() string {
 	for i, tbl := range db.Tables {
 		fmt.Fprintf(buf, "\t%d: %s\n", i, tbl.String())
 	}
+	for i, view := range db.Views {
+		fmt.Fprintf(buf, "\t%d: %s\n", i, view.String())
+	}
+	for i, r := range db.Routines {
+		fmt.Fprintf(buf, "\t%d: %s\n", i, r.String())
+	}
 	return buf.String()
 }
 
@@ -107,9 +526,9 @@ Variables!
 */
 
 type Variable struct {
-	Name  string
-	Type  SQLType
-	Value interface{}
+	Name  string      `json:"name"`
+	Type  SQLType     `json:"type"`
+	Value interface{} `json:"value,omitempty"`
 }
 
 func (v *Variable) scan(rows *sql.Rows) error {
@@ -142,58 +561,100 @@ Tables!
 */
 
 type Table struct {
-	Name string
+	Name    string `json:"name"`
+	Comment string `json:"comment,omitempty"` // Set with the table's COMMENT clause.
 
-	Pk *Index
+	Pk *Index `json:"pk,omitempty"`
 
-	Columns []Column
-	Indices []Index
-
-	// add more stuff like keys
+	Columns     []Column     `json:"columns,omitempty"` // In the order of the table.
+	Indices     []Index      `json:"indices,omitempty"`
+	ForeignKeys []ForeignKey `json:"foreign_keys,omitempty"`
+	Constraints []Constraint `json:"constraints,omitempty"`
+	Triggers    []Trigger    `json:"triggers,omitempty"`
 }
 
+// Has returns the column named colname, or nil if the table has no
+// such column.
 func (tbl *Table) Has(colname string) *Column {
-	data := tbl.Columns
-	i := sort.Search(len(data), func(i int) bool {
-		return data[i].Name == colname
-	})
-	ok := i < len(data) && data[i].Name == colname
-	if !ok {
-		return nil
+	// columns are in the order of the table, not sorted by name
+	for i := range tbl.Columns {
+		if tbl.Columns[i].Name == colname {
+			return &tbl.Columns[i]
+		}
 	}
-	return &data[i]
+	return nil
 }
 
-func (tbl *Table) load(q queryer) error {
-	if err := tbl.loadColumns(q); err != nil {
-		return err
+// InPk tells if the column named colname is part of the primary key.
+func (tbl *Table) InPk(colname string) bool {
+	if tbl.Pk == nil {
+		return false
 	}
+	for _, col := range tbl.Pk.Columns {
+		if col.Name == colname {
+			return true
+		}
+	}
+	return false
+}
 
-	sort.Sort(columnsByName(tbl.Columns))
+func (tbl *Table) load(q queryer, schema string, l *lenience) error {
+	if err := tbl.loadColumns(q, schema, l); err != nil {
+		return err
+	}
 
-	if err := tbl.loadIndices(q); err != nil {
+	if err := tbl.loadIndices(q, schema); err != nil {
 		return err
 	}
 
 	sort.Sort(indexByKeyName(tbl.Indices))
 
-	return nil
+	tbl.collectUniqueConstraints()
+
+	if err := tbl.loadForeignKeys(q, schema); err != nil {
+		return err
+	}
+
+	sort.Sort(foreignKeysByName(tbl.ForeignKeys))
+
+	return tbl.loadTriggers(q, schema)
+}
+
+// TriggersOn returns the triggers fired by `event` (INSERT, UPDATE or
+// DELETE), in the order they fire.
+func (tbl *Table) TriggersOn(event string) []Trigger {
+	var trgs []Trigger
+	for _, timing := range []string{"BEFORE", "INSTEAD OF", "AFTER"} {
+		for _, trg := range tbl.Triggers {
+			if strings.EqualFold(trg.Event, event) && strings.EqualFold(trg.Timing, timing) {
+				trgs = append(trgs, trg)
+			}
+		}
+	}
+	return trgs
 }
 
-func (tbl *Table) loadColumns(q queryer) error {
+// loadColumns keeps the columns that can't be understood, degraded,
+// when l is lenient.
+func (tbl *Table) loadColumns(q queryer, schema string, l *lenience) error {
 	// sprintf'ing queries, because yolo (because prepared stmts dont
 	// work for DDL)
-	rows, err := q.Query(fmt.Sprintf("describe %s", tbl.Name))
+	rows, err := q.Query(fmt.Sprintf("describe %s.%s", mysqlQuote(schema), mysqlQuote(tbl.Name)))
 	if err != nil {
-		return fmt.Errorf("describing table %q, %v", tbl.Name, err)
+		return fmt.Errorf("describing table %q, %w", tbl.Name, err)
 	}
 	defer rows.Close()
 
 	for i := 0; rows.Next(); i++ {
 		col := Column{}
-		err := col.scan(rows)
+		typeName, nullable, err := col.scan(rows)
 		if err != nil {
-			return fmt.Errorf("scanning column %d, %v", i, err)
+			return fmt.Errorf("scanning column %d, %w", i, err)
+		}
+		if err := col.parse(typeName, nullable); err != nil {
+			if err := l.warnColumn(tbl.Name, col.Name, err); err != nil {
+				return err
+			}
 		}
 		tbl.Columns = append(tbl.Columns, col)
 	}
@@ -201,12 +662,12 @@ func (tbl *Table) loadColumns(q queryer) error {
 	return rows.Err()
 }
 
-func (tbl *Table) loadIndices(q queryer) error {
+func (tbl *Table) loadIndices(q queryer, schema string) error {
 	// sprintf'ing queries, because yolo (because prepared stmts dont
 	// work for DDL)
-	rows, err := q.Query(fmt.Sprintf("show indexes in %s", tbl.Name))
+	rows, err := q.Query(fmt.Sprintf("show indexes in %s.%s", mysqlQuote(schema), mysqlQuote(tbl.Name)))
 	if err != nil {
-		return fmt.Errorf("showing indices %q, %v", tbl.Name, err)
+		return fmt.Errorf("showing indices %q, %w", tbl.Name, err)
 	}
 	defer rows.Close()
 
@@ -215,7 +676,7 @@ func (tbl *Table) loadIndices(q queryer) error {
 		idx := IndexPart{}
 		err := idx.scan(tbl, rows)
 		if err != nil {
-			return fmt.Errorf("scanning index %d, %v", i, err)
+			return fmt.Errorf("scanning index %d, %w", i, err)
 		}
 		parts = append(parts, idx)
 	}
@@ -224,9 +685,73 @@ func (tbl *Table) loadIndices(q queryer) error {
 	return rows.Err()
 }
 
+func (tbl *Table) loadForeignKeys(q queryer, schema string) error {
+	rows, err := q.Query(`
+SELECT k.CONSTRAINT_NAME, k.COLUMN_NAME,
+       k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME,
+       r.DELETE_RULE, r.UPDATE_RULE
+FROM information_schema.KEY_COLUMN_USAGE k
+JOIN information_schema.REFERENTIAL_CONSTRAINTS r
+  ON  r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA
+  AND r.CONSTRAINT_NAME   = k.CONSTRAINT_NAME
+  AND r.TABLE_NAME        = k.TABLE_NAME
+WHERE k.TABLE_SCHEMA = ? AND k.TABLE_NAME = ?
+ORDER BY k.CONSTRAINT_NAME, k.ORDINAL_POSITION`, schema, tbl.Name)
+	if err != nil {
+		return fmt.Errorf("showing foreign keys %q, %w", tbl.Name, err)
+	}
+	defer rows.Close()
+
+	var parts []foreignKeyPart
+	for i := 0; rows.Next(); i++ {
+		part := foreignKeyPart{}
+		err := rows.Scan(
+			&part.Name,
+			&part.Column,
+			&part.RefTable,
+			&part.RefColumn,
+			&part.OnDelete,
+			&part.OnUpdate,
+		)
+		if err != nil {
+			return fmt.Errorf("scanning foreign key %d, %w", i, err)
+		}
+		parts = append(parts, part)
+	}
+	tbl.ForeignKeys = foreignKeysFromParts(parts)
+
+	return rows.Err()
+}
+
+func (tbl *Table) loadTriggers(q queryer, schema string) error {
+	rows, err := q.Query(`
+SELECT TRIGGER_NAME, ACTION_TIMING, EVENT_MANIPULATION, ACTION_STATEMENT
+FROM information_schema.TRIGGERS
+WHERE EVENT_OBJECT_SCHEMA = ? AND EVENT_OBJECT_TABLE = ?
+ORDER BY ACTION_TIMING DESC, EVENT_MANIPULATION, ACTION_ORDER`, schema, tbl.Name)
+	if err != nil {
+		return fmt.Errorf("showing triggers %q, %w", tbl.Name, err)
+	}
+	defer rows.Close()
+
+	for i := 0; rows.Next(); i++ {
+		trg := Trigger{Table: tbl.Name}
+		err := rows.Scan(&trg.Name, &trg.Timing, &trg.Event, &trg.Body)
+		if err != nil {
+			return fmt.Errorf("scanning trigger %d, %w", i, err)
+		}
+		tbl.Triggers = append(tbl.Triggers, trg)
+	}
+
+	return rows.Err()
+}
+
 func (tbl Table) String() string {
 	buf := bytes.NewBuffer(nil)
-	fmt.Fprintf(buf, "\ttable %q, %d columns\n", tbl.Name, len(tbl.Columns))
+	fmt.Fprintf(buf, "\ttable %q, %d columns, %d foreign keys\n", tbl.Name, len(tbl.Columns), len(tbl.ForeignKeys))
+	if tbl.Comment != "" {
+		fmt.Fprintf(buf, "\t// %s\n", tbl.Comment)
+	}
 
 	w := tabwriter.NewWriter(buf, 8, 8, 0, ' ', 0)
 
@@ -244,6 +769,87 @@ func (tbl Table) String() string {
 	fmt.Fprintf(w, "\t)\n")
 	w.Flush()
 
+	fmt.Fprintf(w, "\tvar (\n")
+	for _, fk := range tbl.ForeignKeys {
+		fmt.Fprintf(w, "\t\t%s\n", fk.String())
+	}
+	fmt.Fprintf(w, "\t)\n")
+	w.Flush()
+
+	fmt.Fprintf(w, "\tvar (\n")
+	for _, c := range tbl.Constraints {
+		fmt.Fprintf(w, "\t\t%s\n", c.String())
+	}
+	fmt.Fprintf(w, "\t)\n")
+	w.Flush()
+
+	fmt.Fprintf(w, "\tvar (\n")
+	for _, trg := range tbl.Triggers {
+		fmt.Fprintf(w, "\t\t%s\n", trg.String())
+	}
+	fmt.Fprintf(w, "\t)\n")
+	w.Flush()
+
+	return buf.String()
+}
+
+/*
+Views!
+*/
+
+type View struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition,omitempty"` // The SELECT statement of the view.
+
+	Columns []Column `json:"columns,omitempty"`
+}
+
+func (view *View) load(q queryer, schema string, l *lenience) error {
+	// views are described like tables, but keep the order of their
+	// SELECT
+	tbl := Table{Name: view.Name}
+	if err := tbl.loadColumns(q, schema, l); err != nil {
+		return err
+	}
+	view.Columns = tbl.Columns
+
+	return view.loadDefinition(q, schema)
+}
+
+func (view *View) loadDefinition(q queryer, schema string) error {
+	rows, err := q.Query(`
+SELECT VIEW_DEFINITION
+FROM information_schema.VIEWS
+WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?`, schema, view.Name)
+	if err != nil {
+		return fmt.Errorf("showing definition of view %q, %w", view.Name, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := rows.Scan(&view.Definition); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (view View) String() string {
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "\tview %q, %d columns\n", view.Name, len(view.Columns))
+
+	w := tabwriter.NewWriter(buf, 8, 8, 0, ' ', 0)
+
+	fmt.Fprintf(w, "\tvar (\n")
+	for _, col := range view.Columns {
+		fmt.Fprintf(w, "\t\t%s\n", col.String())
+	}
+	fmt.Fprintf(w, "\t)\n")
+	w.Flush()
+
+	fmt.Fprintf(buf, "\tas %s\n", view.Definition)
+
 	return buf.String()
 }
 
@@ -252,19 +858,27 @@ Columns!
 */
 
 type Column struct {
-	Name     string
-	Type     SQLType
-	Nullable bool
+	Name      string   `json:"name"`
+	Type      SQLType  `json:"type"`
+	TypeName  string   `json:"type_name,omitempty"` // The type as declared, like `int(10) unsigned`.
+	Nullable  bool     `json:"nullable,omitempty"`
+	Length    int      `json:"length,omitempty"`    // Maximum length of char, varchar, binary and varbinary columns, display width of integer columns, 0 otherwise.
+	Size      int      `json:"size,omitempty"`      // Bytes used to store integer columns, 0 if unknown.
+	Precision int      `json:"precision,omitempty"` // Total number of digits of decimal and floating point columns, 0 if unspecified.
+	Scale     int      `json:"scale,omitempty"`     // Number of digits after the decimal point of decimal columns.
+	Unsigned  bool     `json:"unsigned,omitempty"`  // Whether numeric columns are unsigned.
+	Comment   string   `json:"comment,omitempty"`   // Set with the column's COMMENT clause.
+	Values    []string `json:"values,omitempty"`    // The values allowed in enum and set columns.
 	// add more stuff like `key` and `extra`
-	Key     interface{}
-	Default interface{}
-	Extra   interface{}
+	Key     interface{} `json:"key,omitempty"`
+	Default interface{} `json:"default,omitempty"`
+	Extra   interface{} `json:"extra,omitempty"`
 }
 
-func (col *Column) scan(rows *sql.Rows) error {
-	var typeName string
-	var nullable string
-	err := rows.Scan(
+// scan reads a row of `describe`, returning the type and nullability
+// for parse.
+func (col *Column) scan(rows *sql.Rows) (typeName, nullable string, err error) {
+	err = rows.Scan(
 		&col.Name,
 		&typeName,
 		&nullable,
@@ -272,18 +886,69 @@ func (col *Column) scan(rows *sql.Rows) error {
 		&col.Default,
 		&col.Extra,
 	)
-	if err != nil {
-		return err
-	}
+	return typeName, nullable, err
+}
+
+// parse sets the details of col from its type and nullability, as
+// `describe` and information_schema.COLUMNS report them. A column of an
+// unknown type is left as bytes, and an invalid default as none, along
+// with the error.
+func (col *Column) parse(typeName, nullable string) error {
+	var err error
 	col.Nullable = ("YES" == nullable)
+	col.TypeName = typeName
 	col.Type, err = parseSQLTypeName(typeName)
 	if err != nil {
+		col.Type = SQLBytes
 		return err
 	}
-	if def, ok := col.Default.([]byte); ok {
+	col.parseTypeDetails(typeName)
+	if col.Type == SQLEnum || col.Type == SQLSet {
+		col.Values = parseEnumValues(typeName)
+	}
+	def, ok := col.Default.([]byte)
+	now, isNow := currentTimestamp(def)
+	switch {
+	case !ok:
+	case isNow && col.Type == SQLTime:
+		// spelled like in DDL, and flagged as an expression like MySQL 8
+		// does, unlike MySQL 5.7 and MariaDB
+		col.Default = now
+		if !col.ParseExtra().DefaultGenerated {
+			col.Extra = []byte(strings.TrimSpace(bytesText(col.Extra) + " DEFAULT_GENERATED"))
+		}
+	case col.ParseExtra().DefaultGenerated:
+		// an expression, kept as its text like in DDL
+		col.Default = string(def)
+	case col.Type == SQLInteger && col.Unsigned:
+		// might not fit in an int64
+		col.Default, err = strconv.ParseUint(string(def), 10, 64)
+	default:
 		col.Default, err = col.Type.ParseBytes(def)
 	}
-	return err
+	if err != nil {
+		col.Default = nil
+		return fmt.Errorf("parsing default %q, %w", def, err)
+	}
+	return nil
+}
+
+// currentTimestamp spells defaults like `CURRENT_TIMESTAMP`, `now()` or
+// MariaDB's `current_timestamp(3)` like DDL does, if def is one.
+func currentTimestamp(def []byte) (string, bool) {
+	name, args := string(def), ""
+	if i := strings.IndexByte(name, '('); i > 0 {
+		name, args = name[:i], name[i:]
+	}
+	switch strings.ToLower(name) {
+	case "current_timestamp", "now", "localtime", "localtimestamp":
+	default:
+		return "", false
+	}
+	if args == "()" {
+		args = ""
+	}
+	return "CURRENT_TIMESTAMP" + args, true
 }
 
 func (col Column) String() string {
@@ -293,7 +958,22 @@ func (col Column) String() string {
 	if col.Nullable {
 		fmt.Fprintf(buf, "*")
 	}
+	if col.Unsigned {
+		fmt.Fprintf(buf, "unsigned ")
+	}
 	fmt.Fprintf(buf, "%s", col.Type)
+	switch {
+	case col.Precision != 0:
+		fmt.Fprintf(buf, "(%d,%d)", col.Precision, col.Scale)
+	case col.Length != 0:
+		fmt.Fprintf(buf, "(%d)", col.Length)
+	}
+	if col.Size != 0 {
+		fmt.Fprintf(buf, "[%d bytes]", col.Size)
+	}
+	if len(col.Values) != 0 {
+		fmt.Fprintf(buf, "%q", col.Values)
+	}
 
 	if col.Default != nil {
 		fmt.Fprintf(buf, " = %#v", col.Default)
@@ -308,11 +988,81 @@ func (col Column) String() string {
 			fmt.Fprintf(buf, "\t//")
 		}
 		fmt.Fprintf(buf, " extra %q", string(extra))
+		keyed = true
+	}
+	if col.Comment != "" {
+		if !keyed {
+			fmt.Fprintf(buf, "\t//")
+		}
+		fmt.Fprintf(buf, " %s", col.Comment)
 	}
 
 	return buf.String()
 }
 
+// Generated is how the database computes a generated column.
+type Generated int
+
+const (
+	startGenerated Generated = iota
+
+	GeneratedVirtual // Computed when read.
+	GeneratedStored  // Computed when written, and stored.
+
+	stopGenerated
+)
+
+// ColumnExtra is what the Extra of a column tells, as `describe`
+// reports it, like `auto_increment` or `DEFAULT_GENERATED on update
+// CURRENT_TIMESTAMP`.
+type ColumnExtra struct {
+	AutoIncrement            bool
+	Generated                Generated // Zero if the column isn't generated.
+	DefaultGenerated         bool      // The default is an expression, like CURRENT_TIMESTAMP.
+	OnUpdateCurrentTimestamp bool
+}
+
+// ParseExtra reads the flags of col.Extra, ignoring those it doesn't
+// know, like INVISIBLE.
+func (col Column) ParseExtra() ColumnExtra {
+	var extra ColumnExtra
+	words := strings.Fields(bytesText(col.Extra))
+	for i := 0; i < len(words); i++ {
+		switch strings.ToUpper(words[i]) {
+		case "AUTO_INCREMENT":
+			extra.AutoIncrement = true
+		case "VIRTUAL":
+			extra.Generated = GeneratedVirtual
+		case "STORED":
+			extra.Generated = GeneratedStored
+		case "DEFAULT_GENERATED":
+			extra.DefaultGenerated = true
+		case "ON":
+			// on update CURRENT_TIMESTAMP, maybe with a precision
+			if i+2 < len(words) && strings.EqualFold(words[i+1], "update") &&
+				strings.HasPrefix(strings.ToUpper(words[i+2]), "CURRENT_TIMESTAMP") {
+				extra.OnUpdateCurrentTimestamp = true
+				i += 2
+			}
+		}
+	}
+	return extra
+}
+
+// SetOnInsert tells if an INSERT should set the column, rather than
+// leave it to the database to assign.
+func (col Column) SetOnInsert() bool {
+	extra := col.ParseExtra()
+	return !extra.AutoIncrement && extra.Generated == 0 && !extra.DefaultGenerated
+}
+
+// SetOnUpdate tells if an UPDATE should set the column, rather than
+// leave it to the database to assign.
+func (col Column) SetOnUpdate() bool {
+	extra := col.ParseExtra()
+	return !extra.AutoIncrement && extra.Generated == 0 && !extra.OnUpdateCurrentTimestamp
+}
+
 /*
 Indices!
 */
@@ -331,17 +1081,17 @@ const (
 )
 
 type Index struct {
-	KeyName      string
-	NonUnique    bool      // 0 if the index cannot contain duplicates, 1 if it can.
-	Cardinality  int       // An estimate of the number of unique values in the index. This is updated by running ANALYZE TABLE or myisamchk -a. Cardinality is counted based on statistics stored as integers, so the value is not necessarily exact even for small tables. The higher the cardinality, the greater the chance that MySQL uses the index when doing joins.
-	SubPart      *int      // The number of indexed characters if the column is only partly indexed, NULL if the entire column is indexed.
-	Packed       *string   // Indicates how the key is packed. NULL if it is not.
-	IndexType    IndexType // The index method used (BTREE, FULLTEXT, HASH, RTREE).
-	Comment      string    // Information about the index not described in its own column, such as disabled if the index is disabled.
-	IndexComment string    // Any comment provided for the index with a COMMENT attribute when the index was created.
-
-	Columns []Column
-	Parts   []IndexPart
+	KeyName      string    `json:"key_name,omitempty"`
+	NonUnique    bool      `json:"non_unique,omitempty"`    // 0 if the index cannot contain duplicates, 1 if it can.
+	Cardinality  int       `json:"cardinality,omitempty"`   // An estimate of the number of unique values in the index. This is updated by running ANALYZE TABLE or myisamchk -a. Cardinality is counted based on statistics stored as integers, so the value is not necessarily exact even for small tables. The higher the cardinality, the greater the chance that MySQL uses the index when doing joins.
+	SubPart      *int      `json:"sub_part,omitempty"`      // The number of indexed characters if the column is only partly indexed, NULL if the entire column is indexed.
+	Packed       *string   `json:"packed,omitempty"`        // Indicates how the key is packed. NULL if it is not.
+	IndexType    IndexType `json:"index_type,omitempty"`    // The index method used (BTREE, FULLTEXT, HASH, RTREE).
+	Comment      string    `json:"comment,omitempty"`       // Information about the index not described in its own column, such as disabled if the index is disabled.
+	IndexComment string    `json:"index_comment,omitempty"` // Any comment provided for the index with a COMMENT attribute when the index was created.
+
+	Columns []Column    `json:"columns,omitempty"`
+	Parts   []IndexPart `json:"parts,omitempty"`
 }
 
 func (idx Index) IsPrimary() bool { return idx.KeyName == "PRIMARY" }
@@ -369,48 +1119,43 @@ func indicesFromParts(parts []IndexPart) (pk *Index, indices []Index) {
 		idx.Columns = append(idx.Columns, part.Column)
 		indexSet[idx.KeyName] = idx
 	}
-	var pkname string
+	// the set is keyed by name, so there's at most one primary key
 	for _, idx := range indexSet {
 		if idx.IsPrimary() {
-			if pkname == "" {
-				pkname = idx.KeyName
-				continue
-			} else {
-				panic("multiple primary keys")
-			}
+			primary := idx
+			pk = &primary
+			continue
 		}
 		indices = append(indices, idx)
 	}
-	if pkname != "" {
-		idx := indexSet[pkname]
-		pk = &idx
-	}
 
 	return
 }
 
 type IndexPart struct {
-	Column Column
-
-	Table        string    // The name of the table.
-	NonUnique    bool      // 0 if the index cannot contain duplicates, 1 if it can.
-	KeyName      string    // The name of the index. If the index is the primary key, the name is always PRIMARY.
-	SeqInIndex   int       // The column sequence number in the index, starting with 1.
-	ColumnName   string    // The column name.
-	IsAscending  bool      // How the column is sorted in the index. In MySQL, this can have values “A” (Ascending) or NULL (Not sorted).
-	Cardinality  int       // An estimate of the number of unique values in the index. This is updated by running ANALYZE TABLE or myisamchk -a. Cardinality is counted based on statistics stored as integers, so the value is not necessarily exact even for small tables. The higher the cardinality, the greater the chance that MySQL uses the index when doing joins.
-	SubPart      *int      // The number of indexed characters if the column is only partly indexed, NULL if the entire column is indexed.
-	Packed       *string   // Indicates how the key is packed. NULL if it is not.
-	CanBeNull    bool      // Contains YES if the column may contain NULL values and '' if not.
-	IndexType    IndexType // The index method used (BTREE, FULLTEXT, HASH, RTREE).
-	Comment      string    // Information about the index not described in its own column, such as disabled if the index is disabled.
-	IndexComment string    // Any comment provided for the index with a COMMENT attribute when the index was created.
+	Column Column `json:"column"`
+
+	Table        string    `json:"table,omitempty"`         // The name of the table.
+	NonUnique    bool      `json:"non_unique,omitempty"`    // 0 if the index cannot contain duplicates, 1 if it can.
+	KeyName      string    `json:"key_name,omitempty"`      // The name of the index. If the index is the primary key, the name is always PRIMARY.
+	SeqInIndex   int       `json:"seq_in_index,omitempty"`  // The column sequence number in the index, starting with 1.
+	ColumnName   string    `json:"column_name,omitempty"`   // The column name.
+	IsAscending  bool      `json:"is_ascending,omitempty"`  // How the column is sorted in the index. In MySQL, this can have values “A” (Ascending) or NULL (Not sorted).
+	Cardinality  int       `json:"cardinality,omitempty"`   // An estimate of the number of unique values in the index. This is updated by running ANALYZE TABLE or myisamchk -a. Cardinality is counted based on statistics stored as integers, so the value is not necessarily exact even for small tables. The higher the cardinality, the greater the chance that MySQL uses the index when doing joins.
+	SubPart      *int      `json:"sub_part,omitempty"`      // The number of indexed characters if the column is only partly indexed, NULL if the entire column is indexed.
+	Packed       *string   `json:"packed,omitempty"`        // Indicates how the key is packed. NULL if it is not.
+	CanBeNull    bool      `json:"can_be_null,omitempty"`   // Contains YES if the column may contain NULL values and '' if not.
+	IndexType    IndexType `json:"index_type,omitempty"`    // The index method used (BTREE, FULLTEXT, HASH, RTREE).
+	Comment      string    `json:"comment,omitempty"`       // Information about the index not described in its own column, such as disabled if the index is disabled.
+	IndexComment string    `json:"index_comment,omitempty"` // Any comment provided for the index with a COMMENT attribute when the index was created.
 }
 
 func (idx *IndexPart) String() string {
 	return idx.ColumnName
 }
 
+// scan reads a row of `show indexes`. The part is bound to its column
+// in tbl, unless tbl is nil because the row tells the table.
 func (idx *IndexPart) scan(tbl *Table, rows *sql.Rows) error {
 	var (
 		nonUnique int
@@ -448,6 +1193,14 @@ func (idx *IndexPart) scan(tbl *Table, rows *sql.Rows) error {
 		idx.IndexType = IndexRtree
 	}
 
+	if err != nil || tbl == nil {
+		return err
+	}
+	return idx.bindColumn(tbl)
+}
+
+// bindColumn finds the column of tbl that this part indexes.
+func (idx *IndexPart) bindColumn(tbl *Table) error {
 	for _, col := range tbl.Columns {
 		if col.Name == idx.ColumnName {
 			idx.Column = col
@@ -456,34 +1209,65 @@ func (idx *IndexPart) scan(tbl *Table, rows *sql.Rows) error {
 				idx.Column.Nullable = false
 			}
 
-			return err
+			return nil
 		}
 	}
 
 	return fmt.Errorf("column %q doesn't exist for index %q", idx.ColumnName, idx.KeyName)
 }
 
-type columnsByName []Column
+/*
+Foreign keys!
+*/
 
-func (b columnsByName) Len() int      { return len(b) }
-func (b columnsByName) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
-func (b columnsByName) Less(i, j int) bool {
-	iname := b[i].Name
-	jname := b[j].Name
+type ForeignKey struct {
+	Name       string   `json:"name"`                  // The name of the constraint.
+	Columns    []string `json:"columns,omitempty"`     // The columns of the table holding the key, in order.
+	RefTable   string   `json:"ref_table,omitempty"`   // The table being referenced.
+	RefColumns []string `json:"ref_columns,omitempty"` // The columns being referenced, matching Columns.
+	OnDelete   string   `json:"on_delete,omitempty"`   // The ON DELETE rule: CASCADE, SET NULL, SET DEFAULT, RESTRICT or NO ACTION.
+	OnUpdate   string   `json:"on_update,omitempty"`   // The ON UPDATE rule: CASCADE, SET NULL, SET DEFAULT, RESTRICT or NO ACTION.
+}
 
-	if iname == "id" {
-		return true
-	}
-	if jname == "id" {
-		return false
-	}
-	switch {
-	case strings.Contains(iname, "_id") && !strings.Contains(jname, "_id"):
-		return true
-	case !strings.Contains(iname, "_id") && strings.Contains(jname, "_id"):
-		return false
+func (fk ForeignKey) String() string {
+	return fmt.Sprintf("%q (%s) \t-> %s (%s) \ton delete %s on update %s",
+		fk.Name,
+		strings.Join(fk.Columns, ", "),
+		fk.RefTable,
+		strings.Join(fk.RefColumns, ", "),
+		fk.OnDelete,
+		fk.OnUpdate,
+	)
+}
+
+// foreignKeyPart is one column of a foreign key, as found in
+// information_schema.KEY_COLUMN_USAGE.
+type foreignKeyPart struct {
+	Name      string
+	Column    string
+	RefTable  string
+	RefColumn string
+	OnDelete  string
+	OnUpdate  string
+}
+
+// foreignKeysFromParts groups the parts of each key, which must be
+// ordered by constraint name, then by position in the key.
+func foreignKeysFromParts(parts []foreignKeyPart) (fks []ForeignKey) {
+	for _, part := range parts {
+		if n := len(fks); n == 0 || fks[n-1].Name != part.Name {
+			fks = append(fks, ForeignKey{
+				Name:     part.Name,
+				RefTable: part.RefTable,
+				OnDelete: part.OnDelete,
+				OnUpdate: part.OnUpdate,
+			})
+		}
+		fk := &fks[len(fks)-1]
+		fk.Columns = append(fk.Columns, part.Column)
+		fk.RefColumns = append(fk.RefColumns, part.RefColumn)
 	}
-	return iname < jname
+	return
 }
 
 type indexByKeyName []Index
@@ -491,3 +1275,9 @@ type indexByKeyName []Index
 func (b indexByKeyName) Len() int           { return len(b) }
 func (b indexByKeyName) Less(i, j int) bool { return b[i].KeyName < b[j].KeyName }
 func (b indexByKeyName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+type foreignKeysByName []ForeignKey
+
+func (b foreignKeysByName) Len() int           { return len(b) }
+func (b foreignKeysByName) Less(i, j int) bool { return b[i].Name < b[j].Name }
+func (b foreignKeysByName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
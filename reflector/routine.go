@@ -0,0 +1,179 @@
+package reflector
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+/*
+Routines!
+*/
+
+type Routine struct {
+	Name    string  `json:"name"`
+	Type    string  `json:"type"`              // PROCEDURE or FUNCTION.
+	Params  []Param `json:"params,omitempty"`  // In the order they're declared.
+	Returns *Column `json:"returns,omitempty"` // The return type of functions, nil for procedures.
+	Body    string  `json:"body,omitempty"`
+	Comment string  `json:"comment,omitempty"`
+}
+
+// Param is a parameter of a routine. Its Column describes its name and
+// type.
+type Param struct {
+	Column
+	Mode string `json:"mode,omitempty"` // IN, OUT or INOUT.
+}
+
+// IsFunction tells if the routine is a function, called in
+// expressions, rather than a procedure.
+func (r Routine) IsFunction() bool {
+	return r.Type == "FUNCTION"
+}
+
+// Outs returns the OUT and INOUT parameters of a procedure.
+func (r Routine) Outs() []Param {
+	var outs []Param
+	for _, p := range r.Params {
+		if p.Mode != "IN" {
+			outs = append(outs, p)
+		}
+	}
+	return outs
+}
+
+func (r Routine) String() string {
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "%s %q(", strings.ToLower(r.Type), r.Name)
+	for i, p := range r.Params {
+		if i != 0 {
+			fmt.Fprintf(buf, ", ")
+		}
+		fmt.Fprintf(buf, "%s %s %s", p.Mode, p.Name, p.Type)
+	}
+	fmt.Fprintf(buf, ")")
+	if r.Returns != nil {
+		fmt.Fprintf(buf, " %s", r.Returns.Type)
+	}
+	return buf.String()
+}
+
+// loadRoutines loads the stored procedures and functions, then their
+// parameters, all at once. A lenient l leaves out the routines whose
+// parameters can't be understood.
+func (db *DBSchema) loadRoutines(q queryer, l *lenience) error {
+	rows, err := q.Query(`
+SELECT ROUTINE_NAME, ROUTINE_TYPE, COALESCE(ROUTINE_DEFINITION, ''), ROUTINE_COMMENT
+FROM information_schema.ROUTINES
+WHERE ROUTINE_SCHEMA = ?
+ORDER BY ROUTINE_NAME, ROUTINE_TYPE`, db.Name)
+	if err != nil {
+		return fmt.Errorf("showing routines, %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r := Routine{}
+		if err := rows.Scan(&r.Name, &r.Type, &r.Body, &r.Comment); err != nil {
+			return err
+		}
+		db.Routines = append(db.Routines, r)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = q.Query(`
+SELECT SPECIFIC_NAME, ROUTINE_TYPE, COALESCE(PARAMETER_MODE, ''), COALESCE(PARAMETER_NAME, ''), DTD_IDENTIFIER
+FROM information_schema.PARAMETERS
+WHERE SPECIFIC_SCHEMA = ?
+ORDER BY SPECIFIC_NAME, ROUTINE_TYPE, ORDINAL_POSITION`, db.Name)
+	if err != nil {
+		return fmt.Errorf("showing routine parameters, %w", err)
+	}
+	defer rows.Close()
+
+	broken := make(map[*Routine]bool)
+	for i := 0; rows.Next(); i++ {
+		var (
+			name, typ, dtd string
+			p              Param
+		)
+		if err := rows.Scan(&name, &typ, &p.Mode, &p.Name, &dtd); err != nil {
+			return fmt.Errorf("scanning parameter %d, %w", i, err)
+		}
+		r := db.routine(name, typ)
+		if r == nil || broken[r] {
+			continue
+		}
+		if err := p.parseType(dtd); err != nil {
+			err = fmt.Errorf("scanning parameter %d of %q, %w", i, name, err)
+			if err := l.warnRoutine(name, err); err != nil {
+				return err
+			}
+			broken[r] = true
+			continue
+		}
+		if p.Mode == "" {
+			// position 0, the return value of a function
+			ret := p.Column
+			r.Returns = &ret
+			continue
+		}
+		r.Params = append(r.Params, p)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	routines := db.Routines[:0]
+	for i := range db.Routines {
+		if !broken[&db.Routines[i]] {
+			routines = append(routines, db.Routines[i])
+		}
+	}
+	db.Routines = routines
+	return nil
+}
+
+func (db *DBSchema) routine(name, typ string) *Routine {
+	for i := range db.Routines {
+		if db.Routines[i].Name == name && db.Routines[i].Type == typ {
+			return &db.Routines[i]
+		}
+	}
+	return nil
+}
+
+// parseType sets the type of p from its declaration, like
+// `varchar(20)` or `decimal(10,2) unsigned`.
+func (p *Param) parseType(dtd string) error {
+	dtd = stripCharset(dtd)
+
+	var err error
+	p.TypeName = dtd
+	p.Type, err = parseSQLTypeName(dtd)
+	if err != nil {
+		return err
+	}
+	p.parseTypeDetails(dtd)
+	if p.Type == SQLEnum || p.Type == SQLSet {
+		p.Values = parseEnumValues(dtd)
+	}
+	// routines take and return NULL like any value
+	p.Nullable = true
+	return nil
+}
+
+// stripCharset removes the CHARSET and COLLATE clauses that some
+// versions of MySQL put in the types of parameters.
+func stripCharset(dtd string) string {
+	lower := strings.ToLower(dtd)
+	for _, clause := range []string{" charset ", " character set ", " collate "} {
+		if i := strings.Index(lower, clause); i > 0 {
+			dtd, lower = dtd[:i], lower[:i]
+		}
+	}
+	return strings.TrimSpace(dtd)
+}
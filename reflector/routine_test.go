@@ -0,0 +1,86 @@
+package reflector
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestParamParseType(t *testing.T) {
+	var p Param
+	if err := p.parseType("varchar(20) CHARSET utf8mb4 COLLATE utf8mb4_bin"); err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != SQLString || p.Length != 20 || !p.Nullable {
+		t.Fatalf("got %#v", p)
+	}
+}
+
+func TestLoadRoutines(t *testing.T) {
+	schema := &fakeSchema{
+		// a function and a procedure can have the same name
+		routines: [][]driver.Value{
+			{"add_tax", "FUNCTION", "RETURN amount * 1.2", ""},
+			{"broken", "PROCEDURE", "BEGIN END", ""},
+			{"close_order", "PROCEDURE", "BEGIN END", "Closes an order."},
+			{"stats", "FUNCTION", "RETURN 1", ""},
+			{"stats", "PROCEDURE", "BEGIN END", ""},
+		},
+		// the mode and name of a return value are COALESCEd to ""
+		params: [][]driver.Value{
+			{"add_tax", "FUNCTION", "", "", "decimal(10,2)"},
+			{"add_tax", "FUNCTION", "IN", "amount", "decimal(10,2)"},
+			{"broken", "PROCEDURE", "IN", "x", "widget"},
+			{"close_order", "PROCEDURE", "IN", "order_id", "int(11)"},
+			{"close_order", "PROCEDURE", "OUT", "total", "decimal(10,2)"},
+			{"close_order", "PROCEDURE", "INOUT", "note", "varchar(20) CHARSET utf8mb4"},
+			{"stats", "FUNCTION", "", "", "int"},
+			{"stats", "PROCEDURE", "IN", "since", "date"},
+		},
+	}
+	db, _ := openFakeMySQL(t, schema)
+	defer db.Close()
+
+	if err := (&DBSchema{Name: "fake"}).loadRoutines(db, nil); err == nil {
+		t.Fatal("loaded a routine with an unknown parameter type")
+	}
+
+	got := &DBSchema{Name: "fake"}
+	l := &lenience{}
+	if err := got.loadRoutines(db, l); err != nil {
+		t.Fatal(err)
+	}
+	if w := l.list(); len(w) != 1 || w[0].Routine != "broken" {
+		t.Errorf("warned %v, want broken left out", w)
+	}
+
+	want := []struct {
+		name, typ string
+		params    []string // mode and name
+		returns   SQLType
+	}{
+		{"add_tax", "FUNCTION", []string{"IN amount"}, SQLDecimal},
+		{"close_order", "PROCEDURE", []string{"IN order_id", "OUT total", "INOUT note"}, startSQLType},
+		{"stats", "FUNCTION", nil, SQLInteger},
+		{"stats", "PROCEDURE", []string{"IN since"}, startSQLType},
+	}
+	if len(got.Routines) != len(want) {
+		t.Fatalf("loaded %v, want %d routines", got.Routines, len(want))
+	}
+	for i, w := range want {
+		r := got.Routines[i]
+		var params []string
+		for _, p := range r.Params {
+			params = append(params, p.Mode+" "+p.Name)
+		}
+		if r.Name != w.name || r.Type != w.typ || !reflect.DeepEqual(params, w.params) {
+			t.Errorf("routine %d is %v, want %s %s%v", i, r, w.typ, w.name, w.params)
+		}
+		if (r.Returns == nil) != (w.returns == startSQLType) || r.Returns != nil && r.Returns.Type != w.returns {
+			t.Errorf("%s %s returns %v, want %v", r.Type, r.Name, r.Returns, w.returns)
+		}
+	}
+	if close := got.Routines[1]; close.Comment != "Closes an order." || close.Params[2].Length != 20 {
+		t.Errorf("close_order is %#v", close)
+	}
+}
@@ -20,30 +20,50 @@ const (
 	SQLFloat
 	SQLBool
 	SQLTime
+	SQLEnum
+	SQLSet
+	SQLDecimal
+	SQLJSON
+	SQLPoint
+	SQLGeometry
+	SQLBit
 
 	stopSQLType
 )
 
 func (s SQLType) ParseBytes(b []byte) (interface{}, error) {
 	switch s {
-	case SQLString:
+	case SQLString, SQLEnum:
 		return string(b), nil
-	case SQLBytes:
+	case SQLSet:
+		if len(b) == 0 {
+			return []string{}, nil
+		}
+		return strings.Split(string(b), ","), nil
+	case SQLBytes, SQLJSON, SQLPoint, SQLGeometry:
 		return b, nil
+	case SQLBit:
+		return parseBitLiteral(b)
 	case SQLInteger:
 		return strconv.ParseInt(string(b), 10, 64)
 	case SQLFloat:
 		return strconv.ParseFloat(string(b), 64)
+	case SQLDecimal:
+		// kept as text, floats aren't exact
+		if _, err := strconv.ParseFloat(string(b), 64); err != nil {
+			return nil, err
+		}
+		return string(b), nil
 	case SQLBool:
 		b = bytes.ToLower(b)
-		if bytes.Compare(b, []byte("yes")) != 0 ||
-			bytes.Compare(b, []byte("on")) != 0 ||
-			bytes.Compare(b, []byte("enabled")) != 0 {
+		if bytes.Compare(b, []byte("yes")) == 0 ||
+			bytes.Compare(b, []byte("on")) == 0 ||
+			bytes.Compare(b, []byte("enabled")) == 0 {
 			return true, nil
 		}
-		if bytes.Compare(b, []byte("no")) != 0 ||
-			bytes.Compare(b, []byte("off")) != 0 ||
-			bytes.Compare(b, []byte("disabled")) != 0 {
+		if bytes.Compare(b, []byte("no")) == 0 ||
+			bytes.Compare(b, []byte("off")) == 0 ||
+			bytes.Compare(b, []byte("disabled")) == 0 {
 			return false, nil
 		}
 		return strconv.ParseBool(string(b))
@@ -62,15 +82,12 @@ func (s SQLType) ParseBytes(b []byte) (interface{}, error) {
 		}
 		return nil, fmt.Errorf("invalid time string: %q", string(b))
 	}
-	panic("invalid SQLType: " + s.String())
+	return nil, fmt.Errorf("invalid SQLType: %v", s)
 }
 
 func parseSQLTypeName(name string) (SQLType, error) {
 
-	if l := strings.Index(name, "("); l > 0 && l < len(name) {
-		name = name[:l]
-	}
-	name = strings.ToLower(name)
+	name = baseTypeName(name)
 
 	var (
 		t   SQLType
@@ -78,12 +95,14 @@ func parseSQLTypeName(name string) (SQLType, error) {
 	)
 	switch name {
 
-	case "integer", "serial", "tinybit", "bit",
+	case "integer", "serial", "tinybit",
 		"tinyint", "smallint", "mediumint", "int", "bigint":
 		t = SQLInteger
 
-	case "decimal", "dec", "fixed", "numeric", "float", "double",
-		"double precision":
+	case "decimal", "dec", "fixed", "numeric":
+		t = SQLDecimal
+
+	case "float", "double", "double precision", "real":
 		t = SQLFloat
 
 	case "bool", "boolean":
@@ -100,12 +119,206 @@ func parseSQLTypeName(name string) (SQLType, error) {
 		"tinyblob", "mediumblob", "blob", "longblob":
 		t = SQLBytes
 
+	case "json":
+		t = SQLJSON
+
+	case "point":
+		t = SQLPoint
+
+	case "geometry", "linestring", "polygon", "multipoint",
+		"multilinestring", "multipolygon", "geometrycollection",
+		"geomcollection":
+		t = SQLGeometry
+
+	case "bit":
+		t = SQLBit
+
+	case "enum":
+		t = SQLEnum
+
+	case "set":
+		t = SQLSet
+
 	default:
-		err = fmt.Errorf("unknown type %q", name)
+		err = &UnknownTypeError{TypeName: name}
 	}
 	return t, err
 }
 
+// parseEnumValues returns the values allowed by `enum('a','b')` or
+// `set('a','b')`, or nil if name doesn't list values.
+func parseEnumValues(name string) []string {
+	l := strings.Index(name, "(")
+	r := strings.LastIndex(name, ")")
+	if l < 0 || r < l {
+		return nil
+	}
+	list := name[l+1 : r]
+
+	var (
+		values  []string
+		value   []byte
+		inQuote bool
+	)
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+		switch {
+		case !inQuote && c == '\'':
+			inQuote = true
+			value = []byte{}
+		case inQuote && c == '\'' && i+1 < len(list) && list[i+1] == '\'':
+			// '' is an escaped quote
+			value = append(value, c)
+			i++
+		case inQuote && c == '\\' && i+1 < len(list):
+			value = append(value, list[i+1])
+			i++
+		case inQuote && c == '\'':
+			inQuote = false
+			values = append(values, string(value))
+		case inQuote:
+			value = append(value, c)
+		}
+	}
+	return values
+}
+
+// parseBitLiteral reads the defaults of bit columns, written like
+// `b'101'`.
+func parseBitLiteral(b []byte) (uint64, error) {
+	lit := string(b)
+	if strings.HasPrefix(lit, "b'") && strings.HasSuffix(lit, "'") {
+		lit = lit[2 : len(lit)-1]
+	}
+	return strconv.ParseUint(lit, 2, 64)
+}
+
+// baseTypeName strips the modifiers of a type name, so that
+// `bigint(20) unsigned zerofill` is `bigint`.
+func baseTypeName(name string) string {
+	if l := strings.Index(name, "("); l > 0 && l < len(name) {
+		if r := strings.Index(name[l:], ")"); r > 0 {
+			name = name[:l] + name[l+r+1:]
+		} else {
+			name = name[:l]
+		}
+	}
+	var words []string
+	for _, word := range strings.Fields(strings.ToLower(name)) {
+		switch word {
+		case "unsigned", "signed", "zerofill":
+		default:
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// typeArgs returns the numbers in the parens of types like
+// `decimal(10,2)`, or nil if there are none.
+func typeArgs(name string) []int {
+	l := strings.Index(name, "(")
+	r := strings.Index(name, ")")
+	if l < 1 || r < l {
+		return nil
+	}
+	var args []int
+	for _, arg := range strings.Split(name[l+1:r], ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(arg))
+		if err != nil {
+			return nil
+		}
+		args = append(args, n)
+	}
+	return args
+}
+
+// parseTypeDetails sets the length, precision, scale, size and
+// signedness of col from its type name, like `varchar(255)`,
+// `decimal(10,2)` or `int(10) unsigned`.
+func (col *Column) parseTypeDetails(name string) {
+	base := baseTypeName(name)
+	args := typeArgs(name)
+
+	switch base {
+	case "char", "varchar", "character", "character varying",
+		"nchar", "nvarchar", "binary", "varbinary":
+		if len(args) == 1 {
+			col.Length = args[0]
+		}
+
+	case "bit":
+		// the number of bits, 1 if unspecified
+		col.Length = 1
+		if len(args) == 1 {
+			col.Length = args[0]
+		}
+
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint",
+		"int2", "int4", "int8", "smallserial", "serial", "bigserial":
+		col.Size = integerSize(base)
+		// the display width, as in `tinyint(1)`
+		if len(args) == 1 {
+			col.Length = args[0]
+		}
+
+	case "decimal", "dec", "fixed", "numeric", "float", "double",
+		"double precision", "real":
+		if len(args) > 0 {
+			col.Precision = args[0]
+		}
+		if len(args) > 1 {
+			col.Scale = args[1]
+		}
+	}
+
+	for _, word := range strings.Fields(strings.ToLower(name)) {
+		if word == "unsigned" {
+			col.Unsigned = true
+		}
+	}
+}
+
+// timeLayout is the layout of the values of the temporal column col, as
+// MySQL writes them, with as many fractional digits as its type keeps.
+func (col Column) timeLayout() string {
+	layout := "2006-01-02 15:04:05"
+	switch baseTypeName(col.TypeName) {
+	case "date":
+		return "2006-01-02"
+	case "year":
+		return "2006"
+	case "time":
+		layout = "15:04:05"
+	}
+	if args := typeArgs(col.TypeName); len(args) == 1 {
+		if args[0] > 0 {
+			layout += "." + strings.Repeat("0", args[0])
+		}
+		return layout
+	}
+	// unknown precision, keep what's there
+	return layout + ".999999"
+}
+
+// integerSize returns the number of bytes used to store the integer
+// type named base.
+func integerSize(base string) int {
+	switch base {
+	case "tinyint":
+		return 1
+	case "smallint", "int2", "smallserial":
+		return 2
+	case "mediumint":
+		return 3
+	case "int", "integer", "int4", "serial":
+		return 4
+	case "bigint", "int8", "bigserial":
+		return 8
+	}
+	return 0
+}
+
 func guessSQLType(value interface{}) (SQLType, error) {
 
 	// check if its already typed
@@ -164,3 +377,25 @@ func guessSQLType(value interface{}) (SQLType, error) {
 	}
 	return SQLBytes, nil
 }
+
+// parseDefault turns literal defaults such as `'abc'::text` or `42`,
+// as reported by PostgreSQL and SQLite, into values of type t.
+// Expressions like `now()` are kept as their source text.
+func parseDefault(t SQLType, expr string) interface{} {
+	lit := expr
+	if i := strings.LastIndex(lit, "::"); i > 0 {
+		lit = lit[:i]
+	}
+	if len(lit) >= 2 && lit[0] == '\'' && lit[len(lit)-1] == '\'' {
+		lit = strings.Replace(lit[1:len(lit)-1], "''", "'", -1)
+	} else if t == SQLString || t == SQLBytes || t == SQLTime || t == SQLEnum || t == SQLSet ||
+		t == SQLJSON || t == SQLPoint || t == SQLGeometry {
+		// unquoted, so not a literal
+		return expr
+	}
+	v, err := t.ParseBytes([]byte(lit))
+	if err != nil {
+		return expr
+	}
+	return v
+}
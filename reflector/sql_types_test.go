@@ -0,0 +1,176 @@
+package reflector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDefault(t *testing.T) {
+	tests := []struct {
+		typ  SQLType
+		expr string
+		want interface{}
+	}{
+		{SQLString, "'abc'::character varying", "abc"},
+		{SQLString, "'it''s'::text", "it's"},
+		{SQLInteger, "42", int64(42)},
+		{SQLTime, "now()", "now()"},
+		{SQLDecimal, "12.50", "12.50"},
+		{SQLDecimal, "'0.10'::numeric", "0.10"},
+	}
+
+	for _, tt := range tests {
+		got := parseDefault(tt.typ, tt.expr)
+		if got != tt.want {
+			t.Fatalf("parseDefault(%v, %q)=%#v, want %#v", tt.typ, tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseEnumValues(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"enum('active','blocked')", []string{"active", "blocked"}},
+		{"set('a','b,c','')", []string{"a", "b,c", ""}},
+		{"enum('it''s','no\\'pe')", []string{"it's", "no'pe"}},
+		{"enum('a)','b')", []string{"a)", "b"}},
+	}
+
+	for _, tt := range tests {
+		got := parseEnumValues(tt.name)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("parseEnumValues(%q)=%q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseTypeDetails(t *testing.T) {
+	tests := []struct {
+		name string
+		want Column
+	}{
+		{"varchar(255)", Column{Length: 255}},
+		{"decimal(10,2)", Column{Precision: 10, Scale: 2}},
+		{"tinyint(1)", Column{Length: 1, Size: 1}},
+		{"bigint unsigned", Column{Size: 8, Unsigned: true}},
+		{"int(10) unsigned zerofill", Column{Length: 10, Size: 4, Unsigned: true}},
+		{"text", Column{}},
+		{"bit(12)", Column{Length: 12}},
+		{"bit", Column{Length: 1}},
+	}
+
+	for _, tt := range tests {
+		var got Column
+		got.parseTypeDetails(tt.name)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("parseTypeDetails(%q)=%#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseBitLiteral(t *testing.T) {
+	v, err := SQLBit.ParseBytes([]byte("b'101'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != uint64(5) {
+		t.Fatalf("want 5, got %#v", v)
+	}
+}
+
+func TestParseBool(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"yes", true},
+		{"ON", true},
+		{"enabled", true},
+		{"1", true},
+		{"no", false},
+		{"Off", false},
+		{"disabled", false},
+		{"false", false},
+	}
+	for _, tt := range tests {
+		v, err := SQLBool.ParseBytes([]byte(tt.text))
+		if err != nil {
+			t.Fatalf("parsing %q: %v", tt.text, err)
+		}
+		if v != tt.want {
+			t.Errorf("%q parsed as %#v, want %v", tt.text, v, tt.want)
+		}
+	}
+}
+
+func TestParseSQLTypeNameModifiers(t *testing.T) {
+	for _, name := range []string{"bigint unsigned", "int(10) unsigned zerofill", "BIGINT(20) UNSIGNED"} {
+		got, err := parseSQLTypeName(name)
+		if err != nil {
+			t.Fatalf("parseSQLTypeName(%q): %v", name, err)
+		}
+		if got != SQLInteger {
+			t.Fatalf("parseSQLTypeName(%q)=%v, want %v", name, got, SQLInteger)
+		}
+	}
+}
+
+func TestColumnParseExtra(t *testing.T) {
+	tests := []struct {
+		extra          string
+		want           ColumnExtra
+		insert, update bool
+	}{
+		{"", ColumnExtra{}, true, true},
+		{"auto_increment", ColumnExtra{AutoIncrement: true}, false, false},
+		{"VIRTUAL GENERATED", ColumnExtra{Generated: GeneratedVirtual}, false, false},
+		{"STORED GENERATED INVISIBLE", ColumnExtra{Generated: GeneratedStored}, false, false},
+		{"DEFAULT_GENERATED", ColumnExtra{DefaultGenerated: true}, false, true},
+		{"on update CURRENT_TIMESTAMP", ColumnExtra{OnUpdateCurrentTimestamp: true}, true, false},
+		{"DEFAULT_GENERATED on update CURRENT_TIMESTAMP(3)", ColumnExtra{DefaultGenerated: true, OnUpdateCurrentTimestamp: true}, false, false},
+	}
+	for _, tt := range tests {
+		col := Column{Extra: []byte(tt.extra)}
+		if got := col.ParseExtra(); got != tt.want {
+			t.Errorf("extra %q parsed as %+v, want %+v", tt.extra, got, tt.want)
+		}
+		if got := col.SetOnInsert(); got != tt.insert {
+			t.Errorf("extra %q set on insert=%v, want %v", tt.extra, got, tt.insert)
+		}
+		if got := col.SetOnUpdate(); got != tt.update {
+			t.Errorf("extra %q set on update=%v, want %v", tt.extra, got, tt.update)
+		}
+	}
+}
+
+func TestColumnParseExpressionDefaults(t *testing.T) {
+	tests := []struct {
+		typeName, def, extra string
+		want                 interface{}
+		wantExtra            string
+	}{
+		// MySQL 8
+		{"datetime", "CURRENT_TIMESTAMP", "DEFAULT_GENERATED", "CURRENT_TIMESTAMP", "DEFAULT_GENERATED"},
+		{"timestamp(3)", "CURRENT_TIMESTAMP(3)", "DEFAULT_GENERATED on update CURRENT_TIMESTAMP(3)",
+			"CURRENT_TIMESTAMP(3)", "DEFAULT_GENERATED on update CURRENT_TIMESTAMP(3)"},
+		{"char(36)", "uuid()", "DEFAULT_GENERATED", "uuid()", "DEFAULT_GENERATED"},
+		// MySQL 5.7
+		{"timestamp", "CURRENT_TIMESTAMP", "on update CURRENT_TIMESTAMP", "CURRENT_TIMESTAMP", "on update CURRENT_TIMESTAMP DEFAULT_GENERATED"},
+		// MariaDB
+		{"datetime", "current_timestamp()", "", "CURRENT_TIMESTAMP", "DEFAULT_GENERATED"},
+		// literals
+		{"varchar(10)", "now", "", "now", ""},
+	}
+	for _, tt := range tests {
+		col := Column{Default: []byte(tt.def), Extra: []byte(tt.extra)}
+		if err := col.parse(tt.typeName, "NO"); err != nil {
+			t.Fatalf("parsing %s default %q: %v", tt.typeName, tt.def, err)
+		}
+		if col.Default != tt.want || bytesText(col.Extra) != tt.wantExtra {
+			t.Errorf("%s default %q is %#v with extra %q, want %#v with %q",
+				tt.typeName, tt.def, col.Default, col.Extra, tt.want, tt.wantExtra)
+		}
+	}
+}
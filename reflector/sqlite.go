@@ -0,0 +1,563 @@
+package reflector
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+/*
+SQLite!
+*/
+
+// pragmas reported as variables of a SQLite database.
+var sqlitePragmas = []string{
+	"auto_vacuum",
+	"encoding",
+	"foreign_keys",
+	"journal_mode",
+	"page_size",
+	"user_version",
+}
+
+// DescribeSQLite loads the tables of the SQLite database opened in db.
+// The name of the database is not stored in the file, so it must be
+// given as `dbname`.
+func DescribeSQLite(db *sql.DB, dbname string) (*DBSchema, error) {
+
+	schema := &DBSchema{
+		Name:    dbname,
+		Dialect: DialectSQLite,
+	}
+
+	return schema, schema.loadSQLite(db)
+}
+
+func (db *DBSchema) loadSQLite(q queryer) error {
+	if err := db.loadSQLiteVariables(q); err != nil {
+		return fmt.Errorf("loading variables: %w", err)
+	}
+	if err := db.loadSQLiteTables(q); err != nil {
+		return fmt.Errorf("loading tables: %w", err)
+	}
+	if err := db.loadSQLiteViews(q); err != nil {
+		return fmt.Errorf("loading views: %w", err)
+	}
+	return nil
+}
+
+func (db *DBSchema) loadSQLiteVariables(q queryer) error {
+	for _, pragma := range sqlitePragmas {
+		v := Variable{Name: pragma}
+		if err := v.scanSQLite(q); err != nil {
+			return fmt.Errorf("reading pragma %q, %w", pragma, err)
+		}
+		db.Variables = append(db.Variables, v)
+	}
+	return nil
+}
+
+func (db *DBSchema) loadSQLiteTables(q queryer) error {
+
+	// SQLite connections are often not shared, so collect the names
+	// before querying each table.
+	names, err := queryStrings(q, `
+select name
+from sqlite_master
+where type = 'table' and name not like 'sqlite_%'
+order by name`)
+	if err != nil {
+		return fmt.Errorf("showing tables, %w", err)
+	}
+
+	for _, name := range names {
+		tbl := Table{Name: name}
+		if err := tbl.loadSQLite(q); err != nil {
+			return fmt.Errorf("loading table %q, %w", tbl.Name, err)
+		}
+		db.Tables = append(db.Tables, tbl)
+	}
+
+	db.resolveSQLiteForeignKeys()
+
+	return nil
+}
+
+func (db *DBSchema) loadSQLiteViews(q queryer) error {
+
+	rows, err := q.Query(`
+select name, sql
+from sqlite_master
+where type = 'view'
+order by name`)
+	if err != nil {
+		return fmt.Errorf("showing views, %w", err)
+	}
+	var views []View
+	for rows.Next() {
+		view := View{}
+		if err := rows.Scan(&view.Name, &view.Definition); err != nil {
+			rows.Close()
+			return err
+		}
+		views = append(views, view)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, view := range views {
+		tbl := Table{Name: view.Name}
+		if _, err := tbl.loadSQLiteColumns(q); err != nil {
+			return fmt.Errorf("loading view %q, %w", view.Name, err)
+		}
+		view.Columns = tbl.Columns
+		db.Views = append(db.Views, view)
+	}
+
+	return nil
+}
+
+// resolveSQLiteForeignKeys fills the referenced columns left out of
+// foreign keys that target a primary key.
+func (db *DBSchema) resolveSQLiteForeignKeys() {
+	pks := make(map[string]*Index)
+	for _, tbl := range db.Tables {
+		pks[tbl.Name] = tbl.Pk
+	}
+	for _, tbl := range db.Tables {
+		for _, fk := range tbl.ForeignKeys {
+			pk := pks[fk.RefTable]
+			if pk == nil {
+				continue
+			}
+			for i, refcol := range fk.RefColumns {
+				if refcol == "" && i < len(pk.Columns) {
+					fk.RefColumns[i] = pk.Columns[i].Name
+				}
+			}
+		}
+	}
+}
+
+func (v *Variable) scanSQLite(q queryer) error {
+	rows, err := q.Query("pragma " + v.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var values []byte
+	if rows.Next() {
+		if err := rows.Scan(&values); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	v.Type, err = guessSQLType(values)
+	if err != nil {
+		return err
+	}
+
+	v.Value, err = v.Type.ParseBytes(values)
+
+	return err
+}
+
+func (tbl *Table) loadSQLite(q queryer) error {
+	pkParts, err := tbl.loadSQLiteColumns(q)
+	if err != nil {
+		return err
+	}
+
+	if err := tbl.loadSQLiteIndices(q, pkParts); err != nil {
+		return err
+	}
+
+	sort.Sort(indexByKeyName(tbl.Indices))
+
+	// SQLite only reports CHECK constraints in the CREATE TABLE
+	// statement, which isn't parsed
+	tbl.collectUniqueConstraints()
+
+	if err := tbl.loadSQLiteForeignKeys(q); err != nil {
+		return err
+	}
+
+	sort.Sort(foreignKeysByName(tbl.ForeignKeys))
+
+	return tbl.loadSQLiteTriggers(q)
+}
+
+// loadSQLiteColumns returns the parts of the primary key, since SQLite
+// doesn't always list it as an index.
+func (tbl *Table) loadSQLiteColumns(q queryer) ([]IndexPart, error) {
+	rows, err := q.Query(fmt.Sprintf("pragma table_info(%s)", sqliteQuote(tbl.Name)))
+	if err != nil {
+		return nil, fmt.Errorf("describing table %q, %w", tbl.Name, err)
+	}
+	defer rows.Close()
+
+	var (
+		pkParts []IndexPart
+		rowid   string
+	)
+	for i := 0; rows.Next(); i++ {
+		col := Column{}
+		var (
+			typeName string
+			pkSeq    int
+		)
+		err := col.scanSQLite(rows, &typeName, &pkSeq)
+		if err != nil {
+			return nil, fmt.Errorf("scanning column %d, %w", i, err)
+		}
+		if pkSeq > 0 {
+			pkParts = append(pkParts, IndexPart{
+				Table:       tbl.Name,
+				KeyName:     "PRIMARY",
+				SeqInIndex:  pkSeq,
+				ColumnName:  col.Name,
+				IsAscending: true,
+				CanBeNull:   col.Nullable,
+				IndexType:   IndexBtree,
+			})
+			if strings.EqualFold(typeName, "integer") {
+				rowid = col.Name
+			}
+		}
+		tbl.Columns = append(tbl.Columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// a lone INTEGER PRIMARY KEY is an alias of the rowid, which
+	// behaves like MySQL's auto_increment
+	if len(pkParts) == 1 && rowid != "" {
+		for i, col := range tbl.Columns {
+			if col.Name == rowid {
+				tbl.Columns[i].Extra = []byte("auto_increment")
+			}
+		}
+	}
+
+	sort.Sort(indexPartsBySeq(pkParts))
+	return pkParts, nil
+}
+
+func (tbl *Table) loadSQLiteIndices(q queryer, parts []IndexPart) error {
+	rows, err := q.Query(fmt.Sprintf("pragma index_list(%s)", sqliteQuote(tbl.Name)))
+	if err != nil {
+		return fmt.Errorf("showing indices %q, %w", tbl.Name, err)
+	}
+
+	type sqliteIndex struct {
+		name   string
+		unique bool
+	}
+	var indices []sqliteIndex
+	for i := 0; rows.Next(); i++ {
+		var (
+			idx    sqliteIndex
+			origin string
+		)
+		if err := scanSQLitePragma(rows, map[string]interface{}{
+			"name":   &idx.name,
+			"unique": &idx.unique,
+			"origin": &origin,
+		}); err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning index %d, %w", i, err)
+		}
+		// the primary key was found with the columns
+		if origin == "pk" {
+			continue
+		}
+		indices = append(indices, idx)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range parts {
+		if err := parts[i].bindColumn(tbl); err != nil {
+			return err
+		}
+	}
+
+	for _, idx := range indices {
+		idxParts, err := tbl.loadSQLiteIndexParts(q, idx.name, idx.unique)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, idxParts...)
+	}
+	tbl.Pk, tbl.Indices = indicesFromParts(parts)
+
+	return nil
+}
+
+func (tbl *Table) loadSQLiteIndexParts(q queryer, name string, unique bool) ([]IndexPart, error) {
+	rows, err := q.Query(fmt.Sprintf("pragma index_info(%s)", sqliteQuote(name)))
+	if err != nil {
+		return nil, fmt.Errorf("showing index %q, %w", name, err)
+	}
+	defer rows.Close()
+
+	var parts []IndexPart
+	for i := 0; rows.Next(); i++ {
+		idx := IndexPart{
+			Table:       tbl.Name,
+			KeyName:     name,
+			NonUnique:   !unique,
+			IsAscending: true,
+			IndexType:   IndexBtree,
+		}
+		var cid int
+		if err := rows.Scan(&idx.SeqInIndex, &cid, &idx.ColumnName); err != nil {
+			return nil, fmt.Errorf("scanning index %q part %d, %w", name, i, err)
+		}
+		// sequence numbers start at 0 in SQLite, 1 in MySQL
+		idx.SeqInIndex++
+		if col := tbl.Has(idx.ColumnName); col != nil {
+			idx.CanBeNull = col.Nullable
+		}
+		if err := idx.bindColumn(tbl); err != nil {
+			return nil, err
+		}
+		parts = append(parts, idx)
+	}
+
+	return parts, rows.Err()
+}
+
+// loadSQLiteForeignKeys names the keys like MySQL does, since SQLite
+// doesn't report the names of constraints.
+func (tbl *Table) loadSQLiteForeignKeys(q queryer) error {
+	rows, err := q.Query(fmt.Sprintf("pragma foreign_key_list(%s)", sqliteQuote(tbl.Name)))
+	if err != nil {
+		return fmt.Errorf("showing foreign keys %q, %w", tbl.Name, err)
+	}
+	defer rows.Close()
+
+	var parts []foreignKeyPart
+	for i := 0; rows.Next(); i++ {
+		var (
+			part foreignKeyPart
+			id   int
+			seq  int
+			to   sql.NullString
+		)
+		err := scanSQLitePragma(rows, map[string]interface{}{
+			"id":        &id,
+			"seq":       &seq,
+			"table":     &part.RefTable,
+			"from":      &part.Column,
+			"to":        &to,
+			"on_update": &part.OnUpdate,
+			"on_delete": &part.OnDelete,
+		})
+		if err != nil {
+			return fmt.Errorf("scanning foreign key %d, %w", i, err)
+		}
+		part.Name = fmt.Sprintf("%s_ibfk_%d", tbl.Name, id+1)
+		// a missing `to` references the primary key of the other
+		// table, resolved once all tables are loaded
+		part.RefColumn = to.String
+		parts = append(parts, part)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	// listed by descending id, in order within a key
+	sort.Stable(foreignKeyPartsByName(parts))
+	tbl.ForeignKeys = foreignKeysFromParts(parts)
+
+	return nil
+}
+
+func (tbl *Table) loadSQLiteTriggers(q queryer) error {
+	rows, err := q.Query(`
+select name, sql
+from sqlite_master
+where type = 'trigger' and tbl_name = ?
+order by name`, tbl.Name)
+	if err != nil {
+		return fmt.Errorf("showing triggers %q, %w", tbl.Name, err)
+	}
+	defer rows.Close()
+
+	for i := 0; rows.Next(); i++ {
+		var ddl string
+		trg := Trigger{Table: tbl.Name}
+		if err := rows.Scan(&trg.Name, &ddl); err != nil {
+			return fmt.Errorf("scanning trigger %d, %w", i, err)
+		}
+		if err := trg.parseSQLite(ddl); err != nil {
+			return fmt.Errorf("parsing trigger %q, %w", trg.Name, err)
+		}
+		tbl.Triggers = append(tbl.Triggers, trg)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// fired in that order, like the other dialects report them
+	sort.Stable(triggersByTiming(tbl.Triggers))
+	return nil
+}
+
+var sqliteTrigger = regexp.MustCompile(`(?is)^\s*CREATE\s+(?:TEMP\s+|TEMPORARY\s+)?TRIGGER\s+.*?` +
+	`\b(?:(BEFORE|AFTER|INSTEAD\s+OF)\s+)?(DELETE|INSERT|UPDATE)\b.*?\bON\b.*?(\bBEGIN\b.*)$`)
+
+// parseSQLite reads the timing, event and body of a trigger from the
+// statement that created it.
+func (trg *Trigger) parseSQLite(ddl string) error {
+	m := sqliteTrigger.FindStringSubmatch(ddl)
+	if m == nil {
+		return fmt.Errorf("unrecognized trigger definition: %q", ddl)
+	}
+	trg.Timing = strings.ToUpper(strings.Join(strings.Fields(m[1]), " "))
+	if trg.Timing == "" {
+		trg.Timing = "BEFORE"
+	}
+	trg.Event = strings.ToUpper(m[2])
+	trg.Body = strings.TrimSpace(m[3])
+	return nil
+}
+
+func (col *Column) scanSQLite(rows *sql.Rows, typeName *string, pkSeq *int) error {
+	var (
+		cid     int
+		notnull bool
+		def     sql.NullString
+	)
+	err := rows.Scan(
+		&cid,
+		&col.Name,
+		typeName,
+		&notnull,
+		&def,
+		pkSeq,
+	)
+	if err != nil {
+		return err
+	}
+	col.Nullable = !notnull && *pkSeq == 0
+	col.TypeName = *typeName
+	col.Type = parseSQLiteTypeName(*typeName)
+	col.parseTypeDetails(*typeName)
+	if base := baseTypeName(*typeName); base == "int" || base == "integer" {
+		// whatever their name, SQLite's own integers hold 64 bits
+		col.Size = 0
+	}
+	if def.Valid {
+		col.Default = parseDefault(col.Type, def.String)
+	}
+	return nil
+}
+
+// parseSQLiteTypeName maps a declared column type to a SQLType. Common
+// names are matched first, then SQLite's own type affinity rules
+// apply, so this never fails.
+func parseSQLiteTypeName(name string) SQLType {
+
+	if l := strings.Index(name, "("); l > 0 && l < len(name) {
+		name = name[:l]
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	switch name {
+	case "bool", "boolean":
+		return SQLBool
+	case "date", "datetime", "timestamp", "time":
+		return SQLTime
+	case "decimal", "numeric":
+		return SQLDecimal
+	case "json":
+		return SQLJSON
+	}
+
+	switch {
+	case strings.Contains(name, "int"):
+		return SQLInteger
+	case strings.Contains(name, "char"),
+		strings.Contains(name, "clob"),
+		strings.Contains(name, "text"):
+		return SQLString
+	case strings.Contains(name, "blob"), name == "":
+		return SQLBytes
+	}
+	// REAL and NUMERIC affinity
+	return SQLFloat
+}
+
+// scanSQLitePragma scans the named columns of a pragma's rows into
+// dst, ignoring the others. The columns returned by pragmas vary with
+// the version of SQLite.
+func scanSQLitePragma(rows *sql.Rows, dst map[string]interface{}) error {
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	toScan := make([]interface{}, len(cols))
+	for i, col := range cols {
+		if field, ok := dst[col]; ok {
+			toScan[i] = field
+		} else {
+			toScan[i] = new(sql.RawBytes)
+		}
+	}
+	return rows.Scan(toScan...)
+}
+
+func queryStrings(q queryer, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var strs []string
+	for rows.Next() {
+		var str string
+		if err := rows.Scan(&str); err != nil {
+			return nil, err
+		}
+		strs = append(strs, str)
+	}
+	return strs, rows.Err()
+}
+
+func sqliteQuote(name string) string {
+	return DialectSQLite.Quote(name)
+}
+
+type indexPartsBySeq []IndexPart
+
+func (b indexPartsBySeq) Len() int           { return len(b) }
+func (b indexPartsBySeq) Less(i, j int) bool { return b[i].SeqInIndex < b[j].SeqInIndex }
+func (b indexPartsBySeq) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+type foreignKeyPartsByName []foreignKeyPart
+
+func (b foreignKeyPartsByName) Len() int           { return len(b) }
+func (b foreignKeyPartsByName) Less(i, j int) bool { return b[i].Name < b[j].Name }
+func (b foreignKeyPartsByName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+type triggersByTiming []Trigger
+
+func (b triggersByTiming) Len() int      { return len(b) }
+func (b triggersByTiming) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b triggersByTiming) Less(i, j int) bool {
+	// BEFORE, INSTEAD OF, then AFTER
+	return b[i].Timing != "AFTER" && b[j].Timing == "AFTER"
+}
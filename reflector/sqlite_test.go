@@ -0,0 +1,169 @@
+package reflector
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestParseSQLiteTypeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want SQLType
+	}{
+		{"INTEGER", SQLInteger},
+		{"bigint", SQLInteger},
+		{"VARCHAR(255)", SQLString},
+		{"text", SQLString},
+		{"CLOB", SQLString},
+		{"BLOB", SQLBytes},
+		{"", SQLBytes},
+		{"REAL", SQLFloat},
+		{"NUMERIC(10,2)", SQLDecimal},
+		{"BOOLEAN", SQLBool},
+		{"DATETIME", SQLTime},
+	}
+
+	for _, tt := range tests {
+		got := parseSQLiteTypeName(tt.name)
+		if got != tt.want {
+			t.Fatalf("parseSQLiteTypeName(%q)=%v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseSQLiteTrigger(t *testing.T) {
+	tests := []struct {
+		ddl  string
+		want Trigger
+	}{
+		{
+			ddl: "CREATE TRIGGER audit_users AFTER INSERT ON users BEGIN INSERT INTO audit VALUES (NEW.id); END",
+			want: Trigger{Timing: "AFTER", Event: "INSERT",
+				Body: "BEGIN INSERT INTO audit VALUES (NEW.id); END"},
+		},
+		{
+			ddl: "create trigger if not exists no_delete instead of delete on user_view begin select raise(abort, 'no'); end",
+			want: Trigger{Timing: "INSTEAD OF", Event: "DELETE",
+				Body: "begin select raise(abort, 'no'); end"},
+		},
+		{
+			ddl: "CREATE TRIGGER touch UPDATE OF name ON users BEGIN UPDATE users SET updated = 1; END",
+			want: Trigger{Timing: "BEFORE", Event: "UPDATE",
+				Body: "BEGIN UPDATE users SET updated = 1; END"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := Trigger{}
+		if err := got.parseSQLite(tt.ddl); err != nil {
+			t.Fatalf("parseSQLite(%q): %v", tt.ddl, err)
+		}
+		if got != tt.want {
+			t.Fatalf("parseSQLite(%q)=%#v, want %#v", tt.ddl, got, tt.want)
+		}
+	}
+}
+
+func TestLoadSQLiteColumnDetails(t *testing.T) {
+	schema := &fakeSchema{tables: []fakeTable{{
+		name: "items",
+		sqliteColumns: [][]driver.Value{
+			{int64(0), "id", "INTEGER", int64(0), nil, int64(1)},
+			{int64(1), "name", "varchar(255)", int64(1), nil, int64(0)},
+			{int64(2), "price", "decimal(10,2)", int64(1), "'0.00'", int64(0)},
+			{int64(3), "active", "tinyint(1)", int64(1), nil, int64(0)},
+			{int64(4), "views", "bigint unsigned", int64(0), nil, int64(0)},
+		},
+	}}}
+	db, _ := openFakeMySQL(t, schema)
+	defer db.Close()
+
+	tbl := Table{Name: "items"}
+	if _, err := tbl.loadSQLiteColumns(db); err != nil {
+		t.Fatal(err)
+	}
+	want := []Column{
+		{Name: "id", Type: SQLInteger, TypeName: "INTEGER", Extra: []byte("auto_increment")},
+		{Name: "name", Type: SQLString, TypeName: "varchar(255)", Length: 255},
+		{Name: "price", Type: SQLDecimal, TypeName: "decimal(10,2)", Precision: 10, Scale: 2, Default: "0.00"},
+		{Name: "active", Type: SQLInteger, TypeName: "tinyint(1)", Length: 1, Size: 1},
+		{Name: "views", Type: SQLInteger, TypeName: "bigint unsigned", Nullable: true, Size: 8, Unsigned: true},
+	}
+	if !reflect.DeepEqual(tbl.Columns, want) {
+		t.Fatalf("loaded\n%#v\nwant\n%#v", tbl.Columns, want)
+	}
+}
+
+func TestLoadSQLiteForeignKeys(t *testing.T) {
+	// listed by descending id, like SQLite does
+	schema := &fakeSchema{tables: []fakeTable{{
+		name: "lines",
+		sqliteFks: [][]driver.Value{
+			{int64(1), int64(0), "orders", "order_id", nil, "NO ACTION", "CASCADE", "NONE"},
+			{int64(1), int64(1), "orders", "shop_id", nil, "NO ACTION", "CASCADE", "NONE"},
+			{int64(0), int64(0), "users", "user_id", "uid", "CASCADE", "SET NULL", "NONE"},
+		},
+	}}}
+	db, _ := openFakeMySQL(t, schema)
+	defer db.Close()
+
+	tbl := Table{Name: "lines"}
+	if err := tbl.loadSQLiteForeignKeys(db); err != nil {
+		t.Fatal(err)
+	}
+	want := []ForeignKey{
+		{Name: "lines_ibfk_1", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"uid"}, OnDelete: "SET NULL", OnUpdate: "CASCADE"},
+		{Name: "lines_ibfk_2", Columns: []string{"order_id", "shop_id"}, RefTable: "orders", RefColumns: []string{"", ""}, OnDelete: "CASCADE", OnUpdate: "NO ACTION"},
+	}
+	if !reflect.DeepEqual(tbl.ForeignKeys, want) {
+		t.Fatalf("loaded %v, want %v", tbl.ForeignKeys, want)
+	}
+}
+
+func TestResolveSQLiteForeignKeys(t *testing.T) {
+	pk := func(cols ...string) *Index {
+		idx := &Index{KeyName: "PRIMARY"}
+		for _, col := range cols {
+			idx.Columns = append(idx.Columns, Column{Name: col})
+		}
+		return idx
+	}
+	tests := []struct {
+		fk   ForeignKey
+		want []string
+	}{
+		{
+			fk:   ForeignKey{RefTable: "orders", RefColumns: []string{"", ""}},
+			want: []string{"id", "shop_id"},
+		},
+		{
+			fk:   ForeignKey{RefTable: "orders", RefColumns: []string{"code"}},
+			want: []string{"code"},
+		},
+		{
+			fk:   ForeignKey{RefTable: "orders", RefColumns: []string{"", "", ""}},
+			want: []string{"id", "shop_id", ""},
+		},
+		{
+			fk:   ForeignKey{RefTable: "keyless", RefColumns: []string{""}},
+			want: []string{""},
+		},
+		{
+			fk:   ForeignKey{RefTable: "missing", RefColumns: []string{""}},
+			want: []string{""},
+		},
+	}
+
+	for _, tt := range tests {
+		db := DBSchema{Tables: []Table{
+			{Name: "orders", Pk: pk("id", "shop_id")},
+			{Name: "keyless"},
+			{Name: "lines", ForeignKeys: []ForeignKey{tt.fk}},
+		}}
+		db.resolveSQLiteForeignKeys()
+		if got := db.Tables[2].ForeignKeys[0].RefColumns; !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("resolved %v to %v, want %v", tt.fk, got, tt.want)
+		}
+	}
+}
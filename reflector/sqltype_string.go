@@ -4,9 +4,9 @@ package reflector
 
 import "fmt"
 
-const _SQLType_name = "startSQLTypeSQLStringSQLBytesSQLIntegerSQLFloatSQLBoolSQLTimestopSQLType"
+const _SQLType_name = "startSQLTypeSQLStringSQLBytesSQLIntegerSQLFloatSQLBoolSQLTimeSQLEnumSQLSetSQLDecimalSQLJSONSQLPointSQLGeometrySQLBitstopSQLType"
 
-var _SQLType_index = [...]uint8{0, 12, 21, 29, 39, 47, 54, 61, 72}
+var _SQLType_index = [...]uint8{0, 12, 21, 29, 39, 47, 54, 61, 68, 74, 84, 91, 99, 110, 116, 127}
 
 func (i SQLType) String() string {
 	if i < 0 || i+1 >= SQLType(len(_SQLType_index)) {
@@ -0,0 +1,66 @@
+package reflector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+/*
+Triggers!
+*/
+
+type Trigger struct {
+	Name   string `json:"name"`
+	Table  string `json:"table,omitempty"`  // The table the trigger is defined on.
+	Timing string `json:"timing,omitempty"` // BEFORE, AFTER or INSTEAD OF.
+	Event  string `json:"event,omitempty"`  // INSERT, UPDATE or DELETE.
+	Body   string `json:"body,omitempty"`   // The statement executed when the trigger fires.
+}
+
+func (trg Trigger) String() string {
+	return fmt.Sprintf("%q \t%s %s", trg.Name, trg.Timing, trg.Event)
+}
+
+var newColumnAssignment = regexp.MustCompile("(?i)\\bNEW\\.[`\"]?(\\w+)[`\"]?\\s*(:?=)")
+
+// AssignedColumns returns the columns of the NEW row that the body of
+// the trigger assigns, like `SET NEW.slug = ...` in MySQL or
+// `NEW.slug := ...` in PL/pgSQL. Only BEFORE triggers can change the
+// row that gets written.
+//
+// The body is not parsed: an `=` counts as an assignment when it starts
+// a statement or follows SET, to tell it apart from comparisons.
+func (trg Trigger) AssignedColumns() []string {
+	var (
+		cols []string
+		seen = make(map[string]bool)
+	)
+	for _, m := range newColumnAssignment.FindAllStringSubmatchIndex(trg.Body, -1) {
+		col := trg.Body[m[2]:m[3]]
+		op := trg.Body[m[4]:m[5]]
+		if op != ":=" && !startsStatement(trg.Body[:m[0]]) {
+			continue
+		}
+		if !seen[strings.ToLower(col)] {
+			seen[strings.ToLower(col)] = true
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// startsStatement tells if what follows `before` is at the start of a
+// statement or of an item in a SET list.
+func startsStatement(before string) bool {
+	before = strings.TrimRight(before, " \t\r\n")
+	if before == "" || strings.HasSuffix(before, ";") || strings.HasSuffix(before, ",") {
+		return true
+	}
+	i := strings.LastIndexAny(before, " \t\r\n;,()")
+	switch strings.ToUpper(before[i+1:]) {
+	case "SET", "BEGIN", "THEN", "ELSE", "LOOP", "DO":
+		return true
+	}
+	return false
+}
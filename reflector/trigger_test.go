@@ -0,0 +1,41 @@
+package reflector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTriggerAssignedColumns(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{
+			body: "SET NEW.slug = lower(NEW.title)",
+			want: []string{"slug"},
+		},
+		{
+			body: "SET NEW.`slug` = lower(NEW.title), NEW.created_at = now()",
+			want: []string{"slug", "created_at"},
+		},
+		{
+			body: "BEGIN IF NEW.total = 0 THEN SET NEW.status = 'empty'; END IF; END",
+			want: []string{"status"},
+		},
+		{
+			body: "BEGIN NEW.updated_at := now(); RETURN NEW; END;",
+			want: []string{"updated_at"},
+		},
+		{
+			body: "INSERT INTO audit (user_id) VALUES (NEW.id)",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		got := Trigger{Body: tt.body}.AssignedColumns()
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("AssignedColumns(%q)=%v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package reflector
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLoadViews(t *testing.T) {
+	schema := &fakeSchema{views: []fakeTable{
+		{
+			name:       "order_totals",
+			definition: "select `total`, `id` from `orders`",
+			columns: [][]driver.Value{
+				{"total", "decimal(10,2)", "YES", "", nil, "", ""},
+				{"id", "int(11)", "NO", "", "0", "", ""},
+			},
+		},
+		{
+			name:       "orders_archive",
+			definition: "select `id` from `old_orders`",
+			columns: [][]driver.Value{
+				{"id", "int(11)", "NO", "", nil, "", ""},
+			},
+		},
+		{
+			name:       "shapes",
+			definition: "select `shape` from `things`",
+			columns: [][]driver.Value{
+				{"shape", "widget", "YES", "", nil, "", ""},
+			},
+		},
+	}}
+	db, _ := openFakeMySQL(t, schema)
+	defer db.Close()
+
+	f, err := NewTableFilter(nil, []string{"*_archive"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strict := &DBSchema{Name: "fake"}
+	err = strict.loadViews(db, f, nil)
+	var colErr *ColumnError
+	if !errors.As(err, &colErr) || colErr.Table != "shapes" {
+		t.Fatalf("err=%v, want an error about the view shapes", err)
+	}
+
+	l := &lenience{}
+	got := &DBSchema{Name: "fake"}
+	if err := got.loadViews(db, f, l); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, view := range got.Views {
+		names = append(names, view.Name)
+	}
+	if want := []string{"order_totals", "shapes"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("loaded views %v, want %v", names, want)
+	}
+
+	totals := got.Views[0]
+	if totals.Definition != "select `total`, `id` from `orders`" {
+		t.Errorf("definition is %q", totals.Definition)
+	}
+	if len(totals.Columns) != 2 || totals.Columns[0].Name != "total" || totals.Columns[1].Name != "id" {
+		t.Errorf("columns are %v, want total and id, in order", totals.Columns)
+	}
+	if !totals.Columns[0].Nullable || totals.Columns[1].Type != SQLInteger {
+		t.Errorf("columns are %v, want a nullable total and an int id", totals.Columns)
+	}
+	if shape := got.Views[1].Columns[0]; shape.Type != SQLBytes {
+		t.Errorf("column is %v, want it as bytes", shape)
+	}
+	if w := l.list(); len(w) != 1 || w[0].Table != "shapes" || w[0].Column != "shape" {
+		t.Errorf("warned %v, want about shapes.shape", w)
+	}
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/aybabtme/sequel/generator"
 	"github.com/aybabtme/sequel/reflector"
@@ -35,7 +42,7 @@ func main() {
 	dbNameFlag := cli.StringFlag{
 		Name:   "db",
 		EnvVar: "MYSQL_DB_NAME",
-		Usage:  "name of the database to connect to",
+		Usage:  "name of the database to connect to; several, comma separated, or globs or /regexps/, generate a package each",
 	}
 
 	dbAddrFlag := cli.StringFlag{
@@ -45,29 +52,143 @@ func main() {
 		Usage:  "location of the database to connect to",
 	}
 
+	bulkFlag := cli.BoolFlag{
+		Name:  "bulk",
+		Usage: "load the schema with a few information_schema queries, rather than a few per table",
+	}
+
+	workersFlag := cli.IntFlag{
+		Name:  "workers",
+		Value: 1,
+		Usage: "number of tables to describe at once",
+	}
+
+	lenientFlag := cli.BoolFlag{
+		Name:  "lenient",
+		Usage: "leave out what can't be described, with a warning, rather than failing",
+	}
+
+	progressFlag := cli.BoolFlag{
+		Name:  "progress",
+		Usage: "log each table as it's described",
+	}
+
+	timeoutFlag := cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "give up describing a database after this long, like 30s; never if unset",
+	}
+
+	tablesFlag := cli.StringSliceFlag{
+		Name:  "tables",
+		Value: &cli.StringSlice{},
+		Usage: "only the tables matching this glob, or /regexp/; can be repeated",
+	}
+
+	excludeTablesFlag := cli.StringSliceFlag{
+		Name:  "exclude-tables",
+		Value: &cli.StringSlice{},
+		Usage: "not the tables matching this glob, or /regexp/; can be repeated",
+	}
+
 	dirFlag := cli.StringFlag{
 		Name:  "dir",
 		Value: ".",
 		Usage: "sub directory where to create the package",
 	}
 
-	app.Name = "sequel"
-	app.Email = "antoinegrondin@gmail.com"
-	app.Author = "Antoine Grondin"
-	app.Version = "0.1"
-	app.Flags = []cli.Flag{
+	decimalFlag := cli.StringFlag{
+		Name:  "decimal-pkg",
+		Usage: "package providing the Decimal and NullDecimal types of decimal columns, generated if not set",
+	}
+
+	schemaFlag := cli.StringFlag{
+		Name:  "schema",
+		Usage: "JSON file written by dump-schema, to generate without connecting to a database",
+	}
+
+	ddlFlag := cli.StringFlag{
+		Name:  "ddl",
+		Usage: "directory of MySQL migrations (.sql files) to read the schema from, instead of connecting to a database",
+	}
+
+	fromFlag := cli.StringFlag{
+		Name:  "from",
+		Usage: "schema to diff from: a JSON file written by dump-schema, a directory of migrations, or a DSN",
+	}
+
+	toFlag := cli.StringFlag{
+		Name:  "to",
+		Usage: "schema to diff to: a JSON file written by dump-schema, a directory of migrations, or a DSN",
+	}
+
+	dbFlags := []cli.Flag{
 		usernameFlag,
 		passwordFlag,
 		dbNameFlag,
 		dbAddrFlag,
+		bulkFlag,
+		workersFlag,
+		lenientFlag,
+		progressFlag,
+		timeoutFlag,
+		tablesFlag,
+		excludeTablesFlag,
+	}
+	genFlags := []cli.Flag{
 		dirFlag,
+		decimalFlag,
 	}
-	app.Action = func(ctx *cli.Context) {
-		var (
-			dsn    string
-			dbname = valOrDefault(ctx, dbNameFlag)
+
+	tableFilter := func(ctx *cli.Context) *reflector.TableFilter {
+		f, err := reflector.NewTableFilter(
+			ctx.StringSlice(tablesFlag.Name),
+			ctx.StringSlice(excludeTablesFlag.Name),
 		)
+		if err != nil {
+			log.Fatalf("filtering tables: %v", err)
+		}
+		return f
+	}
+
+	// newConfig reads the flags shared by the databases of a run.
+	newConfig := func(ctx *cli.Context) *config {
+		cfg := &config{
+			ddl:     ctx.String(ddlFlag.Name),
+			timeout: ctx.Duration(timeoutFlag.Name),
+			dir:     valOrDefault(ctx, dirFlag),
+			describe: reflector.DescribeOptions{
+				Bulk:    ctx.Bool(bulkFlag.Name),
+				Workers: ctx.Int(workersFlag.Name),
+				Tables:  tableFilter(ctx),
+				Lenient: ctx.Bool(lenientFlag.Name),
+			},
+		}
+		if names := ctx.String(dbNameFlag.Name); names != "" {
+			cfg.databases = strings.Split(names, ",")
+		}
+		if ctx.Bool(progressFlag.Name) {
+			cfg.progress = func(dbname, table string, done, total int) {
+				log.Printf("described %s.%s (%d/%d)", dbname, table, done, total)
+			}
+		}
+		if pkg := ctx.String(decimalFlag.Name); pkg != "" {
+			name := path.Base(pkg)
+			generator.DecimalImport = pkg
+			generator.DecimalType = name + ".Decimal"
+			generator.NullDecimalType = name + ".NullDecimal"
+		}
+		return cfg
+	}
 
+	// connect opens the server, and finds the databases of the run. A
+	// single name is connected to, like before there could be many.
+	connect := func(ctx *cli.Context, cfg *config) (*sql.DB, []string) {
+		var dbname string
+		if len(cfg.databases) == 1 && !isPattern(cfg.databases[0]) {
+			dbname = cfg.databases[0]
+		}
+
+		var dsn string
 		if ctx.IsSet(passwordFlag.Name) {
 			dsn = fmt.Sprintf("%s:%s@tcp(%s)/%s",
 				valOrDefault(ctx, usernameFlag),
@@ -86,22 +207,288 @@ func main() {
 		if err != nil {
 			log.Fatalf("opening DB: %v", err)
 		}
-		defer db.Close()
+		if cfg.describe.Workers > 2 {
+			// keep the connections of the workers between tables
+			db.SetMaxIdleConns(cfg.describe.Workers)
+		}
+		if dbname != "" {
+			return db, []string{dbname}
+		}
 
-		schema, err := reflector.DescribeMySQL(db, dbname)
+		f, err := reflector.NewTableFilter(cfg.databases, nil)
+		if err != nil {
+			log.Fatalf("matching databases: %v", err)
+		}
+		timeout, cancel := withTimeout(cfg.timeout)
+		defer cancel()
+		names, err := reflector.ListMySQLDatabases(timeout, db, f)
 		if err != nil {
-			log.Fatalf("describing DB: %v", err)
+			log.Fatalf("listing databases: %v", err)
+		}
+		if len(names) == 0 {
+			log.Fatalf("no database matches %q", strings.Join(cfg.databases, ","))
 		}
+		return db, names
+	}
 
-		dirname := valOrDefault(ctx, dirFlag)
-		if err := generator.Generate(dirname, schema); err != nil {
-			log.Fatalf("generating schema: %v", err)
+	// describeEach describes the databases of the run one after the
+	// other, or the --ddl migrations, and calls do with each schema. A
+	// database failing doesn't stop the others.
+	describeEach := func(ctx *cli.Context, cfg *config, do func(*reflector.DBSchema) error) []summary {
+		if len(cfg.databases) == 0 {
+			// exits with the usage
+			valOrDefault(ctx, dbNameFlag)
 		}
+		if cfg.ddl != "" {
+			if len(cfg.databases) > 1 || isPattern(cfg.databases[0]) {
+				log.Fatalf("--%s migrations describe a single database, not %q", ddlFlag.Name, strings.Join(cfg.databases, ","))
+			}
+			dbname := cfg.databases[0]
+			schema, err := reflector.DescribeMySQLDDL(cfg.ddl, dbname)
+			if err != nil {
+				return []summary{{name: dbname, err: fmt.Errorf("reading DDL: %v", err)}}
+			}
+			schema.Filter(cfg.describe.Tables)
+			return []summary{{name: dbname, schema: schema, err: do(schema)}}
+		}
+
+		db, names := connect(ctx, cfg)
+		defer db.Close()
+
+		var sums []summary
+		for _, dbname := range names {
+			sum := summary{name: dbname}
+			sum.schema, sum.err = cfg.describeMySQL(db, dbname)
+			if sum.err != nil {
+				sum.err = fmt.Errorf("describing DB: %v", sum.err)
+			} else {
+				sum.err = do(sum.schema)
+			}
+			sums = append(sums, sum)
+		}
+		return sums
+	}
+
+	generate := func(cfg *config, schema *reflector.DBSchema) error {
+		if err := generator.Generate(cfg.dir, schema); err != nil {
+			return fmt.Errorf("generating schema: %v", err)
+		}
+		return nil
+	}
+
+	generateEach := func(ctx *cli.Context) {
+		cfg := newConfig(ctx)
+		sums := describeEach(ctx, cfg, func(schema *reflector.DBSchema) error {
+			return generate(cfg, schema)
+		})
+		if failed := writeSummaries(os.Stderr, cfg.dir, sums); failed > 0 {
+			os.Exit(1)
+		}
+	}
+
+	app.Name = "sequel"
+	app.Email = "antoinegrondin@gmail.com"
+	app.Author = "Antoine Grondin"
+	app.Version = "0.1"
+	app.Flags = flags(dbFlags, genFlags)
+	app.Action = generateEach
+	app.Commands = []cli.Command{
+		{
+			Name:  "dump-schema",
+			Usage: "write the schema of the database, or of --ddl migrations, to stdout, as JSON",
+			Flags: flags([]cli.Flag{ddlFlag}, dbFlags),
+			Action: func(ctx *cli.Context) {
+				cfg := newConfig(ctx)
+				if len(cfg.databases) > 1 || len(cfg.databases) == 1 && isPattern(cfg.databases[0]) {
+					log.Fatalf("dump-schema writes a single database, not %q", strings.Join(cfg.databases, ","))
+				}
+				sums := describeEach(ctx, cfg, func(schema *reflector.DBSchema) error {
+					return schema.WriteJSON(os.Stdout)
+				})
+				if err := sums[0].err; err != nil {
+					log.Fatal(err)
+				}
+			},
+		},
+		{
+			Name:  "generate",
+			Usage: "generate a client package from the database, a --schema file or --ddl migrations",
+			Flags: flags([]cli.Flag{schemaFlag, ddlFlag}, dbFlags, genFlags),
+			Action: func(ctx *cli.Context) {
+				filename := ctx.String(schemaFlag.Name)
+				if filename == "" {
+					generateEach(ctx)
+					return
+				}
+				f, err := os.Open(filename)
+				if err != nil {
+					log.Fatalf("opening schema: %v", err)
+				}
+				schema, err := reflector.ReadJSON(f)
+				f.Close()
+				if err != nil {
+					log.Fatalf("reading schema: %v", err)
+				}
+				cfg := newConfig(ctx)
+				schema.Filter(cfg.describe.Tables)
+				if err := generate(cfg, schema); err != nil {
+					log.Fatal(err)
+				}
+			},
+		},
+		{
+			Name:  "diff",
+			Usage: "show how two schemas differ, and the statements migrating --from one --to the other",
+			Flags: []cli.Flag{fromFlag, toFlag, timeoutFlag, tablesFlag, excludeTablesFlag},
+			Action: func(ctx *cli.Context) {
+				timeout, cancel := withTimeout(ctx.Duration(timeoutFlag.Name))
+				defer cancel()
+				f := tableFilter(ctx)
+				from, err := openSchema(timeout, ctx.String(fromFlag.Name), f)
+				if err != nil {
+					log.Fatalf("opening --%s schema: %v", fromFlag.Name, err)
+				}
+				to, err := openSchema(timeout, ctx.String(toFlag.Name), f)
+				if err != nil {
+					log.Fatalf("opening --%s schema: %v", toFlag.Name, err)
+				}
+
+				diff := reflector.Diff(from, to)
+				if diff.Empty() {
+					log.Printf("schemas are the same")
+					return
+				}
+				for _, line := range strings.Split(strings.TrimSpace(diff.String()), "\n") {
+					fmt.Printf("-- %s\n", line)
+				}
+				fmt.Println()
+				for _, w := range diff.Warnings() {
+					log.Printf("warning: %s", w)
+				}
+				if err := diff.WriteSQL(os.Stdout); err != nil {
+					log.Fatalf("writing migration: %v", err)
+				}
+			},
+		},
 	}
 
 	app.Run(os.Args)
 }
 
+// config is read once from the flags, and shared by all the databases
+// of a run.
+type config struct {
+	databases []string // names or patterns, per --db
+	ddl       string
+	timeout   time.Duration
+	dir       string
+	describe  reflector.DescribeOptions
+	progress  func(dbname, table string, done, total int)
+}
+
+// describeMySQL describes a database within the timeout, reporting the
+// progress and the warnings with its name.
+func (cfg *config) describeMySQL(db *sql.DB, dbname string) (*reflector.DBSchema, error) {
+	opts := cfg.describe
+	if cfg.progress != nil {
+		opts.Progress = func(table string, done, total int) {
+			cfg.progress(dbname, table, done, total)
+		}
+	}
+	timeout, cancel := withTimeout(cfg.timeout)
+	defer cancel()
+	schema, err := reflector.DescribeMySQLContext(timeout, db, dbname, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range schema.Warnings {
+		log.Printf("warning: %s: %s", schema.Name, w)
+	}
+	return schema, nil
+}
+
+// summary is what a run did with a database.
+type summary struct {
+	name   string
+	schema *reflector.DBSchema
+	err    error
+}
+
+// writeSummaries writes a line per database, and returns how many
+// failed.
+func writeSummaries(w io.Writer, dir string, sums []summary) (failed int) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "database\ttables\tviews\troutines\twarnings\tpackage\n")
+	for _, sum := range sums {
+		if sum.err != nil {
+			failed++
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t-\t%v\n", sum.name, sum.err)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\n", sum.name,
+			len(sum.schema.Tables), len(sum.schema.Views), len(sum.schema.Routines),
+			len(sum.schema.Warnings), filepath.Join(dir, sum.schema.Name))
+	}
+	tw.Flush()
+	return failed
+}
+
+// isPattern tells if the name of a database is a glob or a /regexp/,
+// matching any number of databases.
+func isPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[") || strings.HasPrefix(name, "/")
+}
+
+// openSchema reads the schema at src: a JSON file written by
+// dump-schema, a directory of MySQL migrations, or else the DSN of a
+// MySQL database, described within ctx. Only the tables and views kept by
+// the filter are described.
+func openSchema(ctx context.Context, src string, tables *reflector.TableFilter) (*reflector.DBSchema, error) {
+	if src == "" {
+		return nil, fmt.Errorf("no schema given")
+	}
+	if fi, err := os.Stat(src); err == nil {
+		var schema *reflector.DBSchema
+		if fi.IsDir() {
+			schema, err = reflector.DescribeMySQLDDL(src, filepath.Base(src))
+		} else {
+			schema, err = readSchema(src)
+		}
+		if err != nil {
+			return nil, err
+		}
+		schema.Filter(tables)
+		return schema, nil
+	}
+
+	// a DSN, like user:pass@tcp(addr)/dbname?param=value, described as
+	// the database it selects
+	db, err := sql.Open("mysql", src)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return reflector.DescribeMySQLContext(ctx, db, "", reflector.DescribeOptions{Tables: tables})
+}
+
+// readSchema reads a JSON file written by dump-schema.
+func readSchema(filename string) (*reflector.DBSchema, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return reflector.ReadJSON(f)
+}
+
+// withTimeout is a context done after timeout, or never if it's zero.
+func withTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
 func valOrDefault(ctx *cli.Context, f cli.StringFlag) string {
 	str := ctx.String(f.Name)
 	if str != "" {
@@ -114,3 +501,12 @@ func valOrDefault(ctx *cli.Context, f cli.StringFlag) string {
 	}
 	return f.Value
 }
+
+// flags joins lists of flags, which commands share.
+func flags(lists ...[]cli.Flag) []cli.Flag {
+	var all []cli.Flag
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	return all
+}